@@ -0,0 +1,69 @@
+package gocacheable
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	gcCacheModule "github.com/josemiguelmelo/gocacheable/cachemodule"
+	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
+	subscriber "github.com/josemiguelmelo/gocacheable/events/subscriber"
+)
+
+// builtinEvents are the event types registered by every manager, acting on
+// the modules subscribed to them
+var builtinEvents = []string{gei.EventDeleteKeys, gei.EventResetModule, gei.EventInvalidateTag}
+
+// SubscribeEvent subscribes a module to an event type. Module events, such as
+// the built-in ones, act on the cache of the module when received, and
+// callback, if not nil, is then called with every event received.
+func (cs *CacheableManager) SubscribeEvent(moduleID string, eventType string, callback func(gei.CacheEvent)) (subscriber.CacheEventSubscriber, error) {
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return subscriber.CacheEventSubscriber{}, err
+	}
+
+	return cs.EventsManager.SubscribeEvent(module.Identifier, eventType, func(event gei.CacheEvent) {
+		if err := invokeOn(module, event); err != nil {
+			logrus.Errorln(fmt.Sprintf("Error handling event %s in module %s with err = %s", eventType, module.Name, err.Error()))
+		}
+		if callback != nil {
+			callback(event)
+		}
+	})
+}
+
+// UnsubscribeEvent unsubscribes a module from an event type
+func (cs *CacheableManager) UnsubscribeEvent(moduleID string, eventType string) error {
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return err
+	}
+
+	return cs.EventsManager.Unsubscribe(module.Identifier, eventType)
+}
+
+// EmitEvent emits an event to every module subscribed to its type
+func (cs *CacheableManager) EmitEvent(eventType string, event gei.CacheEvent) {
+	cs.init()
+	cs.EventsManager.EmitEvent(eventType, event)
+}
+
+// EmitEventContext emits an event to every module subscribed to its type,
+// returning an events.SkippedSubscribersError listing the modules it was not
+// queued for
+func (cs *CacheableManager) EmitEventContext(ctx context.Context, eventType string, event gei.CacheEvent) error {
+	cs.init()
+	return cs.EventsManager.EmitEventContext(ctx, eventType, event)
+}
+
+// invokeOn applies a module event to the cache of module. Other events are
+// left to the subscription callbacks.
+func invokeOn(module *gcCacheModule.CacheModule, event gei.CacheEvent) error {
+	moduleEvent, ok := event.(gei.ModuleCacheEvent)
+	if !ok {
+		return nil
+	}
+	return moduleEvent.InvokeOn(context.Background(), module)
+}
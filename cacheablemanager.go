@@ -1,41 +1,88 @@
 package gocacheable
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"fmt"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/josemiguelmelo/gocacheable/events"
-	"github.com/sirupsen/logrus"
 
 	gcCacheModule "github.com/josemiguelmelo/gocacheable/cachemodule"
 	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	gcStats "github.com/josemiguelmelo/gocacheable/stats"
 )
 
-// CacheableManager is responsible to manage cache storage
+// CacheableManager is responsible to manage cache storage. Modules can be
+// added while others are used. The zero value is a manager without
+// identifier, ready to use. A manager must not be copied after first use.
 type CacheableManager struct {
 	Identifier    string
-	modules       []gcCacheModule.CacheModule
+	modules       *moduleList
 	EventsManager events.CacheEventsManager
+	// setup initializes the managers not created by NewCacheableManager
+	setup sync.Once
 }
 
-// NewCacheableManager Create new CacheableManager object without modules
+// moduleList holds the modules of a manager
+type moduleList struct {
+	mu      sync.RWMutex
+	modules []*gcCacheModule.CacheModule
+}
+
+// NewCacheableManager Create new CacheableManager object without modules. Its
+// events manager has the built-in event types registered.
 func NewCacheableManager(identifier string) CacheableManager {
 	return CacheableManager{
-		Identifier: identifier,
-		modules:    []gcCacheModule.CacheModule{},
+		Identifier:    identifier,
+		modules:       &moduleList{},
+		EventsManager: newEventsManager(),
+	}
+}
+
+// newEventsManager returns an events manager with the built-in event types
+// registered
+func newEventsManager() events.CacheEventsManager {
+	eventsManager := events.NewCacheEventsManager()
+	for _, eventType := range builtinEvents {
+		eventsManager.RegisterEvent(eventType)
 	}
+	return eventsManager
+}
+
+// init sets up the modules and the events manager of a manager declared as a
+// zero value, and returns its modules
+func (cs *CacheableManager) init() *moduleList {
+	cs.setup.Do(func() {
+		if cs.modules == nil {
+			cs.modules = &moduleList{}
+		}
+		if cs.EventsManager == (events.CacheEventsManager{}) {
+			cs.EventsManager = newEventsManager()
+		}
+	})
+	return cs.modules
 }
 
 // ModulesCount returns number of modules
 func (cs *CacheableManager) ModulesCount() int {
-	return len(cs.modules)
+	modules := cs.init()
+	modules.mu.RLock()
+	defer modules.mu.RUnlock()
+	return len(modules.modules)
 }
 
 // ContainsModule verifies if manager contains the module with identifier=:identifier
 func (cs *CacheableManager) ContainsModule(module gcCacheModule.CacheModule) bool {
-	for _, m := range cs.modules {
+	modules := cs.init()
+	modules.mu.RLock()
+	defer modules.mu.RUnlock()
+	return cs.containsModule(module)
+}
+
+func (cs *CacheableManager) containsModule(module gcCacheModule.CacheModule) bool {
+	for _, m := range cs.modules.modules {
 		if m.Identifier == module.Identifier {
 			return true
 		}
@@ -43,65 +90,244 @@ func (cs *CacheableManager) ContainsModule(module gcCacheModule.CacheModule) boo
 	return false
 }
 
-// AddModule adds a new module if it still does not exists
-func (cs *CacheableManager) AddModule(name string, storageProvider gcInterfaces.CacheProviderInterface) error {
+// AddModule adds a new module if it still does not exists, and registers it
+// with the events manager. Providers supporting namespaces get the
+// "<manager identifier>:<module identifier>:" namespace, so that modules can
+// share a storage. Every module needs its own provider instance, as the
+// provider is namespaced and initialized for the module: a provider already
+// namespaced for another module is rejected with interfaces.ErrNamespaceSet.
+// The event queues of the module are configured with
+// cachemodule.WithEventQueue, unless it was already registered.
+func (cs *CacheableManager) AddModule(name string, storageProvider gcInterfaces.CacheProviderInterface, options ...gcCacheModule.Option) error {
+	modules := cs.init()
+	module := gcCacheModule.New(name, storageProvider, options...)
+
+	if cs.ContainsModule(module) {
+		return errors.New("Module already exists")
+	}
+
+	if namespaced, ok := storageProvider.(gcInterfaces.NamespacedCacheProviderInterface); ok {
+		if err := namespaced.SetNamespace(cs.Namespace(module.Identifier)); err != nil {
+			return err
+		}
+	}
+
 	err := storageProvider.Init()
 	if err != nil {
 		return err
 	}
 
-	module := gcCacheModule.New(name, storageProvider)
+	if !cs.EventsManager.ContainsModule(module.Identifier) {
+		if _, err = cs.EventsManager.RegisterModule(module.Identifier, module.EventQueueOptions()...); err != nil {
+			return err
+		}
+	}
 
-	if cs.ContainsModule(module) {
+	modules.mu.Lock()
+	defer modules.mu.Unlock()
+	// The module may have been added meanwhile, as the lock is not held
+	// while the provider is initialized
+	if cs.containsModule(module) {
 		return errors.New("Module already exists")
 	}
-
-	cs.modules = append(cs.modules, module)
+	modules.modules = append(modules.modules, &module)
 	return nil
 }
 
+// Modules returns the modules of the manager
+func (cs *CacheableManager) Modules() []*gcCacheModule.CacheModule {
+	modules := cs.init()
+	modules.mu.RLock()
+	defer modules.mu.RUnlock()
+	return append([]*gcCacheModule.CacheModule{}, modules.modules...)
+}
+
+// Namespace returns the namespace of the keys of a module in providers
+// supporting namespaces
+func (cs *CacheableManager) Namespace(moduleID string) string {
+	return cs.Identifier + ":" + moduleID + ":"
+}
+
 // FindModule finds a module by its identifier
 func (cs *CacheableManager) FindModule(identifier string) (*gcCacheModule.CacheModule, error) {
-	for _, m := range cs.modules {
+	modules := cs.init()
+	modules.mu.RLock()
+	defer modules.mu.RUnlock()
+	for _, m := range modules.modules {
 		if m.Identifier == identifier {
-			return &m, nil
+			return m, nil
 		}
 	}
 	return &gcCacheModule.CacheModule{}, errors.New("Module not found")
 }
 
+// Close stops the background work of every module and the delivery of events
+func (cs *CacheableManager) Close() error {
+	return cs.CloseContext(context.Background())
+}
+
+// CloseContext stops the background work of every module and the delivery of
+// events. The events being emitted are delivered until ctx is done, and the
+// ones left are reported by an events.UndeliveredEventsError.
+func (cs *CacheableManager) CloseContext(ctx context.Context) error {
+	cs.init()
+	err := cs.EventsManager.Close(ctx)
+	for _, m := range cs.Modules() {
+		if closeErr := m.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
 // Get get key value from cache
 func (cs *CacheableManager) Get(moduleID string, key string, out interface{}) error {
+	return cs.GetContext(context.Background(), moduleID, key, out)
+}
+
+// GetContext get key value from cache
+func (cs *CacheableManager) GetContext(ctx context.Context, moduleID string, key string, out interface{}) error {
 	module, err := cs.FindModule(moduleID)
 	if err != nil {
 		return err
 	}
 
-	return module.Get(key, &out)
+	return module.GetContext(ctx, key, out)
+}
+
+// Set caches a value in a module, expiring after timeToLive
+func (cs *CacheableManager) Set(moduleID string, key string, value interface{}, timeToLive time.Duration, options ...gcCacheModule.EntryOption) error {
+	return cs.SetContext(context.Background(), moduleID, key, value, timeToLive, options...)
+}
+
+// SetContext caches a value in a module, expiring after timeToLive. A zero
+// timeToLive caches the value without expiration, a negative one is rejected
+// with cachemodule.ErrNegativeTimeToLive.
+func (cs *CacheableManager) SetContext(ctx context.Context, moduleID string, key string, value interface{}, timeToLive time.Duration, options ...gcCacheModule.EntryOption) error {
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return err
+	}
+
+	return module.SetWithTTLContext(ctx, key, value, timeToLive, options...)
+}
+
+// GetMany returns the results of keys in a module, in the same order
+func (cs *CacheableManager) GetMany(moduleID string, keys []string) ([]gcCacheModule.GetResult, error) {
+	return cs.GetManyContext(context.Background(), moduleID, keys)
+}
+
+// GetManyContext returns the results of keys in a module, in the same order.
+// Every result tells whether its key was found and decodes its value.
+func (cs *CacheableManager) GetManyContext(ctx context.Context, moduleID string, keys []string) ([]gcCacheModule.GetResult, error) {
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return module.GetManyContext(ctx, keys)
+}
+
+// SetMany caches values in a module, expiring after timeToLive
+func (cs *CacheableManager) SetMany(moduleID string, values map[string]interface{}, timeToLive time.Duration, options ...gcCacheModule.EntryOption) error {
+	return cs.SetManyContext(context.Background(), moduleID, values, timeToLive, options...)
+}
+
+// SetManyContext caches values in a module, expiring after timeToLive
+func (cs *CacheableManager) SetManyContext(ctx context.Context, moduleID string, values map[string]interface{}, timeToLive time.Duration, options ...gcCacheModule.EntryOption) error {
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return err
+	}
+
+	return module.SetManyContext(ctx, values, timeToLive, options...)
+}
+
+// DeleteMany removes keys from a module
+func (cs *CacheableManager) DeleteMany(moduleID string, keys []string) error {
+	return cs.DeleteManyContext(context.Background(), moduleID, keys)
+}
+
+// DeleteManyContext removes keys from a module
+func (cs *CacheableManager) DeleteManyContext(ctx context.Context, moduleID string, keys []string) error {
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return err
+	}
+
+	return module.DeleteManyContext(ctx, keys)
 }
 
 // DeleteKey removes a key from a module
 func (cs *CacheableManager) DeleteKey(moduleID string, key string) error {
+	return cs.DeleteKeyContext(context.Background(), moduleID, key)
+}
+
+// DeleteKeyContext removes a key from a module
+func (cs *CacheableManager) DeleteKeyContext(ctx context.Context, moduleID string, key string) error {
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return err
+	}
+
+	return module.DeleteContext(ctx, key)
+}
+
+// InvalidateTag removes every key cached with tag from a module
+func (cs *CacheableManager) InvalidateTag(moduleID string, tag string) error {
+	return cs.InvalidateTagContext(context.Background(), moduleID, tag)
+}
+
+// InvalidateTagContext removes every key cached with tag from a module
+func (cs *CacheableManager) InvalidateTagContext(ctx context.Context, moduleID string, tag string) error {
 	module, err := cs.FindModule(moduleID)
 	if err != nil {
 		return err
 	}
 
-	return module.Delete(key)
+	return module.InvalidateTagContext(ctx, tag)
 }
 
 // Reset resets a module cache
 func (cs *CacheableManager) Reset(moduleID string) error {
+	return cs.ResetContext(context.Background(), moduleID)
+}
+
+// ResetContext resets a module cache
+func (cs *CacheableManager) ResetContext(ctx context.Context, moduleID string) error {
 	module, err := cs.FindModule(moduleID)
 	if err != nil {
 		return err
 	}
 
-	return module.Reset()
+	return module.ResetContext(ctx)
 }
 
-// Cacheable adds cache to the function passed as parameter
-func (cs *CacheableManager) Cacheable(moduleID string, key string, f func() (interface{}, error), out interface{}, timeToLive time.Duration) error {
+// Stats returns the hit, miss, loader and provider statistics of a module.
+// Statistics are always recorded, taking a snapshot is cheap.
+func (cs *CacheableManager) Stats(moduleID string) (gcStats.Stats, error) {
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return gcStats.Stats{}, err
+	}
+
+	return module.Stats(), nil
+}
+
+// Cacheable adds cache to the function passed as parameter, caching its
+// result for timeToLive. A zero timeToLive caches the result without
+// expiration, until it is deleted or evicted, and a negative one is rejected
+// with cachemodule.ErrNegativeTimeToLive.
+func (cs *CacheableManager) Cacheable(moduleID string, key string, f func() (interface{}, error), out interface{}, timeToLive time.Duration, options ...gcCacheModule.EntryOption) error {
+	return cs.CacheableContext(context.Background(), moduleID, key, withoutContext(f), out, timeToLive, options...)
+}
+
+// CacheableContext adds cache to the function passed as parameter. ctx is
+// passed to f and bounds both the cache storage access and the call to f.
+func (cs *CacheableManager) CacheableContext(ctx context.Context, moduleID string, key string, f func(context.Context) (interface{}, error), out interface{}, timeToLive time.Duration, options ...gcCacheModule.EntryOption) error {
+	if timeToLive < 0 {
+		return gcCacheModule.ErrNegativeTimeToLive
+	}
 	module, err := cs.FindModule(moduleID)
 	if err != nil {
 		return err
@@ -111,33 +337,50 @@ func (cs *CacheableManager) Cacheable(moduleID string, key string, f func() (int
 		return errors.New("Cache storage not created")
 	}
 
-	var obj interface{}
-	// Check on cache and return if found
-	err = module.Get(key, &out)
+	// Check on cache and return if found, refreshing it in background if stale
+	result, err := module.LookupContext(ctx, key, out)
 	if err == nil {
+		if result.Stale {
+			module.Refresh(ctx, key, f, timeToLive, options...)
+		}
+		return result.Err
+	}
+
+	obj, err := load(ctx, module, key, f, timeToLive, options...)
+	if err != nil {
 		return err
 	}
+	return module.Convert(obj, out)
+}
 
-	time.AfterFunc(timeToLive, func() {
-		if module.HasKey(key) {
-			if deleteErr := module.Delete(key); deleteErr != nil {
-				logrus.Errorln(fmt.Sprintf("Error deleting cache with key = %s from module %s with err = %s", key, module.Name, deleteErr.Error()))
-			}
-		}
-	})
+// withoutContext adapts a loader that does not take a context
+func withoutContext[T any](f func() (T, error)) func(context.Context) (T, error) {
+	return func(context.Context) (T, error) {
+		return f()
+	}
+}
 
-	obj, err = f()
-	if err == nil {
-		err = module.Set(key, obj)
-		if err != nil {
-			return err
-		}
-		jsonData, _ := json.Marshal(obj)
-		err = json.Unmarshal(jsonData, &out)
-		if err != nil {
-			return err
-		}
+// anyLoader adapts a typed loader to the module loader signature
+func anyLoader[T any](f func(context.Context) (T, error)) func(context.Context) (interface{}, error) {
+	return func(ctx context.Context) (interface{}, error) {
+		return f(ctx)
 	}
+}
 
-	return err
+// load calls f through the module, which caches its result under key for
+// timeToLive. A concurrent load of key sharing its result through single
+// flight may return a value of another type, which fails with a
+// *TypeMismatchError.
+func load[T any](ctx context.Context, module *gcCacheModule.CacheModule, key string, f func(context.Context) (T, error), timeToLive time.Duration, options ...gcCacheModule.EntryOption) (T, error) {
+	obj, err := module.LoadContext(ctx, key, anyLoader(f), timeToLive, options...)
+
+	var value T
+	if obj == nil {
+		return value, err
+	}
+	value, ok := obj.(T)
+	if !ok {
+		return value, &TypeMismatchError{Key: key, Expected: reflect.TypeOf(&value).Elem(), Actual: reflect.TypeOf(obj)}
+	}
+	return value, err
 }
@@ -1,13 +1,20 @@
 package gocacheable
 
 import (
+	"context"
 	"errors"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	gcCacheModule "github.com/josemiguelmelo/gocacheable/cachemodule"
+	"github.com/josemiguelmelo/gocacheable/events"
+	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
 	bcProvider "github.com/josemiguelmelo/gocacheable/providers/bigcache"
+	lruProvider "github.com/josemiguelmelo/gocacheable/providers/lru"
+	redisProvider "github.com/josemiguelmelo/gocacheable/providers/redis"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,12 +39,38 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
+// newTestProvider returns a small in-memory provider for a module, so that
+// tests do not each allocate the storage of a bigcache provider
+func newTestProvider() *lruProvider.LRUProvider {
+	return lruProvider.NewLRUProvider(1000, 0)
+}
+
 func TestManagerCreatedSuccessfully(t *testing.T) {
 	// Check manager does not contain any module
 	assert.Equal(t, 0, cacheableManager.ModulesCount())
 	assert.Equal(t, identifier, cacheableManager.Identifier)
 }
 
+func TestZeroValueManager(t *testing.T) {
+	var manager CacheableManager
+	assert.Equal(t, 0, manager.ModulesCount())
+	_, err := manager.FindModule("posts")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, manager.AddModule("posts", newTestProvider()))
+	assert.Equal(t, 1, manager.ModulesCount())
+	assert.Nil(t, manager.Set("posts", "key", "value", time.Minute))
+
+	received := make(chan gei.CacheEvent, 1)
+	_, err = manager.SubscribeEvent("posts", gei.EventResetModule, func(event gei.CacheEvent) {
+		received <- event
+	})
+	assert.Nil(t, err)
+	manager.EmitEvent(gei.EventResetModule, &gei.ResetCacheEvent{})
+	<-received
+	assert.Nil(t, manager.Close())
+}
+
 func TestAddModuleToManager(t *testing.T) {
 	storageProvider := &bcProvider.BigCacheProvider{
 		Lifetime: 2,
@@ -193,3 +226,288 @@ func TestCacheableTimeToLive(t *testing.T) {
 	assert.Equal(t, "no", outValue.St)
 	assert.Equal(t, "", outValue.notShown)
 }
+
+func TestCacheableWithoutTimeToLive(t *testing.T) {
+	// A zero time to live caches without expiration
+	var outValue ExampleObj
+	err := cacheableManager.Cacheable(moduleName, "without_time_to_live", func() (interface{}, error) {
+		return ExampleObj{St: "yes"}, nil
+	}, &outValue, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "yes", outValue.St)
+
+	time.Sleep(50 * time.Millisecond)
+
+	err = cacheableManager.Cacheable(moduleName, "without_time_to_live", func() (interface{}, error) {
+		return ExampleObj{St: "no"}, nil
+	}, &outValue, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "yes", outValue.St)
+}
+
+func TestNegativeTimeToLive(t *testing.T) {
+	called := false
+	var outValue ExampleObj
+	err := cacheableManager.Cacheable(moduleName, "negative_time_to_live", func() (interface{}, error) {
+		called = true
+		return ExampleObj{St: "yes"}, nil
+	}, &outValue, -time.Second)
+	assert.Equal(t, gcCacheModule.ErrNegativeTimeToLive, err)
+	assert.False(t, called)
+
+	_, err = Cacheable(&cacheableManager, moduleName, "negative_time_to_live", func() (string, error) {
+		return "yes", nil
+	}, -time.Second)
+	assert.Equal(t, gcCacheModule.ErrNegativeTimeToLive, err)
+
+	err = cacheableManager.Set(moduleName, "negative_time_to_live", "yes", -time.Second)
+	assert.Equal(t, gcCacheModule.ErrNegativeTimeToLive, err)
+	err = cacheableManager.SetMany(moduleName, map[string]interface{}{"negative_time_to_live": "yes"}, -time.Second)
+	assert.Equal(t, gcCacheModule.ErrNegativeTimeToLive, err)
+
+	var value string
+	assert.NotNil(t, cacheableManager.Get(moduleName, "negative_time_to_live", &value))
+}
+
+func TestCacheableContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var outValue int
+	err := cacheableManager.CacheableContext(ctx, moduleName, "test_context", func(context.Context) (interface{}, error) {
+		return secondMethodToCache(), nil
+	}, &outValue, 100*time.Millisecond)
+	assert.Equal(t, context.Canceled, err)
+
+	err = cacheableManager.CacheableContext(context.Background(), moduleName, "test_context", func(ctx context.Context) (interface{}, error) {
+		return methodToCache(), ctx.Err()
+	}, &outValue, 100*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 9, outValue)
+
+	err = cacheableManager.GetContext(ctx, moduleName, "test_context", &outValue)
+	assert.Equal(t, context.Canceled, err)
+	err = cacheableManager.DeleteKeyContext(ctx, moduleName, "test_context")
+	assert.Equal(t, context.Canceled, err)
+	err = cacheableManager.ResetContext(ctx, moduleName)
+	assert.Equal(t, context.Canceled, err)
+
+	err = cacheableManager.DeleteKeyContext(context.Background(), moduleName, "test_context")
+	assert.Nil(t, err)
+}
+
+func TestModulesShareNamespacedStorage(t *testing.T) {
+	server := miniredis.RunT(t)
+	manager := NewCacheableManager(identifier)
+	for _, name := range []string{"first", "second"} {
+		assert.Nil(t, manager.AddModule(name, &redisProvider.RedisProvider{Addr: server.Addr(), MaxIdle: 10, MaxActive: 100}))
+	}
+
+	var out string
+	loader := func() (interface{}, error) { return "value", nil }
+	assert.Nil(t, manager.Cacheable("first", "key", loader, &out, time.Minute))
+	assert.Nil(t, manager.Cacheable("second", "key", loader, &out, time.Minute))
+	assert.True(t, server.Exists(manager.Namespace("first")+"key"))
+	assert.Equal(t, "testing_manager:second:", manager.Namespace("second"))
+
+	// Resetting a module leaves the keys of the other one
+	assert.Nil(t, manager.Reset("first"))
+	assert.NotNil(t, manager.Get("first", "key", &out))
+	assert.Nil(t, manager.Get("second", "key", &out))
+	assert.Equal(t, "value", out)
+}
+
+func TestModulesNeedTheirOwnProvider(t *testing.T) {
+	server := miniredis.RunT(t)
+	manager := NewCacheableManager(identifier)
+	shared := &redisProvider.RedisProvider{Addr: server.Addr(), MaxIdle: 10, MaxActive: 100}
+
+	assert.Nil(t, manager.AddModule("first", shared))
+	assert.Equal(t, gcInterfaces.ErrNamespaceSet, manager.AddModule("second", shared))
+	assert.Equal(t, manager.Namespace("first"), shared.Namespace)
+	assert.Equal(t, 1, manager.ModulesCount())
+}
+
+func TestManagerBatchOperations(t *testing.T) {
+	server := miniredis.RunT(t)
+	manager := NewCacheableManager(identifier)
+	assert.Nil(t, manager.AddModule("batch", &redisProvider.RedisProvider{Addr: server.Addr(), MaxIdle: 10, MaxActive: 100}))
+
+	err := manager.SetMany("batch", map[string]interface{}{"a": 1, "b": 2}, time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, server.Exists(manager.Namespace("batch")+"a"))
+	assert.Equal(t, time.Minute, server.TTL(manager.Namespace("batch")+"b"))
+
+	results, err := manager.GetMany("batch", []string{"a", "missing", "b"})
+	assert.Nil(t, err)
+	var out int
+	assert.Nil(t, results[0].Decode(&out))
+	assert.Equal(t, 1, out)
+	assert.False(t, results[1].Found)
+	assert.Nil(t, results[2].Decode(&out))
+	assert.Equal(t, 2, out)
+
+	assert.Nil(t, manager.DeleteMany("batch", []string{"a", "missing"}))
+	assert.False(t, server.Exists(manager.Namespace("batch")+"a"))
+	assert.True(t, server.Exists(manager.Namespace("batch")+"b"))
+
+	_, err = manager.GetMany("not_found", []string{"a"})
+	assert.Equal(t, "Module not found", err.Error())
+}
+
+func TestInvalidateTagAcrossInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+	var managers []*CacheableManager
+	for i := 0; i < 2; i++ {
+		manager := NewCacheableManager(identifier)
+		assert.Nil(t, manager.AddModule("tagged", &redisProvider.RedisProvider{Addr: server.Addr(), MaxIdle: 10, MaxActive: 100}))
+		managers = append(managers, &manager)
+	}
+
+	var out string
+	loader := func() (interface{}, error) { return "john", nil }
+	err := managers[0].Cacheable("tagged", "user:42", loader, &out, time.Minute, gcCacheModule.WithTags("user:42"))
+	assert.Nil(t, err)
+	assert.Nil(t, managers[1].Set("tagged", "posts:42", "posts", time.Minute, gcCacheModule.WithTags("user:42")))
+	assert.Nil(t, managers[1].Set("tagged", "posts:43", "posts", time.Minute, gcCacheModule.WithTags("user:43")))
+
+	// The tag index is kept in redis, so any instance can invalidate the tag
+	assert.Nil(t, managers[1].InvalidateTag("tagged", "user:42"))
+	assert.NotNil(t, managers[0].Get("tagged", "user:42", &out))
+	assert.NotNil(t, managers[0].Get("tagged", "posts:42", &out))
+	assert.Nil(t, managers[0].Get("tagged", "posts:43", &out))
+
+	assert.Equal(t, "Module not found", managers[0].InvalidateTag("not_found", "user:42").Error())
+}
+
+func TestEventsActOnSubscribedModules(t *testing.T) {
+	manager := NewCacheableManager(identifier)
+	for _, name := range []string{"users", "posts", "other"} {
+		assert.Nil(t, manager.AddModule(name, newTestProvider()))
+		assert.True(t, manager.EventsManager.ContainsModule(name))
+	}
+
+	received := make(chan gei.CacheEvent)
+	for _, eventType := range []string{gei.EventDeleteKeys, gei.EventResetModule, gei.EventInvalidateTag} {
+		_, err := manager.SubscribeEvent("posts", eventType, func(event gei.CacheEvent) {
+			received <- event
+		})
+		assert.Nil(t, err)
+	}
+	_, err := manager.SubscribeEvent("not_found", gei.EventDeleteKeys, nil)
+	assert.Equal(t, "Module not found", err.Error())
+
+	for _, name := range []string{"posts", "other"} {
+		assert.Nil(t, manager.Set(name, "posts:42", "posts", time.Minute, gcCacheModule.WithTags("user:42")))
+		assert.Nil(t, manager.Set(name, "posts:43", "posts", time.Minute))
+	}
+	var out string
+
+	// The users module updates user 42, the posts module drops what depends on it
+	manager.EmitEvent(gei.EventInvalidateTag, &gei.InvalidateTagCacheEvent{Tag: "user:42"})
+	assert.Equal(t, &gei.InvalidateTagCacheEvent{Tag: "user:42"}, <-received)
+	assert.NotNil(t, manager.Get("posts", "posts:42", &out))
+	assert.Nil(t, manager.Get("posts", "posts:43", &out))
+	assert.Nil(t, manager.Get("other", "posts:42", &out))
+
+	manager.EmitEvent(gei.EventDeleteKeys, &gei.RemoveCacheEvent{Keys: []string{"posts:43"}})
+	<-received
+	assert.NotNil(t, manager.Get("posts", "posts:43", &out))
+	assert.Nil(t, manager.Get("other", "posts:43", &out))
+
+	assert.Nil(t, manager.Set("posts", "posts:44", "posts", time.Minute))
+	manager.EmitEvent(gei.EventResetModule, &gei.ResetCacheEvent{})
+	<-received
+	assert.NotNil(t, manager.Get("posts", "posts:44", &out))
+	assert.Nil(t, manager.Get("other", "posts:43", &out))
+}
+
+func TestUnsubscribeEventAndClose(t *testing.T) {
+	manager := NewCacheableManager(identifier)
+	assert.Nil(t, manager.AddModule("posts", newTestProvider()))
+
+	received := make(chan gei.CacheEvent, 1)
+	_, err := manager.SubscribeEvent("posts", gei.EventDeleteKeys, func(event gei.CacheEvent) {
+		received <- event
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, manager.UnsubscribeEvent("posts", gei.EventDeleteKeys))
+	assert.NotNil(t, manager.UnsubscribeEvent("posts", gei.EventDeleteKeys))
+	assert.Equal(t, "Module not found", manager.UnsubscribeEvent("not_found", gei.EventDeleteKeys).Error())
+
+	// The keys of an unsubscribed module are left untouched
+	var out string
+	assert.Nil(t, manager.Set("posts", "posts:42", "posts", time.Minute))
+	manager.EmitEvent(gei.EventDeleteKeys, &gei.RemoveCacheEvent{Keys: []string{"posts:42"}})
+	assert.Nil(t, manager.Get("posts", "posts:42", &out))
+	assert.Equal(t, 0, len(received))
+
+	_, err = manager.SubscribeEvent("posts", gei.EventDeleteKeys, func(event gei.CacheEvent) {
+		received <- event
+	})
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, manager.CloseContext(ctx))
+
+	// Events emitted once closed are dropped
+	manager.EmitEvent(gei.EventDeleteKeys, &gei.RemoveCacheEvent{Keys: []string{"posts:42"}})
+	assert.Equal(t, 0, len(received))
+}
+
+func TestEmitEventContextSkipsFullQueues(t *testing.T) {
+	queue := []events.QueueOption{events.WithQueueCapacity(1), events.WithOverflowPolicy(events.OverflowError)}
+
+	// Queues configured when registering the module, or when adding it
+	manager := NewCacheableManager(identifier)
+	_, err := manager.EventsManager.RegisterModule("posts", queue...)
+	assert.Nil(t, err)
+	assert.Nil(t, manager.AddModule("posts", newTestProvider()))
+	testEmitEventContextSkipsFullQueues(t, &manager)
+
+	manager = NewCacheableManager(identifier)
+	assert.Nil(t, manager.AddModule("posts", newTestProvider(), gcCacheModule.WithEventQueue(queue...)))
+	testEmitEventContextSkipsFullQueues(t, &manager)
+}
+
+func testEmitEventContextSkipsFullQueues(t *testing.T, manager *CacheableManager) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	_, err := manager.SubscribeEvent("posts", gei.EventResetModule, func(gei.CacheEvent) {
+		started <- struct{}{}
+		<-release
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, manager.EmitEventContext(context.Background(), gei.EventResetModule, &gei.ResetCacheEvent{}))
+	<-started
+	assert.Nil(t, manager.EmitEventContext(context.Background(), gei.EventResetModule, &gei.ResetCacheEvent{}))
+	err = manager.EmitEventContext(context.Background(), gei.EventResetModule, &gei.ResetCacheEvent{})
+	assert.True(t, errors.Is(err, events.ErrQueueFull))
+
+	close(release)
+	assert.Nil(t, manager.Close())
+}
+
+func TestStats(t *testing.T) {
+	manager := NewCacheableManager(identifier)
+	assert.Nil(t, manager.AddModule("posts", newTestProvider()))
+
+	var out string
+	for i := 0; i < 3; i++ {
+		err := manager.Cacheable("posts", "posts:42", func() (interface{}, error) {
+			return "posts", nil
+		}, &out, time.Minute)
+		assert.Nil(t, err)
+	}
+
+	stats, err := manager.Stats("posts")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.LoaderCalls)
+	assert.Equal(t, uint64(1), stats.Sets)
+
+	_, err = manager.Stats("not_found")
+	assert.Equal(t, "Module not found", err.Error())
+}
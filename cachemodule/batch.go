@@ -0,0 +1,205 @@
+package cachemodule
+
+import (
+	"context"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	gcStats "github.com/josemiguelmelo/gocacheable/stats"
+)
+
+// ErrNotFound is returned when decoding the result of a key not found. It is
+// the error providers return for missing keys.
+var ErrNotFound = gcInterfaces.ErrNotFound
+
+// GetResult is the result of a key read by GetMany
+type GetResult struct {
+	Key string
+	// Found is true if a usable entry was found for the key
+	Found bool
+	// Stale is true if the soft time to live of the value has elapsed
+	Stale bool
+	// Err is the loader error replayed from a negative cache entry. When Found
+	// is false, it is the reason an entry stored under the key was not usable.
+	Err error
+
+	decode func(out interface{}) error
+}
+
+// Decode decodes the value found into out, which must be a pointer. It returns
+// Err if set, and ErrNotFound if the key was not found.
+func (r GetResult) Decode(out interface{}) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	if !r.Found {
+		return ErrNotFound
+	}
+	return r.decode(out)
+}
+
+// GetMany returns the results of keys, in the same order
+func (cm CacheModule) GetMany(keys []string) ([]GetResult, error) {
+	return cm.GetManyContext(context.Background(), keys)
+}
+
+// GetManyContext returns the results of keys, in the same order. Providers
+// implementing BatchCacheProviderInterface read every key in a single call,
+// others are read one key at a time. Missing keys are not an error.
+func (cm CacheModule) GetManyContext(ctx context.Context, keys []string) ([]GetResult, error) {
+	values, err := cm.getMany(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]GetResult, len(keys))
+	for i, key := range keys {
+		results[i] = GetResult{Key: key}
+		valueByte, ok := values[key]
+		if !ok {
+			cm.stats.RecordMiss()
+			continue
+		}
+
+		e, err := cm.open(key, valueByte)
+		if err != nil {
+			cm.stats.RecordMiss()
+			results[i].Err = err
+			continue
+		}
+		cm.stats.RecordHit()
+		results[i] = cm.result(key, e)
+	}
+	return results, nil
+}
+
+// result returns the result of the entry found under key
+func (cm CacheModule) result(key string, e entry) GetResult {
+	if e.kind == entryError {
+		return GetResult{Key: key, Found: true, Err: cm.negativeCaching.decodeError(e.payload)}
+	}
+	return GetResult{
+		Key:   key,
+		Found: true,
+		Stale: e.isStale(cm.now()),
+		decode: func(out interface{}) error {
+			_, err := cm.decodeValue(e, out)
+			return err
+		},
+	}
+}
+
+// getMany returns the stored values of the keys found
+func (cm CacheModule) getMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if batch, ok := cm.cacheStorage.(gcInterfaces.BatchCacheProviderInterface); ok {
+		start := time.Now()
+		values, err := batch.GetManyContext(ctx, keys)
+		cm.observe(gcStats.GetMany, start, err)
+		return values, err
+	}
+
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		start := time.Now()
+		valueByte, err := cm.cacheStorage.GetContext(ctx, key)
+		cm.observeRead(start, err)
+		if err != nil {
+			// Like single reads, errors are misses unless ctx is done
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		values[key] = valueByte
+	}
+	return values, nil
+}
+
+// SetMany caches values, expiring after timeToLive
+func (cm *CacheModule) SetMany(values map[string]interface{}, timeToLive time.Duration, options ...EntryOption) error {
+	return cm.SetManyContext(context.Background(), values, timeToLive, options...)
+}
+
+// SetManyContext caches values, expiring after timeToLive. A zero timeToLive
+// caches the values without expiration, a negative one is rejected with
+// ErrNegativeTimeToLive.
+func (cm *CacheModule) SetManyContext(ctx context.Context, values map[string]interface{}, timeToLive time.Duration, options ...EntryOption) error {
+	if timeToLive < 0 {
+		return ErrNegativeTimeToLive
+	}
+	config := newEntryConfig(options)
+
+	sealed := make(map[string][]byte, len(values))
+	for key, value := range values {
+		e, err := cm.valueEntry(value, config)
+		if err != nil {
+			return err
+		}
+		if sealed[key], err = cm.seal(key, e); err != nil {
+			return err
+		}
+	}
+
+	if err := cm.setMany(ctx, sealed, timeToLive); err != nil {
+		return err
+	}
+	for key := range sealed {
+		if err := cm.addTags(ctx, key, config.tags, timeToLive); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setMany stores sealed values, expiring after timeToLive
+func (cm *CacheModule) setMany(ctx context.Context, values map[string][]byte, timeToLive time.Duration) error {
+	if batch, ok := cm.cacheStorage.(gcInterfaces.BatchCacheProviderInterface); ok {
+		start := time.Now()
+		err := batch.SetManyContext(ctx, values, timeToLive)
+		cm.observe(gcStats.SetMany, start, err)
+		if err != nil {
+			return err
+		}
+		cm.stats.RecordSets(len(values))
+		for _, valueByte := range values {
+			cm.stats.RecordValueSize(len(valueByte))
+		}
+		return nil
+	}
+	for key, valueByte := range values {
+		start := time.Now()
+		err := cm.cacheStorage.SetWithTTLContext(ctx, key, valueByte, timeToLive)
+		cm.observe(gcStats.Set, start, err)
+		if err != nil {
+			return err
+		}
+		cm.stats.RecordSets(1)
+		cm.stats.RecordValueSize(len(valueByte))
+	}
+	return nil
+}
+
+// DeleteMany removes keys from the cache storage
+func (cm *CacheModule) DeleteMany(keys []string) error {
+	return cm.DeleteManyContext(context.Background(), keys)
+}
+
+// DeleteManyContext removes keys from the cache storage
+func (cm *CacheModule) DeleteManyContext(ctx context.Context, keys []string) error {
+	if batch, ok := cm.cacheStorage.(gcInterfaces.BatchCacheProviderInterface); ok {
+		start := time.Now()
+		err := batch.DeleteManyContext(ctx, keys)
+		cm.observe(gcStats.DeleteMany, start, err)
+		if err != nil {
+			return err
+		}
+		cm.stats.RecordDeletes(len(keys))
+		return nil
+	}
+	for _, key := range keys {
+		if err := cm.DeleteContext(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package cachemodule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gcCompression "github.com/josemiguelmelo/gocacheable/compression"
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	bcProvider "github.com/josemiguelmelo/gocacheable/providers/bigcache"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchProvider adds batch operations to bigcache, counting their calls
+type batchProvider struct {
+	*bcProvider.BigCacheProvider
+	batchCalls int
+}
+
+func (p *batchProvider) GetMany(keys []string) (map[string][]byte, error) {
+	return p.GetManyContext(context.Background(), keys)
+}
+
+func (p *batchProvider) SetMany(values map[string][]byte, ttl time.Duration) error {
+	return p.SetManyContext(context.Background(), values, ttl)
+}
+
+func (p *batchProvider) DeleteMany(keys []string) error {
+	return p.DeleteManyContext(context.Background(), keys)
+}
+
+func (p *batchProvider) GetManyContext(ctx context.Context, keys []string) (map[string][]byte, error) {
+	p.batchCalls++
+	values := map[string][]byte{}
+	for _, key := range keys {
+		if value, err := p.GetContext(ctx, key); err == nil {
+			values[key] = value
+		}
+	}
+	return values, ctx.Err()
+}
+
+func (p *batchProvider) SetManyContext(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+	p.batchCalls++
+	for key, value := range values {
+		if err := p.SetWithTTLContext(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *batchProvider) DeleteManyContext(ctx context.Context, keys []string) error {
+	p.batchCalls++
+	for _, key := range keys {
+		if err := p.DeleteContext(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newBatchModule(t *testing.T, options ...Option) (*CacheModule, *batchProvider) {
+	storage := &batchProvider{BigCacheProvider: &bcProvider.BigCacheProvider{Lifetime: 2}}
+	assert.Nil(t, storage.Init())
+	t.Cleanup(func() { storage.Close() })
+	module := New("test module", storage, options...)
+	return &module, storage
+}
+
+func assertBatch(t *testing.T, module *CacheModule) {
+	err := module.SetMany(map[string]interface{}{"a": "value a", "b": "value b", "c": "value c"}, time.Minute)
+	assert.Nil(t, err)
+
+	results, err := module.GetMany([]string{"a", "missing", "c"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(results))
+
+	var value string
+	assert.Equal(t, "a", results[0].Key)
+	assert.True(t, results[0].Found)
+	assert.Nil(t, results[0].Decode(&value))
+	assert.Equal(t, "value a", value)
+
+	assert.Equal(t, "missing", results[1].Key)
+	assert.False(t, results[1].Found)
+	assert.Equal(t, ErrNotFound, results[1].Decode(&value))
+	assert.True(t, errors.Is(results[1].Decode(&value), gcInterfaces.ErrNotFound))
+
+	assert.True(t, results[2].Found)
+	assert.Nil(t, results[2].Decode(&value))
+	assert.Equal(t, "value c", value)
+
+	assert.Nil(t, module.DeleteMany([]string{"a", "b", "missing"}))
+	results, err = module.GetMany([]string{"a", "b", "c"})
+	assert.Nil(t, err)
+	assert.False(t, results[0].Found)
+	assert.False(t, results[1].Found)
+	assert.True(t, results[2].Found)
+}
+
+func TestBatchFallback(t *testing.T) {
+	module := newTestModule(t)
+	assertBatch(t, module)
+}
+
+func TestBatchProvider(t *testing.T) {
+	module, storage := newBatchModule(t)
+	assertBatch(t, module)
+	// SetMany, GetMany, DeleteMany and GetMany again
+	assert.Equal(t, 4, storage.batchCalls)
+}
+
+func TestBatchEncryptedAndCompressed(t *testing.T) {
+	module, _ := newBatchModule(t, WithEncryption(newKeyring(t, "v1")), WithCompression(gcCompression.Gzip, 0))
+	assertBatch(t, module)
+
+	// Entries are bound to their key, so swapped values are unusable
+	assert.Nil(t, module.Set("x", "value x"))
+	data, err := module.cacheStorage.Get("x")
+	assert.Nil(t, err)
+	assert.Nil(t, module.cacheStorage.Set("y", data))
+
+	results, err := module.GetMany([]string{"x", "y"})
+	assert.Nil(t, err)
+	assert.True(t, results[0].Found)
+	assert.False(t, results[1].Found)
+	assert.NotNil(t, results[1].Err)
+}
+
+func TestBatchNegativeAndStaleEntries(t *testing.T) {
+	module := newNegativeModule(t)
+	now := time.Now()
+	module.now = func() time.Time { return now }
+
+	_, err := module.LoadContext(context.Background(), "negative", countingLoader(new(int), nil, errNotFound), time.Minute)
+	assert.Equal(t, errNotFound, err)
+	assert.Nil(t, module.Set("stale", "value", WithSoftTTL(time.Second)))
+	now = now.Add(2 * time.Second)
+
+	results, err := module.GetMany([]string{"negative", "stale"})
+	assert.Nil(t, err)
+	assert.True(t, results[0].Found)
+	assert.True(t, errors.Is(results[0].Err, errNotFound))
+
+	var value string
+	assert.True(t, errors.Is(results[0].Decode(&value), errNotFound))
+	assert.True(t, results[1].Found)
+	assert.True(t, results[1].Stale)
+	assert.Nil(t, results[1].Decode(&value))
+	assert.Equal(t, "value", value)
+}
+
+func TestBatchContextCanceled(t *testing.T) {
+	module := newTestModule(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := module.GetManyContext(ctx, []string{"a"})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, module.SetManyContext(ctx, map[string]interface{}{"a": "value"}, time.Minute))
+	assert.Equal(t, context.Canceled, module.DeleteManyContext(ctx, []string{"a"}))
+}
@@ -1,26 +1,74 @@
 package cachemodule
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
+	gcCodec "github.com/josemiguelmelo/gocacheable/codec"
+	gcCompression "github.com/josemiguelmelo/gocacheable/compression"
+	gcEncryption "github.com/josemiguelmelo/gocacheable/encryption"
+	"github.com/josemiguelmelo/gocacheable/events"
 	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	gcStats "github.com/josemiguelmelo/gocacheable/stats"
+	"github.com/sirupsen/logrus"
 )
 
+// ErrNegativeTimeToLive is returned when caching a value for a negative time to live
+var ErrNegativeTimeToLive = errors.New("Negative time to live")
+
 // CacheModule represents an applicational module that contains cache
 type CacheModule struct {
-	Identifier   string
-	Name         string
-	cacheStorage gcInterfaces.CacheProviderInterface
+	Identifier       string
+	Name             string
+	cacheStorage     gcInterfaces.CacheProviderInterface
+	codec            gcCodec.Codec
+	compression      gcCompression.Format
+	compressionMin   int
+	decompressionMax int
+	keyring          *gcEncryption.Keyring
+	singleFlight     bool
+	flights          *flightGroup
+	refreshQueueSize int
+	refresher        *refresher
+	negativeCaching  NegativeCaching
+	now              func() time.Time
+	tags             *tagIndex
+	stats            *gcStats.Recorder
+	eventQueue       []events.QueueOption
+}
+
+// LookupResult describes a value found in the cache storage
+type LookupResult struct {
+	// Stale is true if the soft time to live of the value has elapsed
+	Stale bool
+	// Err is the loader error replayed from a negative cache entry. The out
+	// value is left untouched when it is set.
+	Err error
 }
 
 // New create and returns new CacheModule object
-func New(name string, cacheStorage gcInterfaces.CacheProviderInterface) CacheModule {
-	return CacheModule{
-		Identifier:   generateIdentifier(name),
-		Name:         name,
-		cacheStorage: cacheStorage,
+func New(name string, cacheStorage gcInterfaces.CacheProviderInterface, options ...Option) CacheModule {
+	module := CacheModule{
+		Identifier:       generateIdentifier(name),
+		Name:             name,
+		cacheStorage:     cacheStorage,
+		codec:            gcCodec.JSONCodec{},
+		singleFlight:     true,
+		flights:          newFlightGroup(),
+		refreshQueueSize: defaultRefreshQueueSize,
+		now:              time.Now,
+		tags:             newTagIndex(),
+		stats:            gcStats.NewRecorder(),
+	}
+
+	for _, option := range options {
+		option(&module)
 	}
+	module.refresher = newRefresher(module.refreshQueueSize)
+	return module
 }
 
 func generateIdentifier(name string) string {
@@ -28,58 +76,346 @@ func generateIdentifier(name string) string {
 	return strings.ToLower(identifier)
 }
 
+// EventQueueOptions returns the options of the event queues of the module
+func (cm CacheModule) EventQueueOptions() []events.QueueOption {
+	return append([]events.QueueOption{}, cm.eventQueue...)
+}
+
 // IsCacheStorageCreated returns true if module cache storage is already created
 func (cm CacheModule) IsCacheStorageCreated() bool {
 	return cm.cacheStorage == nil
 }
 
-// Get returns a cached value
+// Get returns a cached value decoded into out, which must be a pointer
 func (cm CacheModule) Get(key string, out interface{}) error {
-	var obj interface{}
-	err := getFromCache(cm.cacheStorage, key, &obj)
-	if err == nil {
-		jsonData, err := json.Marshal(obj)
-		if err == nil {
-			return json.Unmarshal(jsonData, &out)
-		}
-		out = obj
+	return cm.GetContext(context.Background(), key, out)
+}
+
+// GetContext returns a cached value decoded into out, which must be a pointer.
+// Stale values are returned as well, and cached loader errors are replayed.
+func (cm CacheModule) GetContext(ctx context.Context, key string, out interface{}) error {
+	result, err := cm.LookupContext(ctx, key, out)
+	if err != nil {
 		return err
 	}
-	return err
+	return result.Err
+}
+
+// LookupContext returns a cached value decoded into out, which must be a
+// pointer. An error is only returned when no usable entry was found.
+func (cm CacheModule) LookupContext(ctx context.Context, key string, out interface{}) (LookupResult, error) {
+	e, err := cm.getEntry(ctx, key)
+	if err != nil {
+		cm.stats.RecordMiss()
+		return LookupResult{}, err
+	}
+	cm.stats.RecordHit()
+	return cm.decodeValue(e, out)
+}
+
+// decodeValue decodes the value of e into out, or returns the loader error it holds
+func (cm CacheModule) decodeValue(e entry, out interface{}) (LookupResult, error) {
+	if e.kind == entryError {
+		return LookupResult{Err: cm.negativeCaching.decodeError(e.payload)}, nil
+	}
+
+	payload, err := gcCompression.DecompressMax(e.compression, e.payload, cm.maxDecompressedSize())
+	if err != nil {
+		return LookupResult{}, err
+	}
+	if err = cm.codec.Unmarshal(payload, out); err != nil {
+		return LookupResult{}, err
+	}
+	return LookupResult{Stale: e.isStale(cm.now())}, nil
 }
 
 // Set caches a value
-func (cm *CacheModule) Set(key string, value interface{}) error {
-	return addToCache(cm.cacheStorage, key, value)
+func (cm *CacheModule) Set(key string, value interface{}, options ...EntryOption) error {
+	return cm.SetContext(context.Background(), key, value, options...)
+}
+
+// SetContext caches a value
+func (cm *CacheModule) SetContext(ctx context.Context, key string, value interface{}, options ...EntryOption) error {
+	return cm.SetWithTTLContext(ctx, key, value, 0, options...)
+}
+
+// SetWithTTL caches a value that expires after timeToLive
+func (cm *CacheModule) SetWithTTL(key string, value interface{}, timeToLive time.Duration, options ...EntryOption) error {
+	return cm.SetWithTTLContext(context.Background(), key, value, timeToLive, options...)
+}
+
+// SetWithTTLContext caches a value that expires after timeToLive. A zero
+// timeToLive caches the value without expiration, a negative one is rejected
+// with ErrNegativeTimeToLive.
+func (cm *CacheModule) SetWithTTLContext(ctx context.Context, key string, value interface{}, timeToLive time.Duration, options ...EntryOption) error {
+	if timeToLive < 0 {
+		return ErrNegativeTimeToLive
+	}
+	config := newEntryConfig(options)
+
+	e, err := cm.valueEntry(value, config)
+	if err != nil {
+		return err
+	}
+	if err = cm.setEntry(ctx, key, e, timeToLive); err != nil {
+		return err
+	}
+	return cm.addTags(ctx, key, config.tags, timeToLive)
+}
+
+// valueEntry encodes and compresses value into an entry
+func (cm *CacheModule) valueEntry(value interface{}, config entryConfig) (entry, error) {
+	payload, err := cm.codec.Marshal(value)
+	if err != nil {
+		return entry{}, err
+	}
+
+	e := entry{kind: entryValue, payload: payload}
+	if err = cm.compress(&e); err != nil {
+		return entry{}, err
+	}
+	if config.softTimeToLive > 0 {
+		e.softExpiresAt = cm.now().Add(config.softTimeToLive)
+	}
+	return e, nil
+}
+
+// Convert stores value into out, which must be a pointer, through the module
+// codec, so that out holds exactly what a read from the cache would return
+func (cm CacheModule) Convert(value interface{}, out interface{}) error {
+	data, err := cm.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return cm.codec.Unmarshal(data, out)
 }
 
 // Delete removes a key from the cache storage
 func (cm *CacheModule) Delete(key string) error {
-	return cm.cacheStorage.Delete(key)
+	return cm.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext removes a key from the cache storage
+func (cm *CacheModule) DeleteContext(ctx context.Context, key string) error {
+	start := time.Now()
+	err := cm.cacheStorage.DeleteContext(ctx, key)
+	cm.observe(gcStats.Delete, start, err)
+	if err != nil {
+		return err
+	}
+	cm.stats.RecordDeletes(1)
+	return nil
 }
 
 // Reset empties the cache storage
 func (cm *CacheModule) Reset() error {
-	return cm.cacheStorage.Reset()
+	return cm.ResetContext(context.Background())
 }
 
+// ResetContext empties the cache storage
+func (cm *CacheModule) ResetContext(ctx context.Context) error {
+	start := time.Now()
+	err := cm.cacheStorage.ResetContext(ctx)
+	cm.observe(gcStats.Reset, start, err)
+	if err != nil {
+		return err
+	}
+	cm.tags.reset()
+	return nil
+}
+
+// HasKey checks if the key exists in the cache storage
 func (cm *CacheModule) HasKey(key string) bool {
-	return cm.cacheStorage.HasKey(key)
+	return cm.HasKeyContext(context.Background(), key)
 }
 
-func getFromCache(cacheStorage gcInterfaces.CacheProviderInterface, key string, out interface{}) error {
-	valueByte, err := cacheStorage.Get(key)
-	if err == nil {
-		return json.Unmarshal(valueByte, &out)
+// HasKeyContext checks if the key exists in the cache storage
+func (cm *CacheModule) HasKeyContext(ctx context.Context, key string) bool {
+	start := time.Now()
+	found := cm.cacheStorage.HasKeyContext(ctx, key)
+	cm.observe(gcStats.HasKey, start, nil)
+	return found
+}
+
+// Load calls loader and caches its result under key for timeToLive. Concurrent
+// loads of the same key share a single loader call unless single flight was
+// disabled for the module.
+func (cm *CacheModule) Load(key string, loader func() (interface{}, error), timeToLive time.Duration, options ...EntryOption) (interface{}, error) {
+	return cm.LoadContext(context.Background(), key, func(context.Context) (interface{}, error) {
+		return loader()
+	}, timeToLive, options...)
+}
+
+// LoadContext calls loader and caches its result under key for timeToLive.
+// The call is abandoned as soon as ctx is done. A negative timeToLive is
+// rejected with ErrNegativeTimeToLive before calling loader.
+func (cm *CacheModule) LoadContext(ctx context.Context, key string, loader func(context.Context) (interface{}, error), timeToLive time.Duration, options ...EntryOption) (interface{}, error) {
+	if timeToLive < 0 {
+		return nil, ErrNegativeTimeToLive
+	}
+	load := func(ctx context.Context) (interface{}, error) {
+		finish := cm.stats.StartLoader()
+		value, err := loader(ctx)
+		finish(err)
+		if err != nil {
+			// A failure to cache the loader error, counted in the provider
+			// errors, must not hide it from the caller
+			if cm.negativeCaching.matches(err) {
+				if setErr := cm.setError(ctx, key, err, options...); setErr != nil {
+					logrus.Errorln(fmt.Sprintf("Error caching loader error with key = %s from module %s with err = %s", key, cm.Name, setErr.Error()))
+				}
+			}
+			return value, err
+		}
+
+		entryTimeToLive := timeToLive
+		if cm.negativeCaching.isEmpty(value) {
+			entryTimeToLive = cm.negativeCaching.TimeToLive
+		}
+		if err = cm.SetWithTTLContext(ctx, key, value, entryTimeToLive, options...); err != nil {
+			return value, err
+		}
+		return value, nil
+	}
+
+	if cm.singleFlight {
+		return cm.flights.do(ctx, key, load)
+	}
+	return callWithContext(ctx, load)
+}
+
+// Refresh schedules a background LoadContext of key on the module refresh
+// worker. It returns false if the refresh was not scheduled because one is
+// already pending for the key, the queue is full or the module was closed.
+func (cm *CacheModule) Refresh(ctx context.Context, key string, loader func(context.Context) (interface{}, error), timeToLive time.Duration, options ...EntryOption) bool {
+	return cm.refresher.schedule(cm, refreshJob{
+		ctx:        ctx,
+		key:        key,
+		loader:     loader,
+		timeToLive: timeToLive,
+		options:    options,
+	})
+}
+
+// Close stops the module background refresh worker, cancelling any refresh in progress
+func (cm *CacheModule) Close() error {
+	cm.refresher.stop()
+	return nil
+}
+
+type loadResult struct {
+	value interface{}
+	err   error
+}
+
+// callWithContext calls f, returning ctx.Err() as soon as ctx is done even if f
+// does not honour the context itself
+func callWithContext(ctx context.Context, f func(context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return f(ctx)
 	}
-	return err
+
+	done := make(chan loadResult, 1)
+	go func() {
+		value, err := f(ctx)
+		done <- loadResult{value: value, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.value, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// maxDecompressedSize returns the size, in bytes, values can be decompressed to
+func (cm CacheModule) maxDecompressedSize() int {
+	if cm.decompressionMax <= 0 {
+		return gcCompression.DefaultMaxSize
+	}
+	return cm.decompressionMax
 }
 
-func addToCache(cacheStorage gcInterfaces.CacheProviderInterface, key string, value interface{}) error {
-	valueByte, _ := json.Marshal(value)
-	err := cacheStorage.Set(key, valueByte)
+// compress compresses the entry payload with the module compression format
+// when it is at least compressionMin bytes long and compressing makes it smaller
+func (cm *CacheModule) compress(e *entry) error {
+	if cm.compression == gcCompression.None || len(e.payload) < cm.compressionMin {
+		return nil
+	}
+
+	compressed, err := gcCompression.Compress(cm.compression, e.payload)
 	if err != nil {
 		return err
 	}
+	if len(compressed) < len(e.payload) {
+		e.payload = compressed
+		e.compression = cm.compression
+	}
 	return nil
 }
+
+func (cm *CacheModule) getEntry(ctx context.Context, key string) (entry, error) {
+	start := time.Now()
+	valueByte, err := cm.cacheStorage.GetContext(ctx, key)
+	cm.observeRead(start, err)
+	if err != nil {
+		return entry{}, err
+	}
+	return cm.open(key, valueByte)
+}
+
+// open decodes the entry stored under key
+func (cm *CacheModule) open(key string, valueByte []byte) (entry, error) {
+	// Modules with encryption only accept encrypted entries, bound to their key
+	if cm.keyring != nil {
+		var err error
+		if valueByte, err = cm.keyring.Decrypt(valueByte, []byte(key)); err != nil {
+			return entry{}, err
+		}
+	}
+	return decodeEntry(valueByte)
+}
+
+// setError caches a loader error for the negative caching time to live
+func (cm *CacheModule) setError(ctx context.Context, key string, loaderErr error, options ...EntryOption) error {
+	payload, err := cm.negativeCaching.encodeError(loaderErr)
+	if err != nil {
+		return err
+	}
+
+	timeToLive := cm.negativeCaching.TimeToLive
+	if err = cm.setEntry(ctx, key, entry{kind: entryError, payload: payload}, timeToLive); err != nil {
+		return err
+	}
+	return cm.addTags(ctx, key, newEntryConfig(options).tags, timeToLive)
+}
+
+func (cm *CacheModule) setEntry(ctx context.Context, key string, e entry, timeToLive time.Duration) error {
+	valueByte, err := cm.seal(key, e)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = cm.cacheStorage.SetWithTTLContext(ctx, key, valueByte, timeToLive)
+	cm.observe(gcStats.Set, start, err)
+	if err != nil {
+		return err
+	}
+	cm.stats.RecordSets(1)
+	cm.stats.RecordValueSize(len(valueByte))
+	return nil
+}
+
+// seal encodes e to be stored under key, encrypting it if the module has a keyring
+func (cm *CacheModule) seal(key string, e entry) ([]byte, error) {
+	valueByte := encodeEntry(e)
+	if cm.keyring == nil {
+		return valueByte, nil
+	}
+	return cm.keyring.Encrypt(valueByte, []byte(key))
+}
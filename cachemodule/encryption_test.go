@@ -0,0 +1,64 @@
+package cachemodule
+
+import (
+	"bytes"
+	"testing"
+
+	gcCompression "github.com/josemiguelmelo/gocacheable/compression"
+	gcEncryption "github.com/josemiguelmelo/gocacheable/encryption"
+	"github.com/stretchr/testify/assert"
+)
+
+func newKeyring(t *testing.T, primary string) *gcEncryption.Keyring {
+	keyring, err := gcEncryption.NewKeyring(primary, map[string][]byte{
+		"v1": bytes.Repeat([]byte{1}, 32),
+		"v2": bytes.Repeat([]byte{2}, 32),
+	})
+	assert.Nil(t, err)
+	return keyring
+}
+
+func TestEncryptedEntries(t *testing.T) {
+	module := newTestModule(t, WithEncryption(newKeyring(t, "v1")), WithCompression(gcCompression.Gzip, 0))
+
+	assert.Nil(t, module.Set("user", "john.doe@example.com"))
+	data, err := module.cacheStorage.Get("user")
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(data, []byte("john.doe")))
+
+	var value string
+	assert.Nil(t, module.Get("user", &value))
+	assert.Equal(t, "john.doe@example.com", value)
+
+	// An encrypted value copied under another key is rejected
+	assert.Nil(t, module.cacheStorage.Set("other", data))
+	assert.NotNil(t, module.Get("other", &value))
+}
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	storage := newTestModule(t).cacheStorage
+	before := New("test module", storage, WithEncryption(newKeyring(t, "v1")))
+	after := New("test module", storage, WithEncryption(newKeyring(t, "v2")))
+
+	assert.Nil(t, before.Set("old", "old value"))
+	assert.Nil(t, after.Set("new", "new value"))
+
+	var value string
+	assert.Nil(t, after.Get("old", &value))
+	assert.Equal(t, "old value", value)
+	assert.Nil(t, before.Get("new", &value))
+	assert.Equal(t, "new value", value)
+}
+
+func TestEncryptionRejectsPlainEntries(t *testing.T) {
+	storage := newTestModule(t).cacheStorage
+	plain := New("test module", storage)
+	encrypted := New("test module", storage, WithEncryption(newKeyring(t, "v1")))
+
+	assert.Nil(t, plain.Set("plain", "value"))
+	assert.Nil(t, encrypted.Set("encrypted", "value"))
+
+	var value string
+	assert.NotNil(t, encrypted.Get("plain", &value))
+	assert.Equal(t, ErrInvalidEntry, plain.Get("encrypted", &value))
+}
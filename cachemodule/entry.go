@@ -0,0 +1,90 @@
+package cachemodule
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	gcCompression "github.com/josemiguelmelo/gocacheable/compression"
+)
+
+// Stored entries are framed with a header holding the metadata the module
+// needs besides the value itself:
+//
+//	version (1 byte) | kind (1 byte) | compression (1 byte) | soft expiration (8 bytes, unix nanoseconds) | payload
+//
+// Version 1 entries have no compression byte and are read as uncompressed.
+const (
+	entryVersion            byte = 2
+	entryHeaderSize              = 11
+	entryVersion1           byte = 1
+	entryVersion1HeaderSize      = 10
+)
+
+// entry kinds
+const (
+	entryValue byte = iota
+	// entryError holds a loader error kept by negative caching
+	entryError
+)
+
+// ErrInvalidEntry is returned when a stored value is not a valid module entry
+var ErrInvalidEntry = errors.New("Invalid cache entry")
+
+// entry is a value stored by a module along with its metadata
+type entry struct {
+	kind byte
+	// compression is the format the payload is compressed with
+	compression gcCompression.Format
+	// softExpiresAt is the time after which the value is stale and must be
+	// refreshed in background. Zero means it never becomes stale.
+	softExpiresAt time.Time
+	payload       []byte
+}
+
+// isStale returns true if the soft time to live of the entry has elapsed
+func (e entry) isStale(now time.Time) bool {
+	return !e.softExpiresAt.IsZero() && !now.Before(e.softExpiresAt)
+}
+
+func encodeEntry(e entry) []byte {
+	data := make([]byte, entryHeaderSize+len(e.payload))
+	data[0] = entryVersion
+	data[1] = e.kind
+	data[2] = byte(e.compression)
+
+	var softExpiresAt int64
+	if !e.softExpiresAt.IsZero() {
+		softExpiresAt = e.softExpiresAt.UnixNano()
+	}
+	binary.BigEndian.PutUint64(data[3:entryHeaderSize], uint64(softExpiresAt))
+	copy(data[entryHeaderSize:], e.payload)
+	return data
+}
+
+func decodeEntry(data []byte) (entry, error) {
+	if len(data) == 0 {
+		return entry{}, ErrInvalidEntry
+	}
+
+	var e entry
+	var softExpiresAt int64
+	switch {
+	case data[0] == entryVersion && len(data) >= entryHeaderSize:
+		e.kind = data[1]
+		e.compression = gcCompression.Format(data[2])
+		softExpiresAt = int64(binary.BigEndian.Uint64(data[3:entryHeaderSize]))
+		e.payload = data[entryHeaderSize:]
+	case data[0] == entryVersion1 && len(data) >= entryVersion1HeaderSize:
+		e.kind = data[1]
+		softExpiresAt = int64(binary.BigEndian.Uint64(data[2:entryVersion1HeaderSize]))
+		e.payload = data[entryVersion1HeaderSize:]
+	default:
+		return entry{}, ErrInvalidEntry
+	}
+
+	if softExpiresAt != 0 {
+		e.softExpiresAt = time.Unix(0, softExpiresAt)
+	}
+	return e, nil
+}
@@ -0,0 +1,98 @@
+package cachemodule
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	gcCompression "github.com/josemiguelmelo/gocacheable/compression"
+	"github.com/stretchr/testify/assert"
+)
+
+func storedEntry(t *testing.T, module *CacheModule, key string) entry {
+	data, err := module.cacheStorage.Get(key)
+	assert.Nil(t, err)
+	e, err := decodeEntry(data)
+	assert.Nil(t, err)
+	return e
+}
+
+func TestEntryRoundTrip(t *testing.T) {
+	softExpiresAt := time.Unix(0, 1557833581123456789)
+	e := entry{kind: entryError, compression: gcCompression.Zstd, softExpiresAt: softExpiresAt, payload: []byte("payload")}
+
+	decoded, err := decodeEntry(encodeEntry(e))
+	assert.Nil(t, err)
+	assert.Equal(t, e.kind, decoded.kind)
+	assert.Equal(t, e.compression, decoded.compression)
+	assert.True(t, softExpiresAt.Equal(decoded.softExpiresAt))
+	assert.Equal(t, "payload", string(decoded.payload))
+
+	_, err = decodeEntry([]byte("\"json value\""))
+	assert.Equal(t, ErrInvalidEntry, err)
+	_, err = decodeEntry(nil)
+	assert.Equal(t, ErrInvalidEntry, err)
+}
+
+func TestDecodeVersion1Entry(t *testing.T) {
+	data := append([]byte{entryVersion1, entryValue, 0, 0, 0, 0, 0, 0, 0, 0}, []byte("\"value\"")...)
+
+	e, err := decodeEntry(data)
+	assert.Nil(t, err)
+	assert.Equal(t, gcCompression.None, e.compression)
+	assert.True(t, e.softExpiresAt.IsZero())
+	assert.Equal(t, "\"value\"", string(e.payload))
+}
+
+func TestCompressionThreshold(t *testing.T) {
+	module := newTestModule(t, WithCompression(gcCompression.Gzip, 100))
+	large := strings.Repeat("rendered page ", 100)
+
+	assert.Nil(t, module.Set("large", large))
+	assert.Nil(t, module.Set("small", "small"))
+	assert.Equal(t, gcCompression.Gzip, storedEntry(t, module, "large").compression)
+	assert.Equal(t, gcCompression.None, storedEntry(t, module, "small").compression)
+
+	var value string
+	assert.Nil(t, module.Get("large", &value))
+	assert.Equal(t, large, value)
+	assert.Nil(t, module.Get("small", &value))
+	assert.Equal(t, "small", value)
+}
+
+func TestCompressionConfigurationChange(t *testing.T) {
+	storage := newTestModule(t).cacheStorage
+	large := strings.Repeat("api response ", 100)
+
+	uncompressed := New("test module", storage)
+	zstdModule := New("test module", storage, WithCompression(gcCompression.Zstd, 0))
+	snappyModule := New("test module", storage, WithCompression(gcCompression.Snappy, 0))
+
+	assert.Nil(t, uncompressed.SetContext(context.Background(), "plain", large))
+	assert.Nil(t, zstdModule.SetContext(context.Background(), "zstd", large))
+	assert.Nil(t, snappyModule.SetContext(context.Background(), "snappy", large))
+
+	// Every module reads every entry, whatever its own configuration
+	for _, module := range []CacheModule{uncompressed, zstdModule, snappyModule} {
+		for _, key := range []string{"plain", "zstd", "snappy"} {
+			var value string
+			assert.Nil(t, module.Get(key, &value))
+			assert.Equal(t, large, value)
+		}
+	}
+}
+
+func TestMaxDecompressedSize(t *testing.T) {
+	storage := newTestModule(t).cacheStorage
+	large := strings.Repeat("api response ", 100)
+
+	writer := New("test module", storage, WithCompression(gcCompression.Zstd, 0))
+	reader := New("test module", storage, WithMaxDecompressedSize(len(large)))
+	assert.Nil(t, writer.Set("large", large))
+
+	var value string
+	assert.Equal(t, gcCompression.ErrTooLarge, reader.Get("large", &value))
+	assert.Nil(t, writer.Get("large", &value))
+	assert.Equal(t, large, value)
+}
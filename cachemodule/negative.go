@@ -0,0 +1,108 @@
+package cachemodule
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// NegativeCaching configures the caching of loader errors and empty results,
+// so that a failing or empty backend is not called on every request
+type NegativeCaching struct {
+	// TimeToLive of negative entries. Negative caching is disabled unless it is
+	// greater than zero.
+	TimeToLive time.Duration
+	// Errors are sentinel errors, matched with errors.Is, whose occurrences are
+	// cached. They are replayed so that errors.Is keeps matching the sentinel.
+	Errors []error
+	// Match selects other errors to cache, for instance by type with errors.As.
+	// They are replayed as a *CachedError carrying the original message.
+	Match func(error) bool
+	// IsEmpty selects loader results that are cached for TimeToLive instead of
+	// the time to live of the call
+	IsEmpty func(value interface{}) bool
+}
+
+// CachedError is a loader error replayed from a negative cache entry
+type CachedError struct {
+	Message string
+	// Err is the sentinel error the original error matched, if any
+	Err error
+}
+
+func (e *CachedError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the sentinel error the original error matched
+func (e *CachedError) Unwrap() error {
+	return e.Err
+}
+
+// negativePayload is the stored form of a cached loader error
+type negativePayload struct {
+	Message  string `json:"message"`
+	Sentinel string `json:"sentinel,omitempty"`
+}
+
+func (nc NegativeCaching) enabled() bool {
+	return nc.TimeToLive > 0
+}
+
+// sentinel returns the configured sentinel matched by err
+func (nc NegativeCaching) sentinel(err error) error {
+	for _, sentinel := range nc.Errors {
+		if errors.Is(err, sentinel) {
+			return sentinel
+		}
+	}
+	return nil
+}
+
+// matches returns true if err must be cached
+func (nc NegativeCaching) matches(err error) bool {
+	if !nc.enabled() {
+		return false
+	}
+	if nc.sentinel(err) != nil {
+		return true
+	}
+	return nc.Match != nil && nc.Match(err)
+}
+
+// isEmpty returns true if value must be cached as an empty result
+func (nc NegativeCaching) isEmpty(value interface{}) bool {
+	return nc.enabled() && nc.IsEmpty != nil && nc.IsEmpty(value)
+}
+
+func (nc NegativeCaching) encodeError(err error) ([]byte, error) {
+	payload := negativePayload{Message: err.Error()}
+	if sentinel := nc.sentinel(err); sentinel != nil {
+		payload.Sentinel = sentinel.Error()
+	}
+	return json.Marshal(payload)
+}
+
+// decodeError rebuilds a cached loader error. Errors that were a configured
+// sentinel are returned as the sentinel itself.
+func (nc NegativeCaching) decodeError(data []byte) error {
+	var payload negativePayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return err
+	}
+
+	cachedErr := &CachedError{Message: payload.Message}
+	if payload.Sentinel != "" {
+		for _, sentinel := range nc.Errors {
+			if sentinel.Error() != payload.Sentinel {
+				continue
+			}
+			if payload.Message == payload.Sentinel {
+				return sentinel
+			}
+			cachedErr.Err = sentinel
+			break
+		}
+	}
+	return cachedErr
+}
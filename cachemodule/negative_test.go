@@ -0,0 +1,132 @@
+package cachemodule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	bcProvider "github.com/josemiguelmelo/gocacheable/providers/bigcache"
+	"github.com/stretchr/testify/assert"
+)
+
+var errNotFound = errors.New("not found")
+
+type backendError struct {
+	Code int
+}
+
+func (e *backendError) Error() string {
+	return fmt.Sprintf("backend error %d", e.Code)
+}
+
+func newNegativeModule(t *testing.T) *CacheModule {
+	return newTestModule(t, WithNegativeCaching(NegativeCaching{
+		TimeToLive: 50 * time.Millisecond,
+		Errors:     []error{errNotFound},
+		Match: func(err error) bool {
+			var backendErr *backendError
+			return errors.As(err, &backendErr)
+		},
+		IsEmpty: func(value interface{}) bool {
+			return value == ""
+		},
+	}))
+}
+
+func countingLoader(calls *int, value interface{}, err error) func(context.Context) (interface{}, error) {
+	return func(context.Context) (interface{}, error) {
+		*calls++
+		return value, err
+	}
+}
+
+func TestNegativeCachingSentinel(t *testing.T) {
+	module := newNegativeModule(t)
+	calls := 0
+
+	_, err := module.LoadContext(context.Background(), "key", countingLoader(&calls, nil, errNotFound), time.Minute)
+	assert.Equal(t, errNotFound, err)
+
+	// The sentinel itself is replayed
+	var value string
+	err = module.Get("key", &value)
+	assert.Equal(t, errNotFound, err)
+
+	// Wrapped sentinels keep their message and still match the sentinel
+	wrapped := fmt.Errorf("loading user: %w", errNotFound)
+	_, err = module.LoadContext(context.Background(), "wrapped", countingLoader(&calls, nil, wrapped), time.Minute)
+	assert.Equal(t, wrapped, err)
+	err = module.Get("wrapped", &value)
+	assert.Equal(t, wrapped.Error(), err.Error())
+	assert.True(t, errors.Is(err, errNotFound))
+	assert.Equal(t, 2, calls)
+
+	// Negative entries expire after their own ttl
+	time.Sleep(100 * time.Millisecond)
+	err = module.Get("key", &value)
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, errNotFound))
+}
+
+func TestNegativeCachingMatch(t *testing.T) {
+	module := newNegativeModule(t)
+	calls := 0
+
+	_, err := module.LoadContext(context.Background(), "key", countingLoader(&calls, nil, &backendError{Code: 503}), time.Minute)
+	assert.NotNil(t, err)
+
+	var value string
+	result, err := module.LookupContext(context.Background(), "key", &value)
+	assert.Nil(t, err)
+	var cachedErr *CachedError
+	assert.True(t, errors.As(result.Err, &cachedErr))
+	assert.Equal(t, "backend error 503", cachedErr.Error())
+}
+
+func TestNegativeCachingIgnoresOtherErrors(t *testing.T) {
+	module := newNegativeModule(t)
+	calls := 0
+
+	_, err := module.LoadContext(context.Background(), "key", countingLoader(&calls, nil, errors.New("timeout")), time.Minute)
+	assert.NotNil(t, err)
+	assert.Equal(t, false, module.HasKey("key"))
+
+	// Without configuration nothing is cached
+	module = newTestModule(t)
+	_, err = module.LoadContext(context.Background(), "key", countingLoader(&calls, nil, errNotFound), time.Minute)
+	assert.Equal(t, errNotFound, err)
+	assert.Equal(t, false, module.HasKey("key"))
+}
+
+func TestNegativeCachingEmptyResult(t *testing.T) {
+	module := newNegativeModule(t)
+	calls := 0
+
+	_, err := module.LoadContext(context.Background(), "key", countingLoader(&calls, "", nil), time.Minute)
+	assert.Nil(t, err)
+
+	var value string
+	err = module.Get("key", &value)
+	assert.Nil(t, err)
+	assert.Equal(t, "", value)
+
+	// Empty results use the negative caching ttl
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, false, module.HasKey("key"))
+}
+
+func TestNegativeCachingStoreFailureReturnsLoaderError(t *testing.T) {
+	storage := &failingProvider{BigCacheProvider: &bcProvider.BigCacheProvider{Lifetime: 2}}
+	assert.Nil(t, storage.Init())
+	defer storage.Close()
+	module := New("test module", storage, WithNegativeCaching(NegativeCaching{
+		TimeToLive: time.Minute,
+		Errors:     []error{errNotFound},
+	}))
+
+	_, err := module.LoadContext(context.Background(), "key", countingLoader(new(int), nil, errNotFound), time.Minute)
+	assert.Equal(t, errNotFound, err)
+	assert.Equal(t, uint64(1), module.Stats().ProviderErrors)
+}
@@ -0,0 +1,116 @@
+package cachemodule
+
+import (
+	"time"
+
+	gcCodec "github.com/josemiguelmelo/gocacheable/codec"
+	gcCompression "github.com/josemiguelmelo/gocacheable/compression"
+	gcEncryption "github.com/josemiguelmelo/gocacheable/encryption"
+	"github.com/josemiguelmelo/gocacheable/events"
+)
+
+// Option configures a CacheModule when it is created
+type Option func(*CacheModule)
+
+// WithSingleFlight enables or disables the coalescing of concurrent loader
+// calls for the same key. It is enabled by default.
+func WithSingleFlight(enabled bool) Option {
+	return func(cm *CacheModule) {
+		cm.singleFlight = enabled
+	}
+}
+
+// WithCodec sets the codec used to serialize the module values. Values are
+// encoded as JSON by default.
+func WithCodec(codec gcCodec.Codec) Option {
+	return func(cm *CacheModule) {
+		cm.codec = codec
+	}
+}
+
+// WithCompression compresses values of at least threshold bytes with format.
+// Each stored value records its own format, so compression can be turned on,
+// off or changed without breaking the reads of existing values.
+func WithCompression(format gcCompression.Format, threshold int) Option {
+	return func(cm *CacheModule) {
+		cm.compression = format
+		cm.compressionMin = threshold
+	}
+}
+
+// WithMaxDecompressedSize sets the size, in bytes, compressed values can be
+// decompressed to. Larger values fail to decode with compression.ErrTooLarge,
+// so that a corrupted entry cannot exhaust the memory. It defaults to
+// compression.DefaultMaxSize.
+func WithMaxDecompressedSize(size int) Option {
+	return func(cm *CacheModule) {
+		cm.decompressionMax = size
+	}
+}
+
+// WithEncryption encrypts the module entries with AES-GCM using keyring. Each
+// entry records the id of the key it was encrypted with, so keys can be rotated
+// while older entries remain readable. Unencrypted entries are treated as missing.
+func WithEncryption(keyring *gcEncryption.Keyring) Option {
+	return func(cm *CacheModule) {
+		cm.keyring = keyring
+	}
+}
+
+// WithRefreshQueueSize sets how many background refreshes of stale entries can
+// be waiting for the module worker. Refreshes are skipped while it is full.
+func WithRefreshQueueSize(size int) Option {
+	return func(cm *CacheModule) {
+		cm.refreshQueueSize = size
+	}
+}
+
+// WithEventQueue configures the event queues of the module, once registered
+// with the events manager of the CacheableManager it is added to
+func WithEventQueue(options ...events.QueueOption) Option {
+	return func(cm *CacheModule) {
+		cm.eventQueue = append(cm.eventQueue, options...)
+	}
+}
+
+// entryConfig holds the settings of a single cached entry
+type entryConfig struct {
+	softTimeToLive time.Duration
+	tags           []string
+}
+
+func newEntryConfig(options []EntryOption) entryConfig {
+	config := entryConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// EntryOption configures a single cached entry
+type EntryOption func(*entryConfig)
+
+// WithSoftTTL sets the time after which a cached value becomes stale. A stale
+// value is still returned by Cacheable but triggers a background refresh,
+// while the time to live of the entry still forces a synchronous reload.
+func WithSoftTTL(softTimeToLive time.Duration) EntryOption {
+	return func(config *entryConfig) {
+		config.softTimeToLive = softTimeToLive
+	}
+}
+
+// WithTags tags a cached entry, so that it is removed by the invalidation of
+// any of its tags
+func WithTags(tags ...string) EntryOption {
+	return func(config *entryConfig) {
+		config.tags = append(config.tags, tags...)
+	}
+}
+
+// WithNegativeCaching enables the caching of selected loader errors and empty
+// results. Cached errors are returned to callers until the entry expires.
+func WithNegativeCaching(negativeCaching NegativeCaching) Option {
+	return func(cm *CacheModule) {
+		cm.negativeCaching = negativeCaching
+	}
+}
@@ -0,0 +1,115 @@
+package cachemodule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultRefreshQueueSize is the number of background refreshes a module can
+// have waiting for its worker
+const defaultRefreshQueueSize = 64
+
+type refreshJob struct {
+	ctx        context.Context
+	key        string
+	loader     func(context.Context) (interface{}, error)
+	timeToLive time.Duration
+	options    []EntryOption
+}
+
+// refresher is the background worker of a module that reloads stale entries.
+// It is started on the first scheduled refresh.
+type refresher struct {
+	startOnce sync.Once
+	jobs      chan refreshJob
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+}
+
+func newRefresher(queueSize int) *refresher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &refresher{
+		jobs:    make(chan refreshJob, queueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		pending: map[string]struct{}{},
+	}
+}
+
+// schedule queues a refresh of job.key unless one is already pending, the
+// queue is full or the refresher was stopped. It returns true if the job was queued.
+func (r *refresher) schedule(cm *CacheModule, job refreshJob) bool {
+	if r.ctx.Err() != nil {
+		return false
+	}
+	r.startOnce.Do(func() {
+		go r.run(cm)
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pending[job.key]; ok {
+		return false
+	}
+
+	select {
+	case r.jobs <- job:
+		r.pending[job.key] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *refresher) run(cm *CacheModule) {
+	defer close(r.done)
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case job := <-r.jobs:
+			r.refresh(cm, job)
+		}
+	}
+}
+
+func (r *refresher) refresh(cm *CacheModule, job refreshJob) {
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, job.key)
+		r.mu.Unlock()
+	}()
+
+	// The refresh keeps the values of the caller context but outlives it, and
+	// is cancelled when the module is closed
+	ctx, cancel := context.WithCancel(context.WithoutCancel(job.ctx))
+	defer cancel()
+	stop := context.AfterFunc(r.ctx, cancel)
+	defer stop()
+
+	_, err := cm.LoadContext(ctx, job.key, job.loader, job.timeToLive, job.options...)
+	if err != nil {
+		logrus.Errorln(fmt.Sprintf("Error refreshing cache with key = %s from module %s with err = %s", job.key, cm.Name, err.Error()))
+	}
+}
+
+// stop cancels any running refresh and waits for the worker to exit
+func (r *refresher) stop() {
+	r.cancel()
+	started := true
+	r.startOnce.Do(func() {
+		started = false
+	})
+	if started {
+		<-r.done
+	}
+}
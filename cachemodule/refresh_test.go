@@ -0,0 +1,85 @@
+package cachemodule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bcProvider "github.com/josemiguelmelo/gocacheable/providers/bigcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestModule(t *testing.T, options ...Option) *CacheModule {
+	storage := &bcProvider.BigCacheProvider{Lifetime: 2}
+	assert.Nil(t, storage.Init())
+	t.Cleanup(func() { storage.Close() })
+	module := New("test module", storage, options...)
+	return &module
+}
+
+func TestLookupStale(t *testing.T) {
+	module := newTestModule(t)
+	now := time.Now()
+	module.now = func() time.Time { return now }
+
+	err := module.Set("key", "value", WithSoftTTL(time.Minute))
+	assert.Nil(t, err)
+
+	var value string
+	result, err := module.LookupContext(context.Background(), "key", &value)
+	assert.Nil(t, err)
+	assert.Equal(t, false, result.Stale)
+	assert.Equal(t, "value", value)
+
+	now = now.Add(time.Minute)
+	result, err = module.LookupContext(context.Background(), "key", &value)
+	assert.Nil(t, err)
+	assert.Equal(t, true, result.Stale)
+
+	// Entries without soft ttl never become stale
+	err = module.Set("key", "value")
+	assert.Nil(t, err)
+	now = now.Add(time.Hour)
+	result, err = module.LookupContext(context.Background(), "key", &value)
+	assert.Nil(t, err)
+	assert.Equal(t, false, result.Stale)
+}
+
+func TestRefreshDeduplicatesAndStops(t *testing.T) {
+	module := newTestModule(t, WithRefreshQueueSize(1))
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	loader := func(ctx context.Context) (interface{}, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return "refreshed", ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.Equal(t, true, module.Refresh(ctx, "key", loader, time.Minute))
+	<-started
+	// The caller context ending must not cancel the refresh
+	cancel()
+	assert.Equal(t, false, module.Refresh(context.Background(), "key", loader, time.Minute))
+
+	close(release)
+	for !module.HasKey("key") {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Closing the module cancels running refreshes and rejects new ones
+	blocked := make(chan struct{})
+	assert.Equal(t, true, module.Refresh(context.Background(), "other", func(ctx context.Context) (interface{}, error) {
+		close(blocked)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, time.Minute))
+	<-blocked
+	assert.Nil(t, module.Close())
+	assert.Equal(t, false, module.Refresh(context.Background(), "key", loader, time.Minute))
+	assert.Equal(t, false, module.HasKey("other"))
+}
@@ -0,0 +1,89 @@
+package cachemodule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// flight is an in progress loader call shared by every caller of the same key
+type flight struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	value      interface{}
+	err        error
+	panicValue interface{}
+}
+
+// flightGroup coalesces concurrent loader calls for the same key, so that only
+// one of them runs and every waiter receives its result
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: map[string]*flight{}}
+}
+
+// do runs fn once per key for all concurrent callers. fn receives a context
+// that keeps the values of the first caller context and is only cancelled when
+// every waiter has given up, so one caller leaving does not fail the others.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if !ok {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+		go g.run(flightCtx, key, f, fn)
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.panicValue != nil {
+			panic(f.panicValue)
+		}
+		return f.value, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			g.forget(key, f)
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, f *flight, fn func(context.Context) (interface{}, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.panicValue = fmt.Errorf("cache loader for key %s panicked: %v", key, r)
+		}
+		g.mu.Lock()
+		g.forget(key, f)
+		g.mu.Unlock()
+		f.cancel()
+		close(f.done)
+	}()
+
+	f.value, f.err = fn(ctx)
+}
+
+// forget removes f from the group unless it was already replaced. Must be
+// called with g.mu held.
+func (g *flightGroup) forget(key string, f *flight) {
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+}
@@ -0,0 +1,81 @@
+package cachemodule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waiters(group *flightGroup, key string) int {
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	if f, ok := group.flights[key]; ok {
+		return f.waiters
+	}
+	return 0
+}
+
+func TestFlightGroupWaiterCancel(t *testing.T) {
+	group := newFlightGroup()
+
+	started := make(chan struct{})
+	loaderCtx := make(chan context.Context, 1)
+	fn := func(ctx context.Context) (interface{}, error) {
+		loaderCtx <- ctx
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := group.do(firstCtx, "key", fn)
+		firstErr <- err
+	}()
+	<-started
+
+	secondErr := make(chan error, 1)
+	go func() {
+		_, err := group.do(secondCtx, "key", fn)
+		secondErr <- err
+	}()
+
+	// Wait for the second caller to join the flight
+	for waiters(group, "key") != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// First waiter leaving must not cancel the shared loader
+	cancelFirst()
+	assert.Equal(t, context.Canceled, <-firstErr)
+	ctx := <-loaderCtx
+	assert.Nil(t, ctx.Err())
+
+	// Last waiter leaving cancels it
+	cancelSecond()
+	assert.Equal(t, context.Canceled, <-secondErr)
+	<-ctx.Done()
+}
+
+func TestFlightGroupPanicPropagates(t *testing.T) {
+	group := newFlightGroup()
+
+	assert.Panics(t, func() {
+		group.do(context.Background(), "key", func(context.Context) (interface{}, error) {
+			panic(errors.New("boom"))
+		})
+	})
+
+	// A panic must not leave the key stuck
+	value, err := group.do(context.Background(), "key", func(context.Context) (interface{}, error) {
+		return 1, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, value)
+}
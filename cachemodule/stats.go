@@ -0,0 +1,28 @@
+package cachemodule
+
+import (
+	"errors"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	gcStats "github.com/josemiguelmelo/gocacheable/stats"
+)
+
+// Stats returns the statistics of the module since it was created
+func (cm CacheModule) Stats() gcStats.Stats {
+	return cm.stats.Snapshot()
+}
+
+// observe records a provider operation started at start and ending with err
+func (cm CacheModule) observe(op gcStats.Operation, start time.Time, err error) {
+	cm.stats.ObserveProvider(op, time.Since(start), err)
+}
+
+// observeRead records a read of a key started at start and ending with err.
+// Missing keys are misses, not provider errors.
+func (cm CacheModule) observeRead(start time.Time, err error) {
+	if errors.Is(err, gcInterfaces.ErrNotFound) {
+		err = nil
+	}
+	cm.observe(gcStats.Get, start, err)
+}
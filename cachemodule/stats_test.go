@@ -0,0 +1,153 @@
+package cachemodule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	bcProvider "github.com/josemiguelmelo/gocacheable/providers/bigcache"
+	gcStats "github.com/josemiguelmelo/gocacheable/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingProvider fails every write to bigcache
+type failingProvider struct {
+	*bcProvider.BigCacheProvider
+}
+
+func (p *failingProvider) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.New("Provider failure")
+}
+
+func (p *failingProvider) DeleteContext(ctx context.Context, key string) error {
+	return errors.New("Provider failure")
+}
+
+// failingReadProvider fails every read from bigcache
+type failingReadProvider struct {
+	*bcProvider.BigCacheProvider
+}
+
+func (p *failingReadProvider) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("Provider failure")
+}
+
+func TestStatsCountsOperations(t *testing.T) {
+	module := newTestModule(t)
+	var value string
+
+	assert.NotNil(t, module.Get("key", &value))
+	assert.Nil(t, module.Set("key", "value"))
+	assert.Nil(t, module.Get("key", &value))
+	assert.Nil(t, module.Get("key", &value))
+	assert.True(t, module.HasKey("key"))
+
+	_, err := module.Load("loaded", func() (interface{}, error) {
+		return "loaded value", nil
+	}, time.Minute)
+	assert.Nil(t, err)
+	_, err = module.Load("failed", func() (interface{}, error) {
+		return nil, errors.New("Loader failure")
+	}, time.Minute)
+	assert.NotNil(t, err)
+	assert.Nil(t, module.Delete("key"))
+
+	stats := module.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.InDelta(t, 2.0/3.0, stats.HitRatio(), 0.001)
+	assert.Equal(t, uint64(2), stats.LoaderCalls)
+	assert.Equal(t, uint64(1), stats.LoaderErrors)
+	assert.Equal(t, uint64(2), stats.LoaderLatency.Count)
+	assert.Equal(t, uint64(2), stats.Sets)
+	assert.Equal(t, uint64(1), stats.Deletes)
+	assert.Equal(t, uint64(0), stats.ProviderErrors)
+	assert.Equal(t, int64(0), stats.LoadersInFlight)
+	assert.Equal(t, uint64(2), stats.ValueSizes.Count)
+
+	assert.Equal(t, 4, len(stats.ProviderLatency))
+	assert.Equal(t, uint64(3), stats.ProviderLatency[gcStats.Get].Count)
+	assert.Equal(t, uint64(2), stats.ProviderLatency[gcStats.Set].Count)
+	assert.Equal(t, uint64(1), stats.ProviderLatency[gcStats.HasKey].Count)
+	assert.Equal(t, uint64(1), stats.ProviderLatency[gcStats.Delete].Count)
+}
+
+func TestStatsCountsBatchOperations(t *testing.T) {
+	module, _ := newBatchModule(t)
+
+	assert.Nil(t, module.SetMany(map[string]interface{}{"a": "value a", "b": "value b", "c": "value c"}, time.Minute))
+	_, err := module.GetMany([]string{"a", "missing", "c"})
+	assert.Nil(t, err)
+	assert.Nil(t, module.DeleteMany([]string{"a", "b"}))
+
+	stats := module.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(3), stats.Sets)
+	assert.Equal(t, uint64(3), stats.ValueSizes.Count)
+	assert.Equal(t, uint64(2), stats.Deletes)
+	assert.Equal(t, uint64(1), stats.ProviderLatency[gcStats.SetMany].Count)
+	assert.Equal(t, uint64(1), stats.ProviderLatency[gcStats.GetMany].Count)
+	assert.Equal(t, uint64(1), stats.ProviderLatency[gcStats.DeleteMany].Count)
+}
+
+func TestStatsCountsProviderErrors(t *testing.T) {
+	storage := &failingProvider{BigCacheProvider: &bcProvider.BigCacheProvider{Lifetime: 2}}
+	assert.Nil(t, storage.Init())
+	defer storage.Close()
+	module := New("test module", storage)
+
+	assert.NotNil(t, module.Set("key", "value"))
+	assert.NotNil(t, module.Delete("key"))
+	_, err := module.Load("key", func() (interface{}, error) {
+		return "value", nil
+	}, time.Minute)
+	assert.NotNil(t, err)
+
+	stats := module.Stats()
+	assert.Equal(t, uint64(3), stats.ProviderErrors)
+	assert.Equal(t, uint64(0), stats.Sets)
+	assert.Equal(t, uint64(0), stats.Deletes)
+	assert.Equal(t, uint64(1), stats.LoaderCalls)
+	assert.Equal(t, uint64(0), stats.LoaderErrors)
+}
+
+func TestStatsCountsReadErrors(t *testing.T) {
+	storage := &failingReadProvider{BigCacheProvider: &bcProvider.BigCacheProvider{Lifetime: 2}}
+	assert.Nil(t, storage.Init())
+	defer storage.Close()
+	module := New("test module", storage)
+
+	var value string
+	assert.NotNil(t, module.Get("key", &value))
+	_, err := module.GetMany([]string{"a", "b"})
+	assert.Nil(t, err)
+
+	// Failed reads are misses, and provider errors unlike missing keys
+	stats := module.Stats()
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.Equal(t, uint64(3), stats.ProviderErrors)
+	assert.Equal(t, uint64(3), stats.ProviderLatency[gcStats.Get].Count)
+}
+
+func TestStatsCountsLoadersInFlight(t *testing.T) {
+	module := newTestModule(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go module.Load("key", func() (interface{}, error) {
+		close(started)
+		<-release
+		return "value", nil
+	}, time.Minute)
+	<-started
+	assert.Equal(t, int64(1), module.Stats().LoadersInFlight)
+
+	close(release)
+	var value string
+	for module.Get("key", &value) != nil {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, int64(0), module.Stats().LoadersInFlight)
+}
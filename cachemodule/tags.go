@@ -0,0 +1,139 @@
+package cachemodule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	gcStats "github.com/josemiguelmelo/gocacheable/stats"
+)
+
+// tagIndex is the in memory index of the keys carrying a tag, used for
+// providers not implementing TaggedCacheProviderInterface
+type tagIndex struct {
+	mu   sync.Mutex
+	tags map[string]*taggedKeys
+}
+
+// taggedKeys are the keys carrying a tag, with the time their entry expires
+type taggedKeys struct {
+	expiresAt map[string]time.Time
+	// pruneAt is the number of keys triggering the removal of expired ones
+	pruneAt int
+}
+
+func newTagIndex() *tagIndex {
+	return &tagIndex{tags: map[string]*taggedKeys{}}
+}
+
+// add adds key to every tag. A zero expiresAt means that the entry never expires.
+func (ti *tagIndex) add(key string, tags []string, expiresAt time.Time, now time.Time) {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+
+	for _, tag := range tags {
+		keys, ok := ti.tags[tag]
+		if !ok {
+			keys = &taggedKeys{expiresAt: map[string]time.Time{}, pruneAt: 1}
+			ti.tags[tag] = keys
+		}
+		keys.expiresAt[key] = expiresAt
+
+		// Remove the expired keys every time the tag doubles in size
+		if len(keys.expiresAt) >= keys.pruneAt {
+			keys.prune(now)
+			keys.pruneAt = 2 * len(keys.expiresAt)
+		}
+	}
+}
+
+// pop removes tag and returns the keys carrying it whose entry may not have expired
+func (ti *tagIndex) pop(tag string, now time.Time) []string {
+	ti.mu.Lock()
+	keys, ok := ti.tags[tag]
+	delete(ti.tags, tag)
+	ti.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+	keys.prune(now)
+	result := make([]string, 0, len(keys.expiresAt))
+	for key := range keys.expiresAt {
+		result = append(result, key)
+	}
+	return result
+}
+
+func (ti *tagIndex) reset() {
+	ti.mu.Lock()
+	defer ti.mu.Unlock()
+	ti.tags = map[string]*taggedKeys{}
+}
+
+func (tk *taggedKeys) prune(now time.Time) {
+	for key, expiresAt := range tk.expiresAt {
+		if !expiresAt.IsZero() && !now.Before(expiresAt) {
+			delete(tk.expiresAt, key)
+		}
+	}
+}
+
+// InvalidateTag removes every key cached with tag
+func (cm *CacheModule) InvalidateTag(tag string) error {
+	return cm.InvalidateTagContext(context.Background(), tag)
+}
+
+// InvalidateTagContext removes every key cached with tag
+func (cm *CacheModule) InvalidateTagContext(ctx context.Context, tag string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	keys, err := cm.popTag(ctx, tag)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return cm.DeleteManyContext(ctx, keys)
+}
+
+// popTag removes the index of tag and returns the keys it held
+func (cm *CacheModule) popTag(ctx context.Context, tag string) ([]string, error) {
+	if tagged, ok := cm.cacheStorage.(gcInterfaces.TaggedCacheProviderInterface); ok {
+		start := time.Now()
+		keys, err := tagged.PopTagContext(ctx, tag)
+		if !errors.Is(err, gcInterfaces.ErrTagsNotSupported) {
+			cm.observe(gcStats.PopTag, start, err)
+			return keys, err
+		}
+	}
+	return cm.tags.pop(tag, cm.now()), nil
+}
+
+// addTags adds key, cached for timeToLive, to the index of every tag
+func (cm *CacheModule) addTags(ctx context.Context, key string, tags []string, timeToLive time.Duration) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	if tagged, ok := cm.cacheStorage.(gcInterfaces.TaggedCacheProviderInterface); ok {
+		start := time.Now()
+		err := tagged.AddTagsContext(ctx, key, tags, timeToLive)
+		if !errors.Is(err, gcInterfaces.ErrTagsNotSupported) {
+			cm.observe(gcStats.AddTags, start, err)
+			return err
+		}
+	}
+
+	now := cm.now()
+	expiresAt := time.Time{}
+	if timeToLive > 0 {
+		expiresAt = now.Add(timeToLive)
+	}
+	cm.tags.add(key, tags, expiresAt, now)
+	return nil
+}
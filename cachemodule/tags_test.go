@@ -0,0 +1,125 @@
+package cachemodule
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidateTag(t *testing.T) {
+	module := newTestModule(t)
+
+	assert.Nil(t, module.SetWithTTL("user:42", "john", time.Minute, WithTags("user:42")))
+	assert.Nil(t, module.SetWithTTL("posts:42", "posts", time.Minute, WithTags("user:42", "posts")))
+	assert.Nil(t, module.SetMany(map[string]interface{}{"posts:1": "a", "posts:2": "b"}, time.Minute, WithTags("posts")))
+	_, err := module.Load("user:43", func() (interface{}, error) { return "jane", nil }, time.Minute, WithTags("user:43"))
+	assert.Nil(t, err)
+
+	assert.Nil(t, module.InvalidateTag("user:42"))
+	assert.False(t, module.HasKey("user:42"))
+	assert.False(t, module.HasKey("posts:42"))
+	assert.True(t, module.HasKey("posts:1"))
+	assert.True(t, module.HasKey("user:43"))
+
+	assert.Nil(t, module.InvalidateTag("posts"))
+	assert.False(t, module.HasKey("posts:1"))
+	assert.False(t, module.HasKey("posts:2"))
+	assert.True(t, module.HasKey("user:43"))
+
+	// Invalidated and unknown tags hold no key
+	assert.Nil(t, module.InvalidateTag("posts"))
+	assert.Nil(t, module.InvalidateTag("unknown"))
+}
+
+func TestInvalidateTagOfNegativeEntry(t *testing.T) {
+	module := newNegativeModule(t)
+
+	_, err := module.LoadContext(context.Background(), "key", countingLoader(new(int), nil, errNotFound), time.Minute, WithTags("tag"))
+	assert.Equal(t, errNotFound, err)
+	assert.True(t, module.HasKey("key"))
+
+	assert.Nil(t, module.InvalidateTag("tag"))
+	assert.False(t, module.HasKey("key"))
+}
+
+func TestInvalidateTagAfterReset(t *testing.T) {
+	module := newTestModule(t)
+
+	assert.Nil(t, module.SetWithTTL("key", "value", time.Minute, WithTags("tag")))
+	assert.Nil(t, module.Reset())
+	assert.Equal(t, 0, len(module.tags.tags))
+}
+
+func TestInvalidateTagBatchProvider(t *testing.T) {
+	module, storage := newBatchModule(t)
+
+	assert.Nil(t, module.SetWithTTL("a", "value", time.Minute, WithTags("tag")))
+	assert.Nil(t, module.SetWithTTL("b", "value", time.Minute, WithTags("tag")))
+	assert.Nil(t, module.InvalidateTag("tag"))
+	assert.False(t, module.HasKey("a"))
+	assert.False(t, module.HasKey("b"))
+	assert.Equal(t, 1, storage.batchCalls)
+}
+
+// unsupportedTagsProvider wraps a provider keeping no tags
+type unsupportedTagsProvider struct {
+	*batchProvider
+}
+
+func (p *unsupportedTagsProvider) AddTagsContext(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	return gcInterfaces.ErrTagsNotSupported
+}
+
+func (p *unsupportedTagsProvider) AddTags(key string, tags []string, ttl time.Duration) error {
+	return gcInterfaces.ErrTagsNotSupported
+}
+
+func (p *unsupportedTagsProvider) PopTagContext(ctx context.Context, tag string) ([]string, error) {
+	return nil, gcInterfaces.ErrTagsNotSupported
+}
+
+func (p *unsupportedTagsProvider) PopTag(tag string) ([]string, error) {
+	return nil, gcInterfaces.ErrTagsNotSupported
+}
+
+func TestInvalidateTagUnsupportedByProvider(t *testing.T) {
+	_, storage := newBatchModule(t)
+	module := New("test module", &unsupportedTagsProvider{storage})
+
+	// The module keeps the index itself
+	assert.Nil(t, module.SetWithTTL("a", "value", time.Minute, WithTags("tag")))
+	assert.Nil(t, module.InvalidateTag("tag"))
+	assert.False(t, module.HasKey("a"))
+	assert.Equal(t, uint64(0), module.Stats().ProviderErrors)
+}
+
+func TestInvalidateTagContextCanceled(t *testing.T) {
+	module := newTestModule(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, module.InvalidateTagContext(ctx, "tag"))
+}
+
+func TestTagIndexPrunesExpiredKeys(t *testing.T) {
+	index := newTagIndex()
+	now := time.Now()
+
+	index.add("expired", []string{"tag"}, now.Add(time.Second), now)
+	index.add("forever", []string{"tag"}, time.Time{}, now)
+	now = now.Add(time.Minute)
+	index.add("live0", []string{"tag"}, now.Add(time.Minute), now)
+	index.add("live1", []string{"tag"}, now.Add(time.Minute), now)
+	// The index is pruned as it grows
+	assert.Equal(t, 3, len(index.tags["tag"].expiresAt))
+
+	index.add("expiring", []string{"tag"}, now.Add(time.Second), now)
+	keys := index.pop("tag", now.Add(2*time.Second))
+	sort.Strings(keys)
+	assert.Equal(t, []string{"forever", "live0", "live1"}, keys)
+	assert.Nil(t, index.pop("tag", now))
+}
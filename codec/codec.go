@@ -0,0 +1,9 @@
+package codec
+
+// Codec serializes the values cached by a module
+type Codec interface {
+	// Marshal returns the encoded form of value
+	Marshal(value interface{}) ([]byte, error)
+	// Unmarshal decodes data into out, which must be a pointer
+	Unmarshal(data []byte, out interface{}) error
+}
@@ -0,0 +1,72 @@
+package codec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type example struct {
+	Name      string
+	Count     int64
+	Data      []byte
+	CreatedAt time.Time
+}
+
+func newExample() example {
+	return example{
+		Name:      "example",
+		Count:     1<<62 + 1,
+		Data:      []byte{0, 1, 2, 255},
+		CreatedAt: time.Date(2019, 5, 14, 11, 33, 1, 123456789, time.UTC),
+	}
+}
+
+func testRoundTrip(t *testing.T, codec Codec) {
+	data, err := codec.Marshal(newExample())
+	assert.Nil(t, err)
+
+	var out example
+	err = codec.Unmarshal(data, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, newExample().Name, out.Name)
+	assert.Equal(t, newExample().Count, out.Count)
+	assert.Equal(t, newExample().Data, out.Data)
+	assert.True(t, newExample().CreatedAt.Equal(out.CreatedAt))
+}
+
+func TestJSONCodec(t *testing.T) {
+	testRoundTrip(t, JSONCodec{})
+}
+
+func TestGobCodec(t *testing.T) {
+	testRoundTrip(t, GobCodec{})
+}
+
+func TestMsgPackCodec(t *testing.T) {
+	testRoundTrip(t, MsgPackCodec{})
+}
+
+func TestRawCodec(t *testing.T) {
+	codec := RawCodec{}
+
+	data, err := codec.Marshal([]byte("raw value"))
+	assert.Nil(t, err)
+	assert.Equal(t, "raw value", string(data))
+
+	var out []byte
+	err = codec.Unmarshal(data, &out)
+	assert.Nil(t, err)
+	assert.Equal(t, "raw value", string(out))
+
+	// Decoded values must not share memory with the stored data
+	data[0] = 'R'
+	assert.Equal(t, "raw value", string(out))
+
+	_, err = codec.Marshal("not raw")
+	assert.Equal(t, ErrNotRaw, err)
+	var notRaw string
+	err = codec.Unmarshal(data, &notRaw)
+	assert.Equal(t, ErrNotRaw, err)
+}
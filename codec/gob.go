@@ -0,0 +1,24 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// GobCodec encodes values with encoding/gob. Values cached behind interface
+// types must have their concrete types registered with gob.Register.
+type GobCodec struct{}
+
+// Marshal returns the gob encoding of value
+func (GobCodec) Marshal(value interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Unmarshal decodes gob data into out
+func (GobCodec) Unmarshal(data []byte, out interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(out)
+}
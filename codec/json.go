@@ -0,0 +1,16 @@
+package codec
+
+import "encoding/json"
+
+// JSONCodec encodes values as JSON. Only exported fields are cached.
+type JSONCodec struct{}
+
+// Marshal returns the JSON encoding of value
+func (JSONCodec) Marshal(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Unmarshal decodes JSON data into out
+func (JSONCodec) Unmarshal(data []byte, out interface{}) error {
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,17 @@
+package codec
+
+import "github.com/vmihailenco/msgpack/v5"
+
+// MsgPackCodec encodes values as MessagePack, which is more compact and faster
+// than JSON and keeps time.Time and []byte values intact
+type MsgPackCodec struct{}
+
+// Marshal returns the MessagePack encoding of value
+func (MsgPackCodec) Marshal(value interface{}) ([]byte, error) {
+	return msgpack.Marshal(value)
+}
+
+// Unmarshal decodes MessagePack data into out
+func (MsgPackCodec) Unmarshal(data []byte, out interface{}) error {
+	return msgpack.Unmarshal(data, out)
+}
@@ -0,0 +1,37 @@
+package codec
+
+import (
+	"errors"
+)
+
+// ErrNotRaw is returned by RawCodec for values that are not byte slices
+var ErrNotRaw = errors.New("Raw codec only supports []byte values")
+
+// RawCodec stores []byte values as they are, without any encoding
+type RawCodec struct{}
+
+// Marshal returns value, which must be a []byte or a *[]byte
+func (RawCodec) Marshal(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case *[]byte:
+		return *v, nil
+	default:
+		return nil, ErrNotRaw
+	}
+}
+
+// Unmarshal copies data into out, which must be a *[]byte or a *interface{}
+func (RawCodec) Unmarshal(data []byte, out interface{}) error {
+	value := append([]byte{}, data...)
+	switch o := out.(type) {
+	case *[]byte:
+		*o = value
+	case *interface{}:
+		*o = value
+	default:
+		return ErrNotRaw
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format identifies how a value was compressed. It is stored with every value,
+// so values remain readable whatever the current module configuration is.
+type Format byte
+
+const (
+	// None leaves values uncompressed
+	None Format = iota
+	// Gzip compresses values with gzip
+	Gzip
+	// Zstd compresses values with Zstandard
+	Zstd
+	// Snappy compresses values with Snappy
+	Snappy
+)
+
+// DefaultMaxSize is the size, in bytes, a value can be decompressed to by
+// Decompress
+const DefaultMaxSize = 64 << 20
+
+var (
+	// ErrUnknownFormat is returned for a format this package does not implement
+	ErrUnknownFormat = errors.New("Unknown compression format")
+	// ErrTooLarge is returned when a value decompresses to more than the maximum size
+	ErrTooLarge = errors.New("Decompressed value is too large")
+)
+
+func (f Format) String() string {
+	switch f {
+	case None:
+		return "none"
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	case Snappy:
+		return "snappy"
+	default:
+		return fmt.Sprintf("format(%d)", byte(f))
+	}
+}
+
+// zstd encoders and decoders are expensive to create and safe for concurrent
+// use. Decoders are kept per maximum size, which they enforce.
+var (
+	zstdOnce     sync.Once
+	zstdEncoder  *zstd.Encoder
+	zstdErr      error
+	zstdMu       sync.Mutex
+	zstdDecoders = map[int]*zstd.Decoder{}
+)
+
+// zstdMinMemory is the smallest memory limit given to zstd decoders
+const zstdMinMemory = 64 << 10
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+	})
+	return zstdErr
+}
+
+// zstdDecoder returns the decoder of values up to maxSize bytes
+func zstdDecoder(maxSize int) (*zstd.Decoder, error) {
+	zstdMu.Lock()
+	defer zstdMu.Unlock()
+	if decoder, ok := zstdDecoders[maxSize]; ok {
+		return decoder, nil
+	}
+	// zstd windows are at least 1 KB, smaller limits are checked on the result
+	memory := maxSize
+	if memory < zstdMinMemory {
+		memory = zstdMinMemory
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(memory)))
+	if err != nil {
+		return nil, err
+	}
+	zstdDecoders[maxSize] = decoder
+	return decoder, nil
+}
+
+// Compress returns data compressed with format
+func Compress(format Format, data []byte) ([]byte, error) {
+	switch format {
+	case None:
+		return data, nil
+	case Gzip:
+		var buffer bytes.Buffer
+		writer := gzip.NewWriter(&buffer)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	case Zstd:
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		return zstdEncoder.EncodeAll(data, nil), nil
+	case Snappy:
+		return snappy.Encode(nil, data), nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// Decompress returns data decompressed from format, failing with ErrTooLarge
+// if it is larger than DefaultMaxSize
+func Decompress(format Format, data []byte) ([]byte, error) {
+	return DecompressMax(format, data, DefaultMaxSize)
+}
+
+// DecompressMax returns data decompressed from format, failing with
+// ErrTooLarge if it is larger than maxSize bytes. The size is checked before
+// allocating the result, so that a corrupted or malicious value cannot
+// exhaust the memory.
+func DecompressMax(format Format, data []byte, maxSize int) ([]byte, error) {
+	switch format {
+	case None:
+		return data, nil
+	case Gzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		decompressed, err := io.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(decompressed) > maxSize {
+			return nil, ErrTooLarge
+		}
+		return decompressed, nil
+	case Zstd:
+		decoder, err := zstdDecoder(maxSize)
+		if err != nil {
+			return nil, err
+		}
+		decompressed, err := decoder.DecodeAll(data, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			return nil, ErrTooLarge
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(decompressed) > maxSize {
+			return nil, ErrTooLarge
+		}
+		return decompressed, nil
+	case Snappy:
+		size, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if size > maxSize {
+			return nil, ErrTooLarge
+		}
+		return snappy.Decode(nil, data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
@@ -0,0 +1,64 @@
+package compression
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("<div>rendered page</div>"), 100)
+
+	for _, format := range []Format{None, Gzip, Zstd, Snappy} {
+		compressed, err := Compress(format, data)
+		assert.Nil(t, err, format.String())
+		if format != None {
+			assert.True(t, len(compressed) < len(data), format.String())
+		}
+
+		decompressed, err := Decompress(format, compressed)
+		assert.Nil(t, err, format.String())
+		assert.Equal(t, data, decompressed, format.String())
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := Compress(Format(42), []byte("data"))
+	assert.Equal(t, ErrUnknownFormat, err)
+
+	_, err = Decompress(Format(42), []byte("data"))
+	assert.Equal(t, ErrUnknownFormat, err)
+	assert.Equal(t, "format(42)", Format(42).String())
+}
+
+func TestDecompressCorruptedData(t *testing.T) {
+	for _, format := range []Format{Gzip, Zstd, Snappy} {
+		_, err := Decompress(format, []byte("not compressed"))
+		assert.NotNil(t, err, format.String())
+	}
+}
+
+func TestDecompressMax(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 1000)
+	for _, format := range []Format{Gzip, Zstd, Snappy} {
+		compressed, err := Compress(format, data)
+		assert.Nil(t, err, format.String())
+
+		decompressed, err := DecompressMax(format, compressed, len(data))
+		assert.Nil(t, err, format.String())
+		assert.Equal(t, data, decompressed, format.String())
+
+		_, err = DecompressMax(format, compressed, len(data)-1)
+		assert.Equal(t, ErrTooLarge, err, format.String())
+	}
+}
+
+func TestDecompressMaxLargeZstd(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 1<<20)
+	compressed, err := Compress(Zstd, data)
+	assert.Nil(t, err)
+
+	_, err = DecompressMax(Zstd, compressed, 1<<19)
+	assert.Equal(t, ErrTooLarge, err)
+}
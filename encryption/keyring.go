@@ -0,0 +1,107 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// Encrypted values are framed as:
+//
+//	version (1 byte) | key id length (1 byte) | key id | nonce | ciphertext
+//
+// The version byte never collides with the first byte of an unencrypted module entry.
+const frameVersion byte = 0xE1
+
+var (
+	// ErrUnknownKey is returned when a value was encrypted with a key the keyring does not hold
+	ErrUnknownKey = errors.New("Unknown encryption key")
+	// ErrInvalidCiphertext is returned when a value is not an encrypted frame
+	ErrInvalidCiphertext = errors.New("Invalid encrypted value")
+	// ErrInvalidKeyID is returned when a key identifier is empty or longer than 255 bytes
+	ErrInvalidKeyID = errors.New("Invalid encryption key id")
+)
+
+// Keyring encrypts values with AES-GCM under its primary key, and decrypts
+// values encrypted under any of its keys. Keys are rotated by adding a new
+// primary key while keeping the previous ones until the values they encrypted expire.
+type Keyring struct {
+	primary string
+	ciphers map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring from keys indexed by their identifier. Keys
+// must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewKeyring(primary string, keys map[string][]byte) (*Keyring, error) {
+	keyring := &Keyring{
+		primary: primary,
+		ciphers: map[string]cipher.AEAD{},
+	}
+
+	for id, key := range keys {
+		if id == "" || len(id) > 255 {
+			return nil, ErrInvalidKeyID
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		keyring.ciphers[id] = aead
+	}
+
+	if _, ok := keyring.ciphers[primary]; !ok {
+		return nil, ErrUnknownKey
+	}
+	return keyring, nil
+}
+
+// Primary returns the identifier of the key new values are encrypted with
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// Encrypt encrypts plaintext with the primary key. additionalData is
+// authenticated but not encrypted, and must be given again to decrypt.
+func (k *Keyring) Encrypt(plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead := k.ciphers[k.primary]
+
+	headerSize := 2 + len(k.primary)
+	frame := make([]byte, headerSize+aead.NonceSize(), headerSize+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	frame[0] = frameVersion
+	frame[1] = byte(len(k.primary))
+	copy(frame[2:], k.primary)
+
+	nonce := frame[headerSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(frame, nonce, plaintext, additionalData), nil
+}
+
+// Decrypt decrypts a value encrypted by Encrypt with any key of the keyring
+func (k *Keyring) Decrypt(frame []byte, additionalData []byte) ([]byte, error) {
+	if len(frame) < 2 || frame[0] != frameVersion {
+		return nil, ErrInvalidCiphertext
+	}
+
+	headerSize := 2 + int(frame[1])
+	if len(frame) < headerSize {
+		return nil, ErrInvalidCiphertext
+	}
+	aead, ok := k.ciphers[string(frame[2:headerSize])]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if len(frame) < headerSize+aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce := frame[headerSize : headerSize+aead.NonceSize()]
+	return aead.Open(nil, nonce, frame[headerSize+aead.NonceSize():], additionalData)
+}
@@ -0,0 +1,78 @@
+package encryption
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, 32)
+	newKey = bytes.Repeat([]byte{2}, 16)
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	keyring, err := NewKeyring("v1", map[string][]byte{"v1": oldKey})
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", keyring.Primary())
+
+	frame, err := keyring.Encrypt([]byte("secret"), []byte("key"))
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(frame, []byte("secret")))
+
+	plaintext, err := keyring.Decrypt(frame, []byte("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	// Values are bound to their additional data
+	_, err = keyring.Decrypt(frame, []byte("other key"))
+	assert.NotNil(t, err)
+
+	// Tampered values are rejected
+	frame[len(frame)-1] ^= 1
+	_, err = keyring.Decrypt(frame, []byte("key"))
+	assert.NotNil(t, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKeyring, err := NewKeyring("v1", map[string][]byte{"v1": oldKey})
+	assert.Nil(t, err)
+	oldFrame, err := oldKeyring.Encrypt([]byte("old secret"), nil)
+	assert.Nil(t, err)
+
+	rotated, err := NewKeyring("v2", map[string][]byte{"v1": oldKey, "v2": newKey})
+	assert.Nil(t, err)
+
+	// Values encrypted under the previous key are still readable
+	plaintext, err := rotated.Decrypt(oldFrame, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "old secret", string(plaintext))
+
+	// New values use the new primary key, unknown to the old keyring
+	newFrame, err := rotated.Encrypt([]byte("new secret"), nil)
+	assert.Nil(t, err)
+	_, err = oldKeyring.Decrypt(newFrame, nil)
+	assert.Equal(t, ErrUnknownKey, err)
+}
+
+func TestInvalidKeyring(t *testing.T) {
+	_, err := NewKeyring("v2", map[string][]byte{"v1": oldKey})
+	assert.Equal(t, ErrUnknownKey, err)
+
+	_, err = NewKeyring("v1", map[string][]byte{"v1": []byte("short")})
+	assert.NotNil(t, err)
+
+	_, err = NewKeyring("", map[string][]byte{"": oldKey})
+	assert.Equal(t, ErrInvalidKeyID, err)
+}
+
+func TestDecryptInvalidFrame(t *testing.T) {
+	keyring, err := NewKeyring("v1", map[string][]byte{"v1": oldKey})
+	assert.Nil(t, err)
+
+	for _, frame := range [][]byte{nil, []byte("plain"), {frameVersion, 10, 'v'}, {frameVersion, 2, 'v', '1'}} {
+		_, err = keyring.Decrypt(frame, nil)
+		assert.NotNil(t, err)
+	}
+}
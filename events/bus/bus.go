@@ -0,0 +1,95 @@
+package bus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/josemiguelmelo/gocacheable/events"
+	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
+)
+
+// Transport carries serialized events between the instances of a service
+type Transport interface {
+	// Publish sends message to every instance subscribed, including this one
+	Publish(ctx context.Context, message []byte) error
+	// Subscribe calls handler with every message published until the
+	// transport is closed. It returns once the subscription is active.
+	Subscribe(ctx context.Context, handler func(message []byte)) error
+	Close() error
+}
+
+// Bus emits cache events to the local events manager and publishes them to
+// the other instances, which emit them to their own events manager. This
+// keeps the in process caches of every instance consistent.
+type Bus struct {
+	// Origin identifies this instance. Events published by it are not emitted
+	// twice when received back from the transport.
+	Origin string
+
+	eventsManager *events.CacheEventsManager
+	transport     Transport
+	registry      *Registry
+}
+
+// New returns a bus emitting the events received to eventsManager. A nil
+// registry uses NewRegistry.
+func New(eventsManager *events.CacheEventsManager, transport Transport, registry *Registry) *Bus {
+	if registry == nil {
+		registry = NewRegistry()
+	}
+	return &Bus{
+		Origin:        newOrigin(),
+		eventsManager: eventsManager,
+		transport:     transport,
+		registry:      registry,
+	}
+}
+
+func newOrigin() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Start subscribes to the events published by the other instances
+func (b *Bus) Start(ctx context.Context) error {
+	return b.transport.Subscribe(ctx, b.receive)
+}
+
+// EmitEvent emits event to the local subscribers of eventType, then publishes
+// it to the other instances. The event type must be registered in the registry.
+func (b *Bus) EmitEvent(ctx context.Context, eventType string, event gei.CacheEvent) error {
+	data, err := encodeMessage(b.registry, b.Origin, eventType, event)
+	if err != nil {
+		return err
+	}
+
+	b.eventsManager.EmitEvent(eventType, event)
+	return b.transport.Publish(ctx, data)
+}
+
+// Close closes the transport
+func (b *Bus) Close() error {
+	return b.transport.Close()
+}
+
+// receive emits an event published by another instance. It does not wait for
+// slow subscribers, which would delay the transport and every other event:
+// the subscribers with a full queue are skipped.
+func (b *Bus) receive(data []byte) {
+	m, event, err := decodeMessage(b.registry, data)
+	if err != nil {
+		logrus.Errorln(fmt.Sprintf("Error decoding event received with err = %s", err.Error()))
+		return
+	}
+	if m.Origin == b.Origin {
+		return
+	}
+	if err = b.eventsManager.TryEmitEvent(m.Type, event); err != nil {
+		logrus.Errorln(fmt.Sprintf("Error emitting event %s received with err = %s", m.Type, err.Error()))
+	}
+}
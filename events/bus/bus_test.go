@@ -0,0 +1,184 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josemiguelmelo/gocacheable/events"
+	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryHub delivers the messages published by any of its transports to all of them
+type memoryHub struct {
+	mu       sync.Mutex
+	handlers []func([]byte)
+}
+
+type memoryTransport struct {
+	hub *memoryHub
+}
+
+func (t *memoryTransport) Publish(ctx context.Context, message []byte) error {
+	t.hub.mu.Lock()
+	handlers := append([]func([]byte){}, t.hub.handlers...)
+	t.hub.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+func (t *memoryTransport) Subscribe(ctx context.Context, handler func([]byte)) error {
+	t.hub.mu.Lock()
+	defer t.hub.mu.Unlock()
+	t.hub.handlers = append(t.hub.handlers, handler)
+	return nil
+}
+
+func (t *memoryTransport) Close() error {
+	return nil
+}
+
+type updateEvent struct {
+	ID int
+}
+
+func (e *updateEvent) Invoke() error {
+	return nil
+}
+
+// newInstance returns the events manager of an instance, with a module
+// subscribed to eventType sending the events received to the returned channel
+func newInstance(t *testing.T, hub *memoryHub, registry *Registry, eventType string) (*Bus, chan gei.CacheEvent) {
+	eventsManager := events.NewCacheEventsManager()
+	assert.Nil(t, eventsManager.RegisterEvent(eventType))
+	_, err := eventsManager.RegisterModule("module")
+	assert.Nil(t, err)
+
+	received := make(chan gei.CacheEvent, 10)
+	_, err = eventsManager.SubscribeEvent("module", eventType, func(event gei.CacheEvent) {
+		received <- event
+	})
+	assert.Nil(t, err)
+
+	eventBus := New(&eventsManager, &memoryTransport{hub: hub}, registry)
+	assert.Nil(t, eventBus.Start(context.Background()))
+	return eventBus, received
+}
+
+func receive(t *testing.T, received chan gei.CacheEvent) gei.CacheEvent {
+	select {
+	case event := <-received:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+		return nil
+	}
+}
+
+func TestBusEmitsToEveryInstance(t *testing.T) {
+	hub := &memoryHub{}
+	registry := NewRegistry()
+	assert.Nil(t, registry.Register("update", &updateEvent{}))
+
+	first, firstReceived := newInstance(t, hub, registry, "updated")
+	_, secondReceived := newInstance(t, hub, registry, "updated")
+
+	assert.Nil(t, first.EmitEvent(context.Background(), "updated", &updateEvent{ID: 42}))
+	assert.Equal(t, &updateEvent{ID: 42}, receive(t, firstReceived))
+	assert.Equal(t, &updateEvent{ID: 42}, receive(t, secondReceived))
+
+	// The emitting instance does not receive its own event twice
+	assert.Nil(t, first.EmitEvent(context.Background(), "updated", &updateEvent{ID: 43}))
+	assert.Equal(t, &updateEvent{ID: 43}, receive(t, firstReceived))
+	assert.Equal(t, &updateEvent{ID: 43}, receive(t, secondReceived))
+	assert.Equal(t, 0, len(firstReceived))
+}
+
+func TestBusBuiltinEvents(t *testing.T) {
+	hub := &memoryHub{}
+	first, _ := newInstance(t, hub, nil, gei.EventInvalidateTag)
+	_, received := newInstance(t, hub, nil, gei.EventInvalidateTag)
+
+	assert.Nil(t, first.EmitEvent(context.Background(), gei.EventInvalidateTag, &gei.InvalidateTagCacheEvent{Tag: "user:42"}))
+	assert.Equal(t, &gei.InvalidateTagCacheEvent{Tag: "user:42"}, receive(t, received))
+}
+
+func TestBusRejectsUnregisteredEvents(t *testing.T) {
+	first, received := newInstance(t, &memoryHub{}, nil, "updated")
+
+	err := first.EmitEvent(context.Background(), "updated", &updateEvent{ID: 42})
+	assert.Equal(t, ErrUnknownEvent, err)
+	assert.Equal(t, 0, len(received))
+
+	// Messages of unknown or invalid events are dropped
+	first.receive([]byte(`{"origin":"other","type":"updated","name":"update","event":{}}`))
+	first.receive([]byte(`not json`))
+	assert.Equal(t, 0, len(received))
+}
+
+func TestBusReceiveDoesNotWaitForSubscribers(t *testing.T) {
+	hub := &memoryHub{}
+	registry := NewRegistry()
+	assert.Nil(t, registry.Register("update", &updateEvent{}))
+	first, _ := newInstance(t, hub, registry, "updated")
+
+	eventsManager := events.NewCacheEventsManager()
+	assert.Nil(t, eventsManager.RegisterEvent("updated"))
+	_, err := eventsManager.RegisterModule("module", events.WithQueueCapacity(0))
+	assert.Nil(t, err)
+	release := make(chan struct{})
+	_, err = eventsManager.SubscribeEvent("module", "updated", func(event gei.CacheEvent) {
+		<-release
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, New(&eventsManager, &memoryTransport{hub: hub}, registry).Start(context.Background()))
+
+	// The blocked subscriber drops the events it cannot queue
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			first.EmitEvent(context.Background(), "updated", &updateEvent{ID: i})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("receive blocked by a slow subscriber")
+	}
+	close(release)
+	assert.Nil(t, eventsManager.Close(context.Background()))
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	assert.Nil(t, registry.Register("update", &updateEvent{}))
+	assert.NotNil(t, registry.Register("update", &gei.ResetCacheEvent{}))
+	assert.NotNil(t, registry.Register("other", &updateEvent{}))
+	assert.NotNil(t, registry.Register("value", updateValue{}))
+
+	data, err := encodeMessage(registry, "origin", "updated", &updateEvent{ID: 42})
+	assert.Nil(t, err)
+	m, event, err := decodeMessage(registry, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "origin", m.Origin)
+	assert.Equal(t, "updated", m.Type)
+	assert.Equal(t, &updateEvent{ID: 42}, event)
+
+	_, err = encodeMessage(registry, "origin", "updated", updateValue{})
+	assert.True(t, errors.Is(err, ErrUnknownEvent))
+}
+
+// updateValue is an event that is not a pointer
+type updateValue struct{}
+
+func (e updateValue) Invoke() error {
+	return nil
+}
@@ -0,0 +1,45 @@
+package bus
+
+import (
+	"encoding/json"
+
+	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
+)
+
+// message is an event as sent over a transport
+type message struct {
+	// Origin identifies the instance publishing the event
+	Origin string `json:"origin"`
+	// Type is the event type subscribed to by modules
+	Type string `json:"type"`
+	// Name is the name of the event in the registry
+	Name  string          `json:"name"`
+	Event json.RawMessage `json:"event"`
+}
+
+func encodeMessage(registry *Registry, origin string, eventType string, event gei.CacheEvent) ([]byte, error) {
+	name, err := registry.name(event)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(message{Origin: origin, Type: eventType, Name: name, Event: data})
+}
+
+func decodeMessage(registry *Registry, data []byte) (message, gei.CacheEvent, error) {
+	var m message
+	if err := json.Unmarshal(data, &m); err != nil {
+		return message{}, nil, err
+	}
+	event, err := registry.new(m.Name)
+	if err != nil {
+		return message{}, nil, err
+	}
+	if err = json.Unmarshal(m.Event, event); err != nil {
+		return message{}, nil, err
+	}
+	return m, event, nil
+}
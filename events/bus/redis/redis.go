@@ -0,0 +1,241 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ACTION_PUBLISH redis publish message action
+	ACTION_PUBLISH = "PUBLISH"
+	// DefaultChannel is the channel events are published to by default
+	DefaultChannel = "gocacheable:events"
+)
+
+const (
+	// minReconnectDelay is the delay before the first attempt to subscribe
+	// again after the connection was lost, doubled after every failure
+	minReconnectDelay = 100 * time.Millisecond
+	// maxReconnectDelay bounds the delay between attempts to subscribe again
+	maxReconnectDelay = 5 * time.Second
+)
+
+// ErrClosed is returned when using a closed transport
+var ErrClosed = errors.New("Transport closed")
+
+// RedisTransport carries events over Redis pub/sub. Messages published while
+// the subscription connection is lost are not received by the instance.
+type RedisTransport struct {
+	Addr    string
+	Channel string
+
+	pool *redis.Pool
+
+	mu     sync.Mutex
+	closed bool
+	// conns are the subscription connections, closed to stop the receivers
+	conns map[redis.Conn]struct{}
+	ctx   context.Context
+	stop  context.CancelFunc
+	wg    sync.WaitGroup
+}
+
+// NewRedisTransport returns a transport publishing to channel on the server at
+// addr. An empty channel uses DefaultChannel.
+func NewRedisTransport(addr string, channel string) *RedisTransport {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	return &RedisTransport{
+		Addr:    addr,
+		Channel: channel,
+		pool: &redis.Pool{
+			MaxIdle: 1,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial("tcp", addr)
+			},
+		},
+		conns: map[redis.Conn]struct{}{},
+		ctx:   ctx,
+		stop:  stop,
+	}
+}
+
+// Publish sends message to every instance subscribed to the channel
+func (t *RedisTransport) Publish(ctx context.Context, message []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if t.isClosed() {
+		return ErrClosed
+	}
+
+	conn, err := t.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_, err = redis.DoWithTimeout(conn, time.Until(deadline), ACTION_PUBLISH, t.Channel, message)
+	} else {
+		_, err = conn.Do(ACTION_PUBLISH, t.Channel, message)
+	}
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Subscribe calls handler with every message published to the channel until
+// the transport is closed, subscribing again when the connection is lost. It
+// returns once the first subscription is active.
+func (t *RedisTransport) Subscribe(ctx context.Context, handler func(message []byte)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// The receiver is counted before Close may wait for it
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return ErrClosed
+	}
+	t.wg.Add(1)
+	t.mu.Unlock()
+
+	conn, err := t.subscribe(ctx)
+	if err != nil {
+		t.wg.Done()
+		return err
+	}
+	go t.receive(conn, handler)
+	return nil
+}
+
+// Close stops the subscriptions, waiting for the handlers in progress
+func (t *RedisTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.stop()
+	for conn := range t.conns {
+		conn.Close()
+	}
+	t.mu.Unlock()
+
+	t.wg.Wait()
+	return t.pool.Close()
+}
+
+func (t *RedisTransport) isClosed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+// subscribe opens a connection subscribed to the channel
+func (t *RedisTransport) subscribe(ctx context.Context) (redis.PubSubConn, error) {
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", t.Addr)
+	if err != nil {
+		if ctx.Err() != nil {
+			return redis.PubSubConn{}, ctx.Err()
+		}
+		return redis.PubSubConn{}, err
+	}
+	conn := redis.PubSubConn{Conn: redis.NewConn(netConn, 0, 0)}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		conn.Close()
+		return redis.PubSubConn{}, ErrClosed
+	}
+	t.conns[conn.Conn] = struct{}{}
+	t.mu.Unlock()
+
+	// Closing the connection unblocks the confirmation wait when ctx is done
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err = conn.Subscribe(t.Channel); err == nil {
+		switch reply := conn.Receive().(type) {
+		case redis.Subscription:
+		case error:
+			err = reply
+		default:
+			err = fmt.Errorf("Unexpected reply %v", reply)
+		}
+	}
+	if err != nil {
+		t.release(conn)
+		if ctx.Err() != nil {
+			return redis.PubSubConn{}, ctx.Err()
+		}
+		return redis.PubSubConn{}, err
+	}
+	return conn, nil
+}
+
+// release closes a subscription connection
+func (t *RedisTransport) release(conn redis.PubSubConn) {
+	t.mu.Lock()
+	delete(t.conns, conn.Conn)
+	t.mu.Unlock()
+	conn.Close()
+}
+
+// receive calls handler with the messages received on conn, subscribing again
+// when the connection is lost, until the transport is closed
+func (t *RedisTransport) receive(conn redis.PubSubConn, handler func(message []byte)) {
+	defer t.wg.Done()
+
+	for {
+		switch reply := conn.Receive().(type) {
+		case redis.Message:
+			handler(reply.Data)
+		case error:
+			t.release(conn)
+			var ok bool
+			if conn, ok = t.resubscribe(reply); !ok {
+				return
+			}
+		}
+	}
+}
+
+// resubscribe subscribes again after the connection was lost with err, until
+// the transport is closed
+func (t *RedisTransport) resubscribe(err error) (redis.PubSubConn, bool) {
+	delay := minReconnectDelay
+	for {
+		if t.ctx.Err() != nil {
+			return redis.PubSubConn{}, false
+		}
+		logrus.Errorln(fmt.Sprintf("Error receiving events from channel %s with err = %s", t.Channel, err.Error()))
+
+		select {
+		case <-t.ctx.Done():
+			return redis.PubSubConn{}, false
+		case <-time.After(delay):
+		}
+
+		var conn redis.PubSubConn
+		if conn, err = t.subscribe(t.ctx); err == nil {
+			return conn, true
+		}
+		delay = min(2*delay, maxReconnectDelay)
+	}
+}
@@ -0,0 +1,136 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/josemiguelmelo/gocacheable"
+	"github.com/josemiguelmelo/gocacheable/events/bus"
+	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
+	lruProvider "github.com/josemiguelmelo/gocacheable/providers/lru"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTransport(t *testing.T, addr string) (*RedisTransport, chan []byte) {
+	transport := NewRedisTransport(addr, "")
+	t.Cleanup(func() { transport.Close() })
+
+	received := make(chan []byte, 10)
+	assert.Nil(t, transport.Subscribe(context.Background(), func(message []byte) {
+		received <- message
+	}))
+	return transport, received
+}
+
+func receive(t *testing.T, received chan []byte) string {
+	select {
+	case message := <-received:
+		return string(message)
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not received")
+		return ""
+	}
+}
+
+func TestPublishAndSubscribe(t *testing.T) {
+	server := miniredis.RunT(t)
+	first, firstReceived := newTransport(t, server.Addr())
+	_, secondReceived := newTransport(t, server.Addr())
+	assert.Equal(t, DefaultChannel, first.Channel)
+
+	assert.Nil(t, first.Publish(context.Background(), []byte("message")))
+	assert.Equal(t, "message", receive(t, firstReceived))
+	assert.Equal(t, "message", receive(t, secondReceived))
+
+	// Other channels are not received
+	other := NewRedisTransport(server.Addr(), "other")
+	defer other.Close()
+	assert.Nil(t, other.Publish(context.Background(), []byte("other")))
+	assert.Nil(t, first.Publish(context.Background(), []byte("next")))
+	assert.Equal(t, "next", receive(t, secondReceived))
+}
+
+func TestSubscribeAgainAfterConnectionLost(t *testing.T) {
+	server := miniredis.RunT(t)
+	transport, received := newTransport(t, server.Addr())
+
+	server.Close()
+	assert.Nil(t, server.Restart())
+
+	// Publish until the subscription is active again
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		transport.Publish(context.Background(), []byte("message"))
+		select {
+		case message := <-received:
+			assert.Equal(t, "message", string(message))
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	t.Fatal("subscription not restored")
+}
+
+func TestClose(t *testing.T) {
+	server := miniredis.RunT(t)
+	transport, _ := newTransport(t, server.Addr())
+
+	assert.Nil(t, transport.Close())
+	assert.Nil(t, transport.Close())
+	assert.Equal(t, ErrClosed, transport.Publish(context.Background(), []byte("message")))
+	assert.Equal(t, ErrClosed, transport.Subscribe(context.Background(), func([]byte) {}))
+}
+
+func TestContextCanceled(t *testing.T) {
+	server := miniredis.RunT(t)
+	transport := NewRedisTransport(server.Addr(), "")
+	defer transport.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, transport.Publish(ctx, []byte("message")))
+	assert.Equal(t, context.Canceled, transport.Subscribe(ctx, func([]byte) {}))
+}
+
+// TestBusInvalidatesEveryInstance runs two instances caching the same value in
+// process, and checks that deleting it from one instance removes it from both
+func TestBusInvalidatesEveryInstance(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	var managers []*gocacheable.CacheableManager
+	var buses []*bus.Bus
+	deleted := make(chan gei.CacheEvent, 10)
+	for i := 0; i < 2; i++ {
+		manager := gocacheable.NewCacheableManager("manager")
+		assert.Nil(t, manager.AddModule("users", lruProvider.NewLRUProvider(1000, 0)))
+		_, err := manager.SubscribeEvent("users", gei.EventDeleteKeys, func(event gei.CacheEvent) {
+			deleted <- event
+		})
+		assert.Nil(t, err)
+		assert.Nil(t, manager.Set("users", "user:42", "john", time.Minute))
+
+		eventBus := bus.New(&manager.EventsManager, NewRedisTransport(server.Addr(), ""), nil)
+		assert.Nil(t, eventBus.Start(context.Background()))
+		t.Cleanup(func() { eventBus.Close() })
+
+		managers = append(managers, &manager)
+		buses = append(buses, eventBus)
+	}
+
+	err := buses[0].EmitEvent(context.Background(), gei.EventDeleteKeys, &gei.RemoveCacheEvent{Keys: []string{"user:42"}})
+	assert.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-deleted:
+		case <-time.After(2 * time.Second):
+			t.Fatal("event not received")
+		}
+	}
+
+	var out string
+	for _, manager := range managers {
+		assert.NotNil(t, manager.Get("users", "user:42", &out))
+	}
+}
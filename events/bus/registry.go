@@ -0,0 +1,79 @@
+package bus
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
+)
+
+// ErrUnknownEvent is returned for events whose type is not registered
+var ErrUnknownEvent = errors.New("Event type not registered")
+
+// Registry maps the events sent over a transport to names, so that they can
+// be decoded by the other instances. Events must be pointers to structs
+// encodable with encoding/json.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// NewRegistry returns a registry with the built-in events registered
+func NewRegistry() *Registry {
+	registry := &Registry{
+		types: map[string]reflect.Type{},
+		names: map[reflect.Type]string{},
+	}
+	registry.Register("remove", &gei.RemoveCacheEvent{})
+	registry.Register("reset", &gei.ResetCacheEvent{})
+	registry.Register("invalidate-tag", &gei.InvalidateTagCacheEvent{})
+	return registry
+}
+
+// Register registers the type of event under name. Every instance must
+// register the same types under the same names.
+func (r *Registry) Register(name string, event gei.CacheEvent) error {
+	eventType := reflect.TypeOf(event)
+	if eventType == nil || eventType.Kind() != reflect.Ptr || eventType.Elem().Kind() != reflect.Struct {
+		return errors.New("Event must be a pointer to a struct")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.types[name]; ok {
+		return errors.New("Event name already registered")
+	}
+	if _, ok := r.names[eventType]; ok {
+		return errors.New("Event type already registered")
+	}
+	r.types[name] = eventType.Elem()
+	r.names[eventType] = name
+	return nil
+}
+
+// name returns the name event is registered under
+func (r *Registry) name(event gei.CacheEvent) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.names[reflect.TypeOf(event)]
+	if !ok {
+		return "", ErrUnknownEvent
+	}
+	return name, nil
+}
+
+// new returns a new event of the type registered under name
+func (r *Registry) new(name string) (gei.CacheEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	eventType, ok := r.types[name]
+	if !ok {
+		return nil, ErrUnknownEvent
+	}
+	return reflect.New(eventType).Interface().(gei.CacheEvent), nil
+}
@@ -6,7 +6,11 @@ import (
 
 // CacheEventChannel to handle events that will affect cache
 type CacheEventChannel struct {
-	Identifier       string
+	Identifier string
+	// Channel never receives events, it is closed when the module is
+	// unregistered.
+	//
+	// Deprecated: events are delivered to the callbacks of the subscriptions.
 	Channel          chan gei.CacheEvent
 	SubscribedEvents []string
 }
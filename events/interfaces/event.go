@@ -1,14 +1,77 @@
 package events
 
+import "context"
+
+const (
+	// EventDeleteKeys is the type of the built-in event removing keys from the subscribed modules
+	EventDeleteKeys = "delete-keys"
+	// EventResetModule is the type of the built-in event resetting the subscribed modules
+	EventResetModule = "reset-module"
+	// EventInvalidateTag is the type of the built-in event invalidating a tag in the subscribed modules
+	EventInvalidateTag = "invalidate-tag"
+)
+
 // CacheEvent interface that represents an event that disputes an action over the cache storage
 type CacheEvent interface {
 	Invoke() error
 }
 
-// RemoveCacheEvent represent an event that removes a key from the cache storage
-type RemoveCacheEvent struct{}
+// ModuleCache is the cache of a module, on which module events act
+type ModuleCache interface {
+	DeleteManyContext(ctx context.Context, keys []string) error
+	ResetContext(ctx context.Context) error
+	InvalidateTagContext(ctx context.Context, tag string) error
+}
+
+// ModuleCacheEvent is a cache event acting on the cache of every module it is
+// delivered to, instead of being invoked once
+type ModuleCacheEvent interface {
+	CacheEvent
+	InvokeOn(ctx context.Context, cache ModuleCache) error
+}
+
+// RemoveCacheEvent represent an event that removes keys from the cache storage
+type RemoveCacheEvent struct {
+	Keys []string
+}
+
+// Invoke does nothing, the keys are removed from each module receiving the event by InvokeOn
+func (removeCacheEvent *RemoveCacheEvent) Invoke() error {
+	return nil
+}
+
+// InvokeOn removes the keys from the cache storage of a module
+func (removeCacheEvent *RemoveCacheEvent) InvokeOn(ctx context.Context, cache ModuleCache) error {
+	if len(removeCacheEvent.Keys) == 0 {
+		return nil
+	}
+	return cache.DeleteManyContext(ctx, removeCacheEvent.Keys)
+}
+
+// ResetCacheEvent represent an event that empties the cache storage
+type ResetCacheEvent struct{}
 
-// Invoke receives the key to be removed and removes it from the cache storage
-func (removeCacheEvent *RemoveCacheEvent) Invoke(key string) error {
+// Invoke does nothing, each module receiving the event is reset by InvokeOn
+func (resetCacheEvent *ResetCacheEvent) Invoke() error {
 	return nil
 }
+
+// InvokeOn empties the cache storage of a module
+func (resetCacheEvent *ResetCacheEvent) InvokeOn(ctx context.Context, cache ModuleCache) error {
+	return cache.ResetContext(ctx)
+}
+
+// InvalidateTagCacheEvent represent an event that removes the keys cached with a tag
+type InvalidateTagCacheEvent struct {
+	Tag string
+}
+
+// Invoke does nothing, the tag is invalidated in each module receiving the event by InvokeOn
+func (invalidateTagCacheEvent *InvalidateTagCacheEvent) Invoke() error {
+	return nil
+}
+
+// InvokeOn removes the keys cached with the tag from the cache storage of a module
+func (invalidateTagCacheEvent *InvalidateTagCacheEvent) InvokeOn(ctx context.Context, cache ModuleCache) error {
+	return cache.InvalidateTagContext(ctx, invalidateTagCacheEvent.Tag)
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeModuleCache records the calls of module events
+type fakeModuleCache struct {
+	deleted     []string
+	resets      int
+	invalidated []string
+}
+
+func (c *fakeModuleCache) DeleteManyContext(ctx context.Context, keys []string) error {
+	c.deleted = append(c.deleted, keys...)
+	return nil
+}
+
+func (c *fakeModuleCache) ResetContext(ctx context.Context) error {
+	c.resets++
+	return nil
+}
+
+func (c *fakeModuleCache) InvalidateTagContext(ctx context.Context, tag string) error {
+	c.invalidated = append(c.invalidated, tag)
+	return nil
+}
+
+func TestBuiltinEvents(t *testing.T) {
+	cache := &fakeModuleCache{}
+	builtins := []ModuleCacheEvent{
+		&RemoveCacheEvent{Keys: []string{"a", "b"}},
+		&RemoveCacheEvent{},
+		&ResetCacheEvent{},
+		&InvalidateTagCacheEvent{Tag: "user:42"},
+	}
+	for _, event := range builtins {
+		assert.Nil(t, event.Invoke())
+		assert.Nil(t, event.InvokeOn(context.Background(), cache))
+	}
+
+	assert.Equal(t, []string{"a", "b"}, cache.deleted)
+	assert.Equal(t, 1, cache.resets)
+	assert.Equal(t, []string{"user:42"}, cache.invalidated)
+}
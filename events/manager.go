@@ -1,43 +1,142 @@
 package events
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/josemiguelmelo/gocacheable/events/channel"
 	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
 	subscriber "github.com/josemiguelmelo/gocacheable/events/subscriber"
 )
 
-// CacheEventsManager manages cache events
+// ErrClosed is returned when using a closed events manager
+var ErrClosed = errors.New("Events manager closed")
+
+// CacheEventsManager manages cache events. It is safe for concurrent use, so
+// that events can be emitted from other goroutines, such as an event bus.
+// Copies of a manager share its modules and event types.
 type CacheEventsManager struct {
-	modules    map[string]*channel.CacheEventChannel
+	state *managerState
+}
+
+type managerState struct {
+	mu         sync.RWMutex
+	modules    map[string]*module
 	eventTypes []string
+	closed     bool
+	// emitting counts the EmitEvent calls in progress
+	emitting sync.WaitGroup
+	// undelivered are the events not delivered because of Close
+	undelivered []UndeliveredEvent
+}
+
+// module is a module registered to listen for events
+type module struct {
+	channel       *channel.CacheEventChannel
+	subscriptions map[string]*subscription
+	queue         queueConfig
+}
+
+// subscription delivers the events of a type to a module. Events are queued
+// in its channel until its callback is called with them.
+type subscription struct {
+	module     string
+	eventType  string
+	channel    chan gei.CacheEvent
+	policy     OverflowPolicy
+	subscriber subscriber.CacheEventSubscriber
+}
+
+// UndeliveredEvent is an event not delivered to a module subscribed to it
+type UndeliveredEvent struct {
+	Module    string
+	EventType string
+	Event     gei.CacheEvent
+}
+
+// UndeliveredEventsError is returned by Close when events could not be
+// delivered before its context was done
+type UndeliveredEventsError struct {
+	Events []UndeliveredEvent
+	Err    error
+}
+
+func (e *UndeliveredEventsError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%d events not delivered", len(e.Events))
+	}
+	return fmt.Sprintf("%d events not delivered: %s", len(e.Events), e.Err.Error())
+}
+
+func (e *UndeliveredEventsError) Unwrap() error {
+	return e.Err
 }
 
 // NewCacheEventsManager create new channel event manager
 func NewCacheEventsManager() CacheEventsManager {
 	return CacheEventsManager{
-		modules:    map[string]*channel.CacheEventChannel{},
-		eventTypes: []string{},
+		state: &managerState{
+			modules:    map[string]*module{},
+			eventTypes: []string{},
+		},
 	}
 }
 
-// RegisterModule register a new module to listen for events
-func (cm *CacheEventsManager) RegisterModule(moduleName string) (*chan gei.CacheEvent, error) {
-	if cm.ContainsModule(moduleName) {
+// RegisterModule register a new module to listen for events. Events are
+// delivered to the callbacks given to SubscribeEvent, never on the channel
+// returned, which is only closed when the module is unregistered. Each
+// subscription of the module queues up to DefaultQueueCapacity events, waiting
+// for room when full, unless configured otherwise by options.
+func (cm *CacheEventsManager) RegisterModule(moduleName string, options ...QueueOption) (*chan gei.CacheEvent, error) {
+	cm.state.mu.Lock()
+	defer cm.state.mu.Unlock()
+
+	if cm.state.closed {
+		return nil, ErrClosed
+	}
+	if cm.containsModule(moduleName) {
 		return nil, errors.New("Module already exists")
 	}
-	cm.modules[moduleName] = &channel.CacheEventChannel{
-		Identifier:       moduleName,
-		Channel:          make(chan gei.CacheEvent),
-		SubscribedEvents: []string{},
+	cm.state.modules[moduleName] = &module{
+		channel: &channel.CacheEventChannel{
+			Identifier:       moduleName,
+			Channel:          make(chan gei.CacheEvent),
+			SubscribedEvents: []string{},
+		},
+		subscriptions: map[string]*subscription{},
+		queue:         newQueueConfig(options),
+	}
+	return &cm.state.modules[moduleName].channel.Channel, nil
+}
+
+// UnregisterModule unsubscribes a module from every event and removes it
+func (cm *CacheEventsManager) UnregisterModule(moduleName string) error {
+	cm.state.mu.Lock()
+	defer cm.state.mu.Unlock()
+
+	m, ok := cm.state.modules[moduleName]
+	if !ok {
+		return errors.New("Module not found")
+	}
+	for _, s := range m.subscriptions {
+		s.subscriber.Unsubscribe()
 	}
-	return &cm.modules[moduleName].Channel, nil
+	delete(cm.state.modules, moduleName)
+	close(m.channel.Channel)
+	return nil
 }
 
 // ContainsModule returns true if module already exists
 func (cm *CacheEventsManager) ContainsModule(moduleName string) bool {
-	if _, ok := cm.modules[moduleName]; ok {
+	cm.state.mu.RLock()
+	defer cm.state.mu.RUnlock()
+	return cm.containsModule(moduleName)
+}
+
+func (cm *CacheEventsManager) containsModule(moduleName string) bool {
+	if _, ok := cm.state.modules[moduleName]; ok {
 		return true
 	}
 	return false
@@ -45,53 +144,117 @@ func (cm *CacheEventsManager) ContainsModule(moduleName string) bool {
 
 // ModuleCount returns the number of registered modules
 func (cm *CacheEventsManager) ModuleCount() int {
-	return len(cm.modules)
+	cm.state.mu.RLock()
+	defer cm.state.mu.RUnlock()
+	return len(cm.state.modules)
 }
 
-// SubscribeEvent subscribes a module to a event
+// SubscribeEvent subscribes a module to a event. The callback is called by a
+// goroutine of the subscription with every event of the type emitted, until
+// the module is unsubscribed.
 func (cm *CacheEventsManager) SubscribeEvent(moduleName string, eventType string, callback func(gei.CacheEvent)) (subscriber.CacheEventSubscriber, error) {
-	if !cm.ContainsModule(moduleName) {
+	cm.state.mu.Lock()
+	defer cm.state.mu.Unlock()
+
+	if cm.state.closed {
+		return subscriber.CacheEventSubscriber{}, ErrClosed
+	}
+
+	if !cm.containsModule(moduleName) {
 		return subscriber.CacheEventSubscriber{}, errors.New("Module not found")
 	}
 
-	if !cm.ContainsEventType(eventType) {
+	if !cm.containsEventType(eventType) {
 		return subscriber.CacheEventSubscriber{}, errors.New("Event not found")
 	}
 
-	if cm.modules[moduleName].IsSubscribedTo(eventType) {
+	m := cm.state.modules[moduleName]
+	if m.channel.IsSubscribedTo(eventType) {
 		return subscriber.CacheEventSubscriber{}, errors.New("Module already subscribed to this event")
 	}
 
-	moduleEvents := cm.modules[moduleName].SubscribedEvents
-	cm.modules[moduleName].SubscribedEvents = append(moduleEvents, eventType)
+	m.channel.SubscribedEvents = append(m.channel.SubscribedEvents, eventType)
+
+	s := &subscription{
+		module:    moduleName,
+		eventType: eventType,
+		channel:   make(chan gei.CacheEvent, m.queue.capacity),
+		policy:    m.queue.overflow,
+	}
+	s.subscriber.Subscribe(&s.channel, func(event gei.CacheEvent) {
+		// Events still queued when Close gives up are not delivered
+		select {
+		case <-s.subscriber.Done():
+			cm.undelivered(moduleName, eventType, event)
+		default:
+			callback(event)
+		}
+	})
+	m.subscriptions[eventType] = s
+	return s.subscriber, nil
+}
+
+// Unsubscribe unsubscribes a module from an event. A callback in progress is
+// not interrupted, the events queued are dropped.
+func (cm *CacheEventsManager) Unsubscribe(moduleName string, eventType string) error {
+	cm.state.mu.Lock()
+	defer cm.state.mu.Unlock()
+
+	m, ok := cm.state.modules[moduleName]
+	if !ok {
+		return errors.New("Module not found")
+	}
+	s, ok := m.subscriptions[eventType]
+	if !ok {
+		return errors.New("Module not subscribed to this event")
+	}
 
-	eventSubscriber := subscriber.CacheEventSubscriber{}
-	eventSubscriber.Subscribe(&cm.modules[moduleName].Channel, callback)
-	return eventSubscriber, nil
+	s.subscriber.Unsubscribe()
+	delete(m.subscriptions, eventType)
+	subscribedEvents := []string{}
+	for _, e := range m.channel.SubscribedEvents {
+		if e != eventType {
+			subscribedEvents = append(subscribedEvents, e)
+		}
+	}
+	m.channel.SubscribedEvents = subscribedEvents
+	return nil
 }
 
 // SubscribedEvents returns list of subscribed event by module name
 func (cm *CacheEventsManager) SubscribedEvents(moduleName string) ([]string, error) {
-	if !cm.ContainsModule(moduleName) {
+	cm.state.mu.RLock()
+	defer cm.state.mu.RUnlock()
+
+	if !cm.containsModule(moduleName) {
 		return nil, errors.New("Module not found")
 	}
 
-	return cm.modules[moduleName].SubscribedEvents, nil
+	return append([]string{}, cm.state.modules[moduleName].channel.SubscribedEvents...), nil
 }
 
 // RegisterEvent register a new event type if it does not exist
 func (cm *CacheEventsManager) RegisterEvent(eventType string) error {
-	if cm.ContainsEventType(eventType) {
+	cm.state.mu.Lock()
+	defer cm.state.mu.Unlock()
+
+	if cm.containsEventType(eventType) {
 		return errors.New("Event already exists")
 	}
 
-	cm.eventTypes = append(cm.eventTypes, eventType)
+	cm.state.eventTypes = append(cm.state.eventTypes, eventType)
 	return nil
 }
 
 // ContainsEventType returns true if event already exists
 func (cm *CacheEventsManager) ContainsEventType(eventType string) bool {
-	for _, e := range cm.eventTypes {
+	cm.state.mu.RLock()
+	defer cm.state.mu.RUnlock()
+	return cm.containsEventType(eventType)
+}
+
+func (cm *CacheEventsManager) containsEventType(eventType string) bool {
+	for _, e := range cm.state.eventTypes {
 		if e == eventType {
 			return true
 		}
@@ -101,14 +264,167 @@ func (cm *CacheEventsManager) ContainsEventType(eventType string) bool {
 
 // EventTypesCount returns the number of event types available
 func (cm *CacheEventsManager) EventTypesCount() int {
-	return len(cm.eventTypes)
+	cm.state.mu.RLock()
+	defer cm.state.mu.RUnlock()
+	return len(cm.state.eventTypes)
 }
 
-// EmitEvent emits the event to all subscribers
+// EmitEvent emits the event to all subscribers. Events emitted once the
+// manager is closed are dropped.
 func (cm *CacheEventsManager) EmitEvent(eventType string, event gei.CacheEvent) {
-	for _, module := range cm.modules {
-		if module.IsSubscribedTo(eventType) {
-			module.Channel <- event
+	cm.EmitEventContext(context.Background(), eventType, event)
+}
+
+// EmitEventContext queues the event for all subscribers. The subscribers with
+// a full queue are handled last, according to their overflow policy, so that
+// they do not delay the others; the ones waiting for room are skipped once
+// ctx is done. It returns a SkippedSubscribersError listing the subscribers
+// the event was not queued for, and ErrClosed once the manager is closed.
+func (cm *CacheEventsManager) EmitEventContext(ctx context.Context, eventType string, event gei.CacheEvent) error {
+	return cm.emit(ctx, eventType, event, true)
+}
+
+// TryEmitEvent queues the event for all subscribers without waiting: the
+// subscribers with the OverflowBlock policy and a full queue are skipped with
+// ErrQueueFull. It suits emitters that must not be delayed by slow
+// subscribers, like the receivers of remote events.
+func (cm *CacheEventsManager) TryEmitEvent(eventType string, event gei.CacheEvent) error {
+	return cm.emit(context.Background(), eventType, event, false)
+}
+
+// emit queues the event for all subscribers, waiting for room in the queues of
+// the subscribers with the OverflowBlock policy if wait is true
+func (cm *CacheEventsManager) emit(ctx context.Context, eventType string, event gei.CacheEvent, wait bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Subscriptions are collected first so that subscribers can use the
+	// manager while the event is being queued
+	cm.state.mu.RLock()
+	if cm.state.closed {
+		cm.state.mu.RUnlock()
+		return ErrClosed
+	}
+	cm.state.emitting.Add(1)
+	defer cm.state.emitting.Done()
+
+	targets := []*subscription{}
+	for _, m := range cm.state.modules {
+		if s, ok := m.subscriptions[eventType]; ok {
+			targets = append(targets, s)
+		}
+	}
+	cm.state.mu.RUnlock()
+
+	full := []*subscription{}
+	for _, s := range targets {
+		if !s.offer(event) {
+			full = append(full, s)
+		}
+	}
+
+	skipped := []SkippedSubscriber{}
+	for _, s := range full {
+		err := s.overflow(ctx, event, wait)
+		if err == errUnsubscribed {
+			// Subscriptions stopped by Close are skipped, others are gone
+			if !cm.undelivered(s.module, eventType, event) {
+				continue
+			}
+			err = ErrClosed
+		}
+		if err != nil {
+			skipped = append(skipped, SkippedSubscriber{Module: s.module, EventType: eventType, Err: err})
 		}
 	}
+	if len(skipped) > 0 {
+		return &SkippedSubscribersError{Subscribers: skipped}
+	}
+	return nil
+}
+
+// undelivered records an event not delivered because the subscription was
+// stopped, returning true if it was stopped by Close
+func (cm *CacheEventsManager) undelivered(moduleName string, eventType string, event gei.CacheEvent) bool {
+	cm.state.mu.Lock()
+	defer cm.state.mu.Unlock()
+
+	if cm.state.closed {
+		cm.state.undelivered = append(cm.state.undelivered, UndeliveredEvent{Module: moduleName, EventType: eventType, Event: event})
+	}
+	return cm.state.closed
+}
+
+// Close stops delivering events. The events being emitted and the events
+// queued are delivered until ctx is done, then every subscription is stopped.
+// The events not delivered are reported by an UndeliveredEventsError.
+func (cm *CacheEventsManager) Close(ctx context.Context) error {
+	cm.state.mu.Lock()
+	if cm.state.closed {
+		cm.state.mu.Unlock()
+		return nil
+	}
+	cm.state.closed = true
+	var subscriptions []*subscription
+	for _, m := range cm.state.modules {
+		for _, s := range m.subscriptions {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+	cm.state.mu.Unlock()
+
+	emitted := make(chan struct{})
+	go func() {
+		cm.state.emitting.Wait()
+		close(emitted)
+	}()
+
+	err := waitFor(ctx, emitted)
+	if err == nil {
+		// Nothing can be queued anymore, the subscribers stop once they
+		// delivered the events left in their queue
+		for _, s := range subscriptions {
+			close(s.channel)
+		}
+		for _, s := range subscriptions {
+			if err == nil {
+				err = waitFor(ctx, s.subscriber.Stopped())
+			}
+		}
+	}
+
+	if err != nil {
+		// Stopping the subscriptions releases the emitters still waiting,
+		// which record their events as undelivered, like the events queued
+		for _, s := range subscriptions {
+			s.subscriber.Unsubscribe()
+		}
+		<-emitted
+		for _, s := range subscriptions {
+			s.drain(func(event gei.CacheEvent) {
+				cm.undelivered(s.module, s.eventType, event)
+			})
+		}
+	}
+
+	cm.state.mu.Lock()
+	undelivered := cm.state.undelivered
+	cm.state.undelivered = nil
+	cm.state.mu.Unlock()
+
+	if len(undelivered) > 0 {
+		return &UndeliveredEventsError{Events: undelivered, Err: err}
+	}
+	return err
+}
+
+// waitFor waits until done is closed, or returns ctx.Err() if ctx is done first
+func waitFor(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
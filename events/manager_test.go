@@ -1,9 +1,14 @@
 package events
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	interfaces "github.com/josemiguelmelo/gocacheable/events/interfaces"
 	"github.com/stretchr/testify/assert"
@@ -157,3 +162,168 @@ func TestEmitEvent(t *testing.T) {
 	invokeCalled = invokeCalled + <-invokeRes
 	assert.Equal(t, 3, invokeCalled)
 }
+
+// newClosingManager returns a manager with a module subscribed to "event",
+// calling callback with every event received
+func newClosingManager(t *testing.T, callback func(interfaces.CacheEvent), options ...QueueOption) (CacheEventsManager, chan struct{}) {
+	manager := NewCacheEventsManager()
+	assert.Nil(t, manager.RegisterEvent("event"))
+	_, err := manager.RegisterModule("module", options...)
+	assert.Nil(t, err)
+	eventSubscriber, err := manager.SubscribeEvent("module", "event", callback)
+	assert.Nil(t, err)
+
+	stopped := make(chan struct{})
+	go func() {
+		<-eventSubscriber.Stopped()
+		close(stopped)
+	}()
+	return manager, stopped
+}
+
+func waitClosed(t *testing.T, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("not closed")
+	}
+}
+
+func TestSubscriptionsReceiveTheirEventsOnly(t *testing.T) {
+	manager := NewCacheEventsManager()
+	_, err := manager.RegisterModule("module")
+	assert.Nil(t, err)
+
+	received := make(chan string, 10)
+	for _, eventType := range []string{"first", "second"} {
+		eventType := eventType
+		assert.Nil(t, manager.RegisterEvent(eventType))
+		_, err = manager.SubscribeEvent("module", eventType, func(interfaces.CacheEvent) {
+			received <- eventType
+		})
+		assert.Nil(t, err)
+	}
+
+	for i := 0; i < 10; i++ {
+		manager.EmitEvent("first", &EventProvider{})
+		assert.Equal(t, "first", <-received)
+		manager.EmitEvent("second", &EventProvider{})
+		assert.Equal(t, "second", <-received)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	received := make(chan interfaces.CacheEvent, 10)
+	manager, stopped := newClosingManager(t, func(event interfaces.CacheEvent) {
+		received <- event
+	})
+
+	assert.Nil(t, manager.Unsubscribe("module", "event"))
+	waitClosed(t, stopped)
+	events, err := manager.SubscribedEvents("module")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(events))
+
+	// Events are no longer delivered, without blocking the emitter
+	manager.EmitEvent("event", &EventProvider{})
+	assert.Equal(t, 0, len(received))
+
+	assert.NotNil(t, manager.Unsubscribe("module", "event"))
+	assert.NotNil(t, manager.Unsubscribe("not_existing_module", "event"))
+
+	// The module can subscribe again
+	_, err = manager.SubscribeEvent("module", "event", func(event interfaces.CacheEvent) {
+		received <- event
+	})
+	assert.Nil(t, err)
+	manager.EmitEvent("event", &EventProvider{})
+	<-received
+}
+
+func TestUnregisterModule(t *testing.T) {
+	manager, stopped := newClosingManager(t, func(interfaces.CacheEvent) {})
+	channel, err := manager.RegisterModule("other")
+	assert.Nil(t, err)
+
+	assert.Nil(t, manager.UnregisterModule("module"))
+	waitClosed(t, stopped)
+	assert.False(t, manager.ContainsModule("module"))
+	assert.NotNil(t, manager.UnregisterModule("module"))
+	manager.EmitEvent("event", &EventProvider{})
+
+	assert.Nil(t, manager.UnregisterModule("other"))
+	_, ok := <-*channel
+	assert.False(t, ok)
+}
+
+func TestCloseDeliversPendingEvents(t *testing.T) {
+	var delivered int32
+	started := make(chan struct{}, 3)
+	manager, stopped := newClosingManager(t, func(interfaces.CacheEvent) {
+		started <- struct{}{}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&delivered, 1)
+	}, WithQueueCapacity(0))
+
+	// Emitters wait for the callback, those not closed out are delivered
+	var wg sync.WaitGroup
+	var emitted int32
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if manager.EmitEventContext(context.Background(), "event", &EventProvider{}) == nil {
+				atomic.AddInt32(&emitted, 1)
+			}
+		}()
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Nil(t, manager.Close(ctx))
+	wg.Wait()
+	waitClosed(t, stopped)
+	assert.Equal(t, atomic.LoadInt32(&emitted), atomic.LoadInt32(&delivered))
+
+	// Closed managers drop events and refuse new subscriptions
+	manager.EmitEvent("event", &EventProvider{})
+	_, err := manager.SubscribeEvent("module", "event", func(interfaces.CacheEvent) {})
+	assert.Equal(t, ErrClosed, err)
+	_, err = manager.RegisterModule("other")
+	assert.Equal(t, ErrClosed, err)
+	assert.Nil(t, manager.Close(ctx))
+}
+
+func TestCloseReportsUndeliveredEvents(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	manager, stopped := newClosingManager(t, func(interfaces.CacheEvent) {
+		started <- struct{}{}
+		<-release
+	})
+
+	// The first event blocks the callback, the second one is queued
+	go manager.EmitEvent("event", &EventProvider{})
+	<-started
+	blocked := &EventProvider{}
+	emitted := make(chan struct{})
+	go func() {
+		manager.EmitEvent("event", blocked)
+		close(emitted)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := manager.Close(ctx)
+	waitClosed(t, emitted)
+
+	var undeliveredErr *UndeliveredEventsError
+	assert.True(t, errors.As(err, &undeliveredErr))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, []UndeliveredEvent{{Module: "module", EventType: "event", Event: blocked}}, undeliveredErr.Events)
+
+	close(release)
+	waitClosed(t, stopped)
+}
@@ -0,0 +1,168 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
+)
+
+// DefaultQueueCapacity is the number of events a subscription queues by default
+const DefaultQueueCapacity = 64
+
+// OverflowPolicy tells what happens to an event emitted to a full queue
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the queue. It is the default policy.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the event emitted
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest event queued to make room for the
+	// event emitted. Queues without capacity drop the event emitted.
+	OverflowDropOldest
+	// OverflowError drops the event emitted, reporting ErrQueueFull
+	OverflowError
+)
+
+var (
+	// ErrQueueFull is reported for the subscribers skipped because their
+	// queue was full, with the OverflowError policy or by TryEmitEvent
+	ErrQueueFull = errors.New("Event queue full")
+	// ErrEventDropped is reported for the subscribers skipped because their
+	// queue was full, with the drop policies
+	ErrEventDropped = errors.New("Event dropped")
+)
+
+// QueueOption configures the event queues of a module
+type QueueOption func(*queueConfig)
+
+// queueConfig holds the settings of the event queues of a module
+type queueConfig struct {
+	capacity int
+	overflow OverflowPolicy
+}
+
+func newQueueConfig(options []QueueOption) queueConfig {
+	config := queueConfig{capacity: DefaultQueueCapacity, overflow: OverflowBlock}
+	for _, option := range options {
+		option(&config)
+	}
+	return config
+}
+
+// WithQueueCapacity sets how many events each subscription of the module can
+// queue while its callback is busy. With a capacity of zero, events are only
+// queued when the callback is ready to receive them.
+func WithQueueCapacity(capacity int) QueueOption {
+	return func(config *queueConfig) {
+		config.capacity = max(capacity, 0)
+	}
+}
+
+// WithOverflowPolicy sets what happens to the events emitted while a queue of
+// the module is full
+func WithOverflowPolicy(policy OverflowPolicy) QueueOption {
+	return func(config *queueConfig) {
+		config.overflow = policy
+	}
+}
+
+// SkippedSubscriber is a subscriber an event was not queued for
+type SkippedSubscriber struct {
+	Module    string
+	EventType string
+	// Err is why the event was not queued: ErrQueueFull, ErrEventDropped,
+	// ErrClosed or the error of the context of the emitter
+	Err error
+}
+
+// SkippedSubscribersError is returned by EmitEventContext when the event was
+// not queued for some of the subscribers
+type SkippedSubscribersError struct {
+	Subscribers []SkippedSubscriber
+}
+
+func (e *SkippedSubscribersError) Error() string {
+	skipped := make([]string, len(e.Subscribers))
+	for i, s := range e.Subscribers {
+		skipped[i] = fmt.Sprintf("%s (%s)", s.Module, s.Err.Error())
+	}
+	return fmt.Sprintf("%d subscribers skipped: %s", len(e.Subscribers), strings.Join(skipped, ", "))
+}
+
+// Unwrap returns the errors of the subscribers skipped, so that errors.Is
+// tells whether a subscriber was skipped for a reason
+func (e *SkippedSubscribersError) Unwrap() []error {
+	errs := make([]error, len(e.Subscribers))
+	for i, s := range e.Subscribers {
+		errs[i] = s.Err
+	}
+	return errs
+}
+
+// errUnsubscribed is returned when queuing an event for a stopped subscription
+var errUnsubscribed = errors.New("Subscription stopped")
+
+// offer queues event if the queue has room
+func (s *subscription) offer(event gei.CacheEvent) bool {
+	select {
+	case s.channel <- event:
+		return true
+	default:
+		return false
+	}
+}
+
+// overflow queues event in a full queue, according to the overflow policy.
+// Without wait, the OverflowBlock policy fails with ErrQueueFull.
+func (s *subscription) overflow(ctx context.Context, event gei.CacheEvent, wait bool) error {
+	switch s.policy {
+	case OverflowDropNewest:
+		return ErrEventDropped
+	case OverflowError:
+		return ErrQueueFull
+	case OverflowDropOldest:
+		if cap(s.channel) == 0 {
+			return ErrEventDropped
+		}
+		// Other emitters may fill the room made, until the event is queued
+		for !s.offer(event) {
+			select {
+			case <-s.channel:
+			default:
+			}
+		}
+		return nil
+	default:
+		if !wait {
+			return ErrQueueFull
+		}
+		select {
+		case s.channel <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.subscriber.Done():
+			return errUnsubscribed
+		}
+	}
+}
+
+// drain calls undelivered with the events left in the queue, once nothing can
+// be queued anymore
+func (s *subscription) drain(undelivered func(event gei.CacheEvent)) {
+	for {
+		select {
+		case event, ok := <-s.channel:
+			if !ok {
+				return
+			}
+			undelivered(event)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	interfaces "github.com/josemiguelmelo/gocacheable/events/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+type numberedEvent struct {
+	n int
+}
+
+func (e *numberedEvent) Invoke() error {
+	return nil
+}
+
+// blockedModule registers a module whose callback blocks until release is
+// closed, sending the number of every event received to delivered
+type blockedModule struct {
+	started   chan struct{}
+	release   chan struct{}
+	delivered chan int
+}
+
+func newBlockedModule(t *testing.T, manager CacheEventsManager, name string, options ...QueueOption) *blockedModule {
+	m := &blockedModule{
+		started:   make(chan struct{}, 1),
+		release:   make(chan struct{}),
+		delivered: make(chan int, 10),
+	}
+	_, err := manager.RegisterModule(name, options...)
+	assert.Nil(t, err)
+	_, err = manager.SubscribeEvent(name, "event", func(event interfaces.CacheEvent) {
+		select {
+		case m.started <- struct{}{}:
+		default:
+		}
+		<-m.release
+		m.delivered <- event.(*numberedEvent).n
+	})
+	assert.Nil(t, err)
+	return m
+}
+
+// received releases the callback and returns the numbers of the events
+// delivered, once the manager is closed
+func (m *blockedModule) received(t *testing.T, manager CacheEventsManager) []int {
+	close(m.release)
+	assert.Nil(t, manager.Close(context.Background()))
+	close(m.delivered)
+
+	numbers := []int{}
+	for n := range m.delivered {
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    OverflowPolicy
+		err       error
+		delivered []int
+	}{
+		{"Block", OverflowBlock, context.DeadlineExceeded, []int{1, 2}},
+		{"DropNewest", OverflowDropNewest, ErrEventDropped, []int{1, 2}},
+		{"DropOldest", OverflowDropOldest, nil, []int{1, 3}},
+		{"Error", OverflowError, ErrQueueFull, []int{1, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager := NewCacheEventsManager()
+			assert.Nil(t, manager.RegisterEvent("event"))
+			module := newBlockedModule(t, manager, "module", WithQueueCapacity(1), WithOverflowPolicy(test.policy))
+
+			// The callback holds the first event, the second one fills the queue
+			assert.Nil(t, manager.EmitEventContext(context.Background(), "event", &numberedEvent{1}))
+			<-module.started
+			assert.Nil(t, manager.EmitEventContext(context.Background(), "event", &numberedEvent{2}))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			err := manager.EmitEventContext(ctx, "event", &numberedEvent{3})
+			if test.err == nil {
+				assert.Nil(t, err)
+			} else {
+				var skipped *SkippedSubscribersError
+				assert.True(t, errors.As(err, &skipped))
+				assert.True(t, errors.Is(err, test.err))
+				assert.Equal(t, []SkippedSubscriber{{Module: "module", EventType: "event", Err: test.err}}, skipped.Subscribers)
+			}
+
+			assert.Equal(t, test.delivered, module.received(t, manager))
+		})
+	}
+}
+
+func TestFullQueueDoesNotDelayOtherSubscribers(t *testing.T) {
+	manager := NewCacheEventsManager()
+	assert.Nil(t, manager.RegisterEvent("event"))
+	slow := newBlockedModule(t, manager, "a_slow", WithQueueCapacity(0))
+	fast := newBlockedModule(t, manager, "b_fast")
+	close(fast.release)
+
+	assert.Nil(t, manager.EmitEventContext(context.Background(), "event", &numberedEvent{1}))
+	<-slow.started
+	assert.Equal(t, 1, <-fast.delivered)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := manager.EmitEventContext(ctx, "event", &numberedEvent{2})
+	assert.Equal(t, &SkippedSubscribersError{Subscribers: []SkippedSubscriber{{Module: "a_slow", EventType: "event", Err: context.DeadlineExceeded}}}, err)
+	assert.Equal(t, 2, <-fast.delivered)
+	assert.Equal(t, []int{1}, slow.received(t, manager))
+}
+
+func TestTryEmitEventDoesNotWait(t *testing.T) {
+	manager := NewCacheEventsManager()
+	assert.Nil(t, manager.RegisterEvent("event"))
+	module := newBlockedModule(t, manager, "module", WithQueueCapacity(1))
+
+	assert.Nil(t, manager.TryEmitEvent("event", &numberedEvent{1}))
+	<-module.started
+	assert.Nil(t, manager.TryEmitEvent("event", &numberedEvent{2}))
+
+	err := manager.TryEmitEvent("event", &numberedEvent{3})
+	assert.Equal(t, &SkippedSubscribersError{Subscribers: []SkippedSubscriber{{Module: "module", EventType: "event", Err: ErrQueueFull}}}, err)
+	assert.Equal(t, []int{1, 2}, module.received(t, manager))
+}
+
+func TestEmitEventContextErrors(t *testing.T) {
+	manager := NewCacheEventsManager()
+	assert.Nil(t, manager.RegisterEvent("event"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, manager.EmitEventContext(ctx, "event", &numberedEvent{1}))
+	assert.Nil(t, manager.EmitEventContext(context.Background(), "not_subscribed", &numberedEvent{1}))
+
+	assert.Nil(t, manager.Close(context.Background()))
+	assert.Equal(t, ErrClosed, manager.EmitEventContext(context.Background(), "event", &numberedEvent{1}))
+}
+
+func TestCloseDeliversQueuedEvents(t *testing.T) {
+	manager := NewCacheEventsManager()
+	assert.Nil(t, manager.RegisterEvent("event"))
+	module := newBlockedModule(t, manager, "module")
+
+	for n := 1; n <= 5; n++ {
+		assert.Nil(t, manager.EmitEventContext(context.Background(), "event", &numberedEvent{n}))
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, module.received(t, manager))
+}
+
+func TestCloseReportsQueuedEvents(t *testing.T) {
+	manager := NewCacheEventsManager()
+	assert.Nil(t, manager.RegisterEvent("event"))
+	module := newBlockedModule(t, manager, "module", WithQueueCapacity(2), WithOverflowPolicy(OverflowBlock))
+
+	events := []*numberedEvent{{1}, {2}, {3}, {4}}
+	for _, event := range events[:3] {
+		assert.Nil(t, manager.EmitEventContext(context.Background(), "event", event))
+	}
+	<-module.started
+	// The fourth event waits for room in the queue
+	skipped := make(chan error)
+	go func() {
+		skipped <- manager.EmitEventContext(context.Background(), "event", events[3])
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := manager.Close(ctx)
+	assert.Equal(t, &SkippedSubscribersError{Subscribers: []SkippedSubscriber{{Module: "module", EventType: "event", Err: ErrClosed}}}, <-skipped)
+
+	var undelivered *UndeliveredEventsError
+	assert.True(t, errors.As(err, &undelivered))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.ElementsMatch(t, []UndeliveredEvent{
+		{Module: "module", EventType: "event", Event: events[1]},
+		{Module: "module", EventType: "event", Event: events[2]},
+		{Module: "module", EventType: "event", Event: events[3]},
+	}, undelivered.Events)
+
+	close(module.release)
+	assert.Equal(t, 1, <-module.delivered)
+}
@@ -1,34 +1,67 @@
 package queue
 
 import (
-	"log"
+	"sync"
 
 	gei "github.com/josemiguelmelo/gocacheable/events/interfaces"
 )
 
-// CacheEventSubscriber to handle events that will affect cache
+// CacheEventSubscriber to handle events that will affect cache. Copies of a
+// subscriber control the same listener.
 type CacheEventSubscriber struct {
 	channel  *chan gei.CacheEvent
 	callback func(gei.CacheEvent)
+	// done is closed by Unsubscribe
+	done        chan struct{}
+	unsubscribe func()
+	// stopped is closed once the listener returned
+	stopped chan struct{}
 }
 
 // Subscribe subscribes to a channel with a callback
 func (subs *CacheEventSubscriber) Subscribe(channel *chan gei.CacheEvent, callback func(gei.CacheEvent)) {
 	subs.channel = channel
 	subs.callback = callback
+	done := make(chan struct{})
+	subs.done = done
+	subs.unsubscribe = sync.OnceFunc(func() { close(done) })
+	subs.stopped = make(chan struct{})
 	go subs.listenForChannel()
 }
 
+// Unsubscribe stops listening for events. A callback in progress is not
+// interrupted, Stopped tells when it returned.
+func (subs *CacheEventSubscriber) Unsubscribe() {
+	if subs.unsubscribe != nil {
+		subs.unsubscribe()
+	}
+}
+
+// Done returns a channel closed once Unsubscribe was called
+func (subs *CacheEventSubscriber) Done() <-chan struct{} {
+	return subs.done
+}
+
+// Stopped returns a channel closed once the subscriber stopped listening
+func (subs *CacheEventSubscriber) Stopped() <-chan struct{} {
+	return subs.stopped
+}
+
+// listenForChannel calls the callback with the events received, until
+// unsubscribed or the channel is closed
 func (subs *CacheEventSubscriber) listenForChannel() {
+	defer close(subs.stopped)
+
 	for {
-		event, ok := <-(*subs.channel)
-		// if channel was closed
-		if !ok {
-			log.Print(subs.channel)
-			log.Println(" channel was closed")
+		select {
+		case <-subs.done:
 			return
+		case event, ok := <-(*subs.channel):
+			// if channel was closed
+			if !ok {
+				return
+			}
+			subs.callback(event)
 		}
-
-		subs.callback(event)
 	}
 }
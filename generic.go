@@ -0,0 +1,114 @@
+package gocacheable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	gcCacheModule "github.com/josemiguelmelo/gocacheable/cachemodule"
+)
+
+// TypeMismatchError is returned when the value loaded for a key is not of the
+// type requested, as when callers of different types share a load through
+// single flight
+type TypeMismatchError struct {
+	Key      string
+	Expected reflect.Type
+	Actual   reflect.Type
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("Value loaded for key %s is %s, not %s", e.Key, e.Actual, e.Expected)
+}
+
+// Get returns the value cached under key in module moduleID decoded as T
+func Get[T any](cs *CacheableManager, moduleID string, key string) (T, error) {
+	return GetContext[T](context.Background(), cs, moduleID, key)
+}
+
+// GetContext returns the value cached under key in module moduleID decoded as T
+func GetContext[T any](ctx context.Context, cs *CacheableManager, moduleID string, key string) (T, error) {
+	var value T
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return value, err
+	}
+
+	err = module.GetContext(ctx, key, &value)
+	return value, err
+}
+
+// KeyResult is the result of a key read by GetMany
+type KeyResult[T any] struct {
+	Key   string
+	Value T
+	// Found is true if a usable entry was found for the key
+	Found bool
+	// Stale is true if the soft time to live of the value has elapsed
+	Stale bool
+	// Err is the loader error replayed from a negative cache entry, or the
+	// reason the value found could not be decoded
+	Err error
+}
+
+// GetMany returns the values cached under keys in module moduleID decoded as T,
+// in the same order
+func GetMany[T any](cs *CacheableManager, moduleID string, keys []string) ([]KeyResult[T], error) {
+	return GetManyContext[T](context.Background(), cs, moduleID, keys)
+}
+
+// GetManyContext returns the values cached under keys in module moduleID
+// decoded as T, in the same order
+func GetManyContext[T any](ctx context.Context, cs *CacheableManager, moduleID string, keys []string) ([]KeyResult[T], error) {
+	results, err := cs.GetManyContext(ctx, moduleID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]KeyResult[T], len(results))
+	for i, result := range results {
+		values[i] = KeyResult[T]{Key: result.Key, Found: result.Found, Stale: result.Stale, Err: result.Err}
+		if result.Found && result.Err == nil {
+			values[i].Err = result.Decode(&values[i].Value)
+		}
+	}
+	return values, nil
+}
+
+// Cacheable returns the value cached under key in module moduleID. On a miss it
+// calls f, caches its result for timeToLive and returns it as is. A zero
+// timeToLive caches the result without expiration, and a negative one is
+// rejected with cachemodule.ErrNegativeTimeToLive.
+func Cacheable[T any](cs *CacheableManager, moduleID string, key string, f func() (T, error), timeToLive time.Duration, options ...gcCacheModule.EntryOption) (T, error) {
+	return CacheableContext(context.Background(), cs, moduleID, key, withoutContext(f), timeToLive, options...)
+}
+
+// CacheableContext is the context aware version of Cacheable. ctx is passed to f
+// and bounds both the cache storage access and the call to f.
+func CacheableContext[T any](ctx context.Context, cs *CacheableManager, moduleID string, key string, f func(context.Context) (T, error), timeToLive time.Duration, options ...gcCacheModule.EntryOption) (T, error) {
+	var value T
+	if timeToLive < 0 {
+		return value, gcCacheModule.ErrNegativeTimeToLive
+	}
+	module, err := cs.FindModule(moduleID)
+	if err != nil {
+		return value, err
+	}
+
+	if module.IsCacheStorageCreated() {
+		return value, errors.New("Cache storage not created")
+	}
+
+	// Check on cache and return if found, refreshing it in background if stale
+	result, err := module.LookupContext(ctx, key, &value)
+	if err == nil {
+		if result.Stale {
+			module.Refresh(ctx, key, anyLoader(f), timeToLive, options...)
+		}
+		return value, result.Err
+	}
+
+	return load(ctx, module, key, f, timeToLive, options...)
+}
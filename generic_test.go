@@ -0,0 +1,370 @@
+package gocacheable
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gcCacheModule "github.com/josemiguelmelo/gocacheable/cachemodule"
+	gcCodec "github.com/josemiguelmelo/gocacheable/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+const genericModuleName = "generic_module"
+
+func createGenericManager(t *testing.T) *CacheableManager {
+	manager := NewCacheableManager(identifier)
+	err := manager.AddModule(genericModuleName, newTestProvider())
+	assert.Nil(t, err)
+	return &manager
+}
+
+type genericObj struct {
+	St    string
+	Big   int64
+	Items []string
+}
+
+func TestGenericCacheable(t *testing.T) {
+	manager := createGenericManager(t)
+
+	value, err := Cacheable(manager, genericModuleName, "generic", func() (genericObj, error) {
+		return genericObj{St: "yes", Big: 1<<62 + 1, Items: []string{"a"}}, nil
+	}, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, "yes", value.St)
+
+	// Cached path must decode into T without losing precision
+	value, err = Cacheable(manager, genericModuleName, "generic", func() (genericObj, error) {
+		panic(errors.New("Should not be here"))
+	}, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, genericObj{St: "yes", Big: 1<<62 + 1, Items: []string{"a"}}, value)
+
+	_, err = Cacheable(manager, "not_found", "generic", func() (genericObj, error) {
+		return genericObj{}, nil
+	}, time.Minute)
+	assert.NotNil(t, err)
+	assert.Equal(t, "Module not found", err.Error())
+}
+
+func TestGenericCacheableLoaderError(t *testing.T) {
+	manager := createGenericManager(t)
+
+	_, err := Cacheable(manager, genericModuleName, "generic_error", func() (int, error) {
+		return 0, errors.New("loader failed")
+	}, time.Minute)
+	assert.NotNil(t, err)
+	assert.Equal(t, "loader failed", err.Error())
+
+	_, err = Get[int](manager, genericModuleName, "generic_error")
+	assert.NotNil(t, err)
+}
+
+func TestGenericGet(t *testing.T) {
+	manager := createGenericManager(t)
+
+	_, err := Get[string](manager, genericModuleName, "generic_get")
+	assert.NotNil(t, err)
+
+	_, err = Cacheable(manager, genericModuleName, "generic_get", func() (string, error) {
+		return "value", nil
+	}, time.Minute)
+	assert.Nil(t, err)
+
+	value, err := Get[string](manager, genericModuleName, "generic_get")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", value)
+
+	_, err = Get[string](manager, "not_found", "generic_get")
+	assert.NotNil(t, err)
+}
+
+func TestGenericCacheableContextCanceled(t *testing.T) {
+	manager := createGenericManager(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := CacheableContext(ctx, manager, genericModuleName, "generic_canceled", func(context.Context) (int, error) {
+		panic(errors.New("Should not be here"))
+	}, time.Minute)
+	assert.Equal(t, context.Canceled, err)
+
+	_, err = GetContext[int](ctx, manager, genericModuleName, "generic_canceled")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestGenericCacheableContextDeadline(t *testing.T) {
+	manager := createGenericManager(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Loader ignores ctx and blocks, the call must still return at the deadline
+	release := make(chan struct{})
+	defer close(release)
+	_, err := CacheableContext(ctx, manager, genericModuleName, "generic_deadline", func(context.Context) (int, error) {
+		<-release
+		return 1, nil
+	}, time.Minute)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	_, err = Get[int](manager, genericModuleName, "generic_deadline")
+	assert.NotNil(t, err)
+}
+
+func concurrentLoads(manager *CacheableManager, moduleID string, callers int, loader func() (int, error)) ([]int, []error) {
+	var wg sync.WaitGroup
+	values := make([]int, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], errs[i] = Cacheable(manager, moduleID, "stampede", loader, time.Minute)
+		}(i)
+	}
+	wg.Wait()
+	return values, errs
+}
+
+func TestGenericCacheableSingleFlight(t *testing.T) {
+	manager := createGenericManager(t)
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 42, nil
+	}
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+
+	values, errs := concurrentLoads(manager, genericModuleName, 20, loader)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for i := range values {
+		assert.Nil(t, errs[i])
+		assert.Equal(t, 42, values[i])
+	}
+}
+
+func TestGenericCacheableSingleFlightSharesError(t *testing.T) {
+	manager := createGenericManager(t)
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return 0, errors.New("loader failed")
+	}
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+
+	_, errs := concurrentLoads(manager, genericModuleName, 10, loader)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, err := range errs {
+		assert.NotNil(t, err)
+		assert.Equal(t, "loader failed", err.Error())
+	}
+}
+
+func TestGenericCacheableWithoutSingleFlight(t *testing.T) {
+	manager := NewCacheableManager(identifier)
+	err := manager.AddModule(genericModuleName, newTestProvider(), gcCacheModule.WithSingleFlight(false))
+	assert.Nil(t, err)
+
+	var calls int32
+	started := make(chan struct{}, 5)
+	release := make(chan struct{})
+	loader := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		started <- struct{}{}
+		<-release
+		return 42, nil
+	}
+	go func() {
+		// Only release once every caller is running its own loader
+		for i := 0; i < 5; i++ {
+			<-started
+		}
+		close(release)
+	}()
+
+	concurrentLoads(&manager, genericModuleName, 5, loader)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&calls))
+}
+
+func TestGenericCacheableStaleWhileRevalidate(t *testing.T) {
+	manager := createGenericManager(t)
+	defer manager.Close()
+
+	var calls int32
+	refreshed := make(chan struct{}, 1)
+	loader := func() (int32, error) {
+		call := atomic.AddInt32(&calls, 1)
+		if call > 1 {
+			refreshed <- struct{}{}
+		}
+		return call, nil
+	}
+
+	value, err := Cacheable(manager, genericModuleName, "swr", loader, time.Minute, gcCacheModule.WithSoftTTL(50*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), value)
+
+	// Fresh value, loader is not called
+	value, err = Cacheable(manager, genericModuleName, "swr", loader, time.Minute, gcCacheModule.WithSoftTTL(50*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), value)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Stale value is returned right away and refreshed in background
+	time.Sleep(100 * time.Millisecond)
+	value, err = Cacheable(manager, genericModuleName, "swr", loader, time.Minute, gcCacheModule.WithSoftTTL(50*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), value)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale value was not refreshed")
+	}
+
+	// Wait for the refreshed value to be stored
+	for {
+		value, err = Get[int32](manager, genericModuleName, "swr")
+		if err == nil && value == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGenericCacheableHardTTL(t *testing.T) {
+	manager := createGenericManager(t)
+	defer manager.Close()
+
+	var calls int32
+	loader := func() (int32, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+
+	_, err := Cacheable(manager, genericModuleName, "hard_ttl", loader, 50*time.Millisecond, gcCacheModule.WithSoftTTL(10*time.Millisecond))
+	assert.Nil(t, err)
+
+	// Once the hard ttl elapsed the value is reloaded synchronously
+	time.Sleep(100 * time.Millisecond)
+	value, err := Cacheable(manager, genericModuleName, "hard_ttl", loader, 50*time.Millisecond, gcCacheModule.WithSoftTTL(10*time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), value)
+}
+
+func TestGenericCacheableNegativeCaching(t *testing.T) {
+	errNotFound := errors.New("not found")
+	manager := NewCacheableManager(identifier)
+	err := manager.AddModule(genericModuleName, newTestProvider(), gcCacheModule.WithNegativeCaching(gcCacheModule.NegativeCaching{
+		TimeToLive: time.Minute,
+		Errors:     []error{errNotFound},
+	}))
+	assert.Nil(t, err)
+
+	var calls int32
+	loader := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "", errNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = Cacheable(&manager, genericModuleName, "negative", loader, time.Minute)
+		assert.Equal(t, errNotFound, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, err = Get[string](&manager, genericModuleName, "negative")
+	assert.Equal(t, errNotFound, err)
+}
+
+func TestGenericCacheableCodecs(t *testing.T) {
+	codecs := []gcCodec.Codec{gcCodec.JSONCodec{}, gcCodec.GobCodec{}, gcCodec.MsgPackCodec{}}
+	for _, codec := range codecs {
+		manager := NewCacheableManager(identifier)
+		err := manager.AddModule(genericModuleName, newTestProvider(), gcCacheModule.WithCodec(codec))
+		assert.Nil(t, err)
+
+		expected := genericObj{St: "yes", Big: 1<<62 + 1, Items: []string{"a", "b"}}
+		_, err = Cacheable(&manager, genericModuleName, "codec", func() (genericObj, error) {
+			return expected, nil
+		}, time.Minute)
+		assert.Nil(t, err)
+
+		value, err := Get[genericObj](&manager, genericModuleName, "codec")
+		assert.Nil(t, err)
+		assert.Equal(t, expected, value)
+	}
+
+	manager := NewCacheableManager(identifier)
+	err := manager.AddModule(genericModuleName, newTestProvider(), gcCacheModule.WithCodec(gcCodec.RawCodec{}))
+	assert.Nil(t, err)
+	_, err = Cacheable(&manager, genericModuleName, "raw", func() ([]byte, error) {
+		return []byte("page"), nil
+	}, time.Minute)
+	assert.Nil(t, err)
+	value, err := Get[[]byte](&manager, genericModuleName, "raw")
+	assert.Nil(t, err)
+	assert.Equal(t, "page", string(value))
+}
+
+func TestGenericGetMany(t *testing.T) {
+	manager := createGenericManager(t)
+
+	err := manager.SetMany(genericModuleName, map[string]interface{}{
+		"first":  genericObj{St: "first", Big: 1<<62 + 1},
+		"second": genericObj{St: "second"},
+	}, time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, manager.Set(genericModuleName, "invalid", "not an object", time.Minute))
+
+	results, err := GetMany[genericObj](manager, genericModuleName, []string{"second", "missing", "first", "invalid"})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(results))
+	assert.Equal(t, KeyResult[genericObj]{Key: "second", Value: genericObj{St: "second"}, Found: true}, results[0])
+	assert.Equal(t, KeyResult[genericObj]{Key: "missing"}, results[1])
+	assert.Equal(t, genericObj{St: "first", Big: 1<<62 + 1}, results[2].Value)
+	assert.True(t, results[3].Found)
+	assert.NotNil(t, results[3].Err)
+
+	_, err = GetMany[genericObj](manager, "not_found", []string{"first"})
+	assert.NotNil(t, err)
+}
+
+func TestGenericCacheableTypeMismatch(t *testing.T) {
+	manager := createGenericManager(t)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		Cacheable(manager, genericModuleName, "mismatch", func() (string, error) {
+			close(started)
+			<-release
+			return "value", nil
+		}, time.Minute)
+	}()
+	<-started
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+
+	// Shares the string load in flight instead of calling its own loader
+	value, err := Cacheable(manager, genericModuleName, "mismatch", func() (int, error) {
+		return 42, nil
+	}, time.Minute)
+	assert.Equal(t, 0, value)
+	var mismatch *TypeMismatchError
+	assert.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, "mismatch", mismatch.Key)
+	assert.Equal(t, reflect.TypeOf(0), mismatch.Expected)
+	assert.Equal(t, reflect.TypeOf(""), mismatch.Actual)
+}
@@ -1,11 +1,84 @@
 package interfaces
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Get for keys not cached or expired, so that a
+// missing key can be told apart from a failure of the storage
+var ErrNotFound = errors.New("Entry not found")
+
+// ErrNamespaceSet is returned by SetNamespace when the provider already has
+// another namespace, usually because it is used by another module
+var ErrNamespaceSet = errors.New("Provider already has another namespace")
+
+// ErrTagsNotSupported is returned by the tag methods of providers wrapping
+// other providers, such as a tiered one, when none of them keeps tags
+var ErrTagsNotSupported = errors.New("Provider does not support tags")
+
 // CacheProviderInterface interface to implement new cache provider
 type CacheProviderInterface interface {
 	Init() error
 	Set(key string, value []byte) error
+	// SetWithTTL stores a value that the storage expires after ttl. A ttl
+	// lower or equal to zero stores the value without expiration.
+	SetWithTTL(key string, value []byte, ttl time.Duration) error
 	Get(key string) ([]byte, error)
 	Delete(key string) error
 	HasKey(key string) bool
 	Reset() error
+
+	// Context aware variants. Implementations must stop waiting on the
+	// underlying storage as soon as ctx is done and return ctx.Err().
+	InitContext(ctx context.Context) error
+	SetContext(ctx context.Context, key string, value []byte) error
+	SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	GetContext(ctx context.Context, key string) ([]byte, error)
+	DeleteContext(ctx context.Context, key string) error
+	HasKeyContext(ctx context.Context, key string) bool
+	ResetContext(ctx context.Context) error
+}
+
+// NamespacedCacheProviderInterface is implemented by providers able to scope
+// their keys to a namespace, so that several modules or services can share
+// the same storage. Reset must then only remove the keys of the namespace.
+type NamespacedCacheProviderInterface interface {
+	// SetNamespace sets the prefix of every key. It is called before Init,
+	// and returns ErrNamespaceSet if the provider already has another
+	// namespace.
+	SetNamespace(namespace string) error
+}
+
+// BatchCacheProviderInterface is implemented by providers able to read and
+// write several keys in a single round trip. Modules fall back to one call per
+// key for providers not implementing it.
+type BatchCacheProviderInterface interface {
+	// GetMany returns the values of the keys found. Missing keys are not an error.
+	GetMany(keys []string) (map[string][]byte, error)
+	// SetMany stores values, expiring after ttl. A ttl lower or equal to zero
+	// stores the values without expiration.
+	SetMany(values map[string][]byte, ttl time.Duration) error
+	// DeleteMany removes keys. Missing keys are not an error.
+	DeleteMany(keys []string) error
+
+	GetManyContext(ctx context.Context, keys []string) (map[string][]byte, error)
+	SetManyContext(ctx context.Context, values map[string][]byte, ttl time.Duration) error
+	DeleteManyContext(ctx context.Context, keys []string) error
+}
+
+// TaggedCacheProviderInterface is implemented by providers able to keep the
+// index of the keys carrying a tag in the storage, shared by every instance
+// using it. Modules keep the index in memory for providers not implementing
+// it, or returning ErrTagsNotSupported.
+type TaggedCacheProviderInterface interface {
+	// AddTags adds key to the index of every tag. An index is kept at least
+	// as long as ttl, or without expiration if ttl is lower or equal to zero.
+	AddTags(key string, tags []string, ttl time.Duration) error
+	// PopTag removes the index of tag and returns the keys it held
+	PopTag(tag string) ([]string, error)
+
+	AddTagsContext(ctx context.Context, key string, tags []string, ttl time.Duration) error
+	PopTagContext(ctx context.Context, tag string) ([]string, error)
 }
@@ -1,9 +1,11 @@
 package interfaces
 
 import (
+	"context"
 	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -23,6 +25,10 @@ func (cacheableImplementation *CacheableImplementation) Set(key string, value []
 	return errors.New(errorMsg)
 }
 
+func (cacheableImplementation *CacheableImplementation) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return cacheableImplementation.Set(key, value)
+}
+
 func (cacheableImplementation *CacheableImplementation) Get(key string) ([]byte, error) {
 	return []byte(""), nil
 }
@@ -39,6 +45,34 @@ func (cacheableImplementation *CacheableImplementation) HasKey(key string) bool
 	return true
 }
 
+func (cacheableImplementation *CacheableImplementation) InitContext(ctx context.Context) error {
+	return cacheableImplementation.Init()
+}
+
+func (cacheableImplementation *CacheableImplementation) SetContext(ctx context.Context, key string, value []byte) error {
+	return cacheableImplementation.Set(key, value)
+}
+
+func (cacheableImplementation *CacheableImplementation) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return cacheableImplementation.SetWithTTL(key, value, ttl)
+}
+
+func (cacheableImplementation *CacheableImplementation) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return cacheableImplementation.Get(key)
+}
+
+func (cacheableImplementation *CacheableImplementation) DeleteContext(ctx context.Context, key string) error {
+	return cacheableImplementation.Delete(key)
+}
+
+func (cacheableImplementation *CacheableImplementation) ResetContext(ctx context.Context) error {
+	return cacheableImplementation.Reset()
+}
+
+func (cacheableImplementation *CacheableImplementation) HasKeyContext(ctx context.Context, key string) bool {
+	return cacheableImplementation.HasKey(key)
+}
+
 func setup() {}
 
 func TestMain(m *testing.M) {
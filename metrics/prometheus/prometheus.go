@@ -0,0 +1,147 @@
+package prometheus
+
+import (
+	"net/http"
+	"sync"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	gocacheable "github.com/josemiguelmelo/gocacheable"
+	gcStats "github.com/josemiguelmelo/gocacheable/stats"
+)
+
+// Namespace prefixes the name of every metric
+const Namespace = "gocacheable"
+
+// moduleLabels label the metrics of a module by the identifier of its manager
+// and its name
+var moduleLabels = []string{"manager", "module"}
+
+// Collector exports the statistics of the modules of cacheable managers. The
+// statistics are read when metrics are collected, so modules added to a
+// manager afterwards are exported as well.
+type Collector struct {
+	mu       sync.Mutex
+	managers []*gocacheable.CacheableManager
+
+	hits            *prom.Desc
+	misses          *prom.Desc
+	hitRatio        *prom.Desc
+	loaderCalls     *prom.Desc
+	loaderErrors    *prom.Desc
+	loadersInFlight *prom.Desc
+	loaderDuration  *prom.Desc
+	sets            *prom.Desc
+	deletes         *prom.Desc
+	providerErrors  *prom.Desc
+	providerLatency *prom.Desc
+	valueSize       *prom.Desc
+}
+
+// NewCollector returns a collector exporting the statistics of the modules of
+// managers
+func NewCollector(managers ...*gocacheable.CacheableManager) *Collector {
+	desc := func(name string, help string, labels ...string) *prom.Desc {
+		return prom.NewDesc(prom.BuildFQName(Namespace, "", name), help, append(moduleLabels, labels...), nil)
+	}
+
+	return &Collector{
+		managers:        append([]*gocacheable.CacheableManager{}, managers...),
+		hits:            desc("hits_total", "Reads finding a usable entry."),
+		misses:          desc("misses_total", "Reads finding no usable entry."),
+		hitRatio:        desc("hit_ratio", "Share of the reads finding a usable entry."),
+		loaderCalls:     desc("loader_calls_total", "Loader calls."),
+		loaderErrors:    desc("loader_errors_total", "Loader calls returning an error."),
+		loadersInFlight: desc("loaders_in_flight", "Loader calls in progress."),
+		loaderDuration:  desc("loader_duration_seconds", "Latency of the loader calls."),
+		sets:            desc("sets_total", "Entries written."),
+		deletes:         desc("deletes_total", "Keys removed."),
+		providerErrors:  desc("provider_errors_total", "Provider operations returning an error."),
+		providerLatency: desc("provider_operation_duration_seconds", "Latency of the provider operations.", "operation"),
+		valueSize:       desc("value_size_bytes", "Size of the values written to the provider."),
+	}
+}
+
+// Add exports the statistics of the modules of manager as well
+func (c *Collector) Add(manager *gocacheable.CacheableManager) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.managers = append(c.managers, manager)
+}
+
+// Describe sends the descriptors of the metrics of the collector
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	for _, desc := range []*prom.Desc{
+		c.hits, c.misses, c.hitRatio,
+		c.loaderCalls, c.loaderErrors, c.loadersInFlight, c.loaderDuration,
+		c.sets, c.deletes, c.providerErrors, c.providerLatency, c.valueSize,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect sends the metrics of every module
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	c.mu.Lock()
+	managers := append([]*gocacheable.CacheableManager{}, c.managers...)
+	c.mu.Unlock()
+
+	for _, manager := range managers {
+		for _, module := range manager.Modules() {
+			c.collectModule(ch, module.Stats(), manager.Identifier, module.Name)
+		}
+	}
+}
+
+func (c *Collector) collectModule(ch chan<- prom.Metric, s gcStats.Stats, labels ...string) {
+	counter := func(desc *prom.Desc, value uint64) {
+		ch <- prom.MustNewConstMetric(desc, prom.CounterValue, float64(value), labels...)
+	}
+	counter(c.hits, s.Hits)
+	counter(c.misses, s.Misses)
+	counter(c.loaderCalls, s.LoaderCalls)
+	counter(c.loaderErrors, s.LoaderErrors)
+	counter(c.sets, s.Sets)
+	counter(c.deletes, s.Deletes)
+	counter(c.providerErrors, s.ProviderErrors)
+
+	ch <- prom.MustNewConstMetric(c.hitRatio, prom.GaugeValue, s.HitRatio(), labels...)
+	ch <- prom.MustNewConstMetric(c.loadersInFlight, prom.GaugeValue, float64(s.LoadersInFlight), labels...)
+
+	ch <- latencyHistogram(c.loaderDuration, s.LoaderLatency, labels...)
+	for op, latency := range s.ProviderLatency {
+		ch <- latencyHistogram(c.providerLatency, latency, append(labels, op.String())...)
+	}
+
+	buckets := make(map[float64]uint64, len(s.ValueSizes.Buckets))
+	for _, b := range s.ValueSizes.Buckets {
+		buckets[float64(b.UpperBound)] = b.Count
+	}
+	ch <- prom.MustNewConstHistogram(c.valueSize, s.ValueSizes.Count, float64(s.ValueSizes.Sum), buckets, labels...)
+}
+
+// latencyHistogram returns a histogram metric of latencies, in seconds
+func latencyHistogram(desc *prom.Desc, s gcStats.HistogramSnapshot, labels ...string) prom.Metric {
+	buckets := make(map[float64]uint64, len(s.Buckets))
+	for _, b := range s.Buckets {
+		buckets[b.UpperBound.Seconds()] = b.Count
+	}
+	return prom.MustNewConstHistogram(desc, s.Count, s.Sum.Seconds(), buckets, labels...)
+}
+
+// Register registers a collector of the statistics of the modules of managers
+// with registerer, and returns it
+func Register(registerer prom.Registerer, managers ...*gocacheable.CacheableManager) (*Collector, error) {
+	collector := NewCollector(managers...)
+	if err := registerer.Register(collector); err != nil {
+		return nil, err
+	}
+	return collector, nil
+}
+
+// Handler returns a handler serving the metrics gathered by gatherer in the
+// Prometheus text format, to be scraped
+func Handler(gatherer prom.Gatherer) http.Handler {
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+}
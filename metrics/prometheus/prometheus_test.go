@@ -0,0 +1,128 @@
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	gocacheable "github.com/josemiguelmelo/gocacheable"
+	lruProvider "github.com/josemiguelmelo/gocacheable/providers/lru"
+)
+
+func newManager(t *testing.T, identifier string, modules ...string) *gocacheable.CacheableManager {
+	manager := gocacheable.NewCacheableManager(identifier)
+	for _, name := range modules {
+		assert.Nil(t, manager.AddModule(name, lruProvider.NewLRUProvider(1000, 0)))
+	}
+	return &manager
+}
+
+func scrape(t *testing.T, registry *prom.Registry) string {
+	server := httptest.NewServer(Handler(registry))
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, response.Header.Get("Content-Type"), "text/plain")
+
+	body, err := io.ReadAll(response.Body)
+	assert.Nil(t, err)
+	return string(body)
+}
+
+func TestCollector(t *testing.T) {
+	manager := newManager(t, "manager", "Posts")
+	var out string
+	for i := 0; i < 4; i++ {
+		err := manager.Cacheable("posts", "posts:42", func() (interface{}, error) {
+			return "posts", nil
+		}, &out, time.Minute)
+		assert.Nil(t, err)
+	}
+
+	registry := prom.NewRegistry()
+	_, err := Register(registry, manager)
+	assert.Nil(t, err)
+
+	metrics := scrape(t, registry)
+	for _, line := range []string{
+		`gocacheable_hits_total{manager="manager",module="Posts"} 3`,
+		`gocacheable_misses_total{manager="manager",module="Posts"} 1`,
+		`gocacheable_hit_ratio{manager="manager",module="Posts"} 0.75`,
+		`gocacheable_loader_calls_total{manager="manager",module="Posts"} 1`,
+		`gocacheable_loader_errors_total{manager="manager",module="Posts"} 0`,
+		`gocacheable_loaders_in_flight{manager="manager",module="Posts"} 0`,
+		`gocacheable_loader_duration_seconds_count{manager="manager",module="Posts"} 1`,
+		`gocacheable_sets_total{manager="manager",module="Posts"} 1`,
+		`gocacheable_deletes_total{manager="manager",module="Posts"} 0`,
+		`gocacheable_provider_errors_total{manager="manager",module="Posts"} 0`,
+		`gocacheable_provider_operation_duration_seconds_count{manager="manager",module="Posts",operation="get"} 4`,
+		`gocacheable_provider_operation_duration_seconds_count{manager="manager",module="Posts",operation="set"} 1`,
+		`gocacheable_value_size_bytes_count{manager="manager",module="Posts"} 1`,
+		`gocacheable_value_size_bytes_bucket{manager="manager",module="Posts",le="64"} 1`,
+	} {
+		assert.Contains(t, metrics, line)
+	}
+}
+
+func TestCollectorAddsManagersAndModules(t *testing.T) {
+	first := newManager(t, "first", "users")
+	registry := prom.NewRegistry()
+	collector, err := Register(registry, first)
+	assert.Nil(t, err)
+
+	// Modules and managers added after registering are exported too
+	assert.Nil(t, first.AddModule("posts", lruProvider.NewLRUProvider(1000, 0)))
+	collector.Add(newManager(t, "second", "users"))
+
+	metrics := scrape(t, registry)
+	assert.Contains(t, metrics, `gocacheable_hits_total{manager="first",module="users"} 0`)
+	assert.Contains(t, metrics, `gocacheable_hits_total{manager="first",module="posts"} 0`)
+	assert.Contains(t, metrics, `gocacheable_hits_total{manager="second",module="users"} 0`)
+
+	_, err = Register(registry, first)
+	assert.NotNil(t, err)
+}
+
+// Run with -race to check that modules can be added while being collected
+func TestCollectWhileAddingModules(t *testing.T) {
+	manager := newManager(t, "manager")
+	registry := prom.NewRegistry()
+	_, err := Register(registry, manager)
+	assert.Nil(t, err)
+
+	const modules = 20
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < modules; i++ {
+			assert.Nil(t, manager.AddModule(fmt.Sprintf("module %d", i), lruProvider.NewLRUProvider(1000, 0)))
+		}
+	}()
+
+	for scraping := true; scraping; {
+		select {
+		case <-done:
+			scraping = false
+		default:
+		}
+		_, err := registry.Gather()
+		assert.Nil(t, err)
+	}
+
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+	for _, family := range families {
+		if family.GetName() == "gocacheable_hits_total" {
+			assert.Equal(t, modules, len(family.GetMetric()))
+		}
+	}
+}
@@ -1,19 +1,49 @@
 package bigcache
 
 import (
+	"context"
+	"encoding/binary"
+	"errors"
 	"time"
 
 	"github.com/allegro/bigcache"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
 )
 
+// expirationHeaderSize is the size of the expiration time prepended to every
+// stored value, as unix nanoseconds. Zero means the value does not expire.
+const expirationHeaderSize = 8
+
+// ErrInvalidEntry is returned when a stored value does not contain the expiration header
+var ErrInvalidEntry = errors.New("Invalid cache entry")
+
 // BigCacheProvider is a storage provider based on bigcache caching system
 type BigCacheProvider struct {
 	cacheStorage *bigcache.BigCache
 	Lifetime     time.Duration
+	// Clock returns the current time used for per-entry expiration, time.Now when nil
+	Clock func() time.Time
+}
+
+func (bigcacheProvider *BigCacheProvider) now() time.Time {
+	if bigcacheProvider.Clock != nil {
+		return bigcacheProvider.Clock()
+	}
+	return time.Now()
 }
 
 // Init initializes bigcache storage
 func (bigcacheProvider *BigCacheProvider) Init() error {
+	return bigcacheProvider.InitContext(context.Background())
+}
+
+// InitContext initializes bigcache storage unless ctx is already done
+func (bigcacheProvider *BigCacheProvider) InitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	configuration := bigcache.Config{
 		Shards:             1024,
 		LifeWindow:         bigcacheProvider.Lifetime * time.Minute,
@@ -29,27 +59,114 @@ func (bigcacheProvider *BigCacheProvider) Init() error {
 
 // Set adds a new value to cache or updates if it already exists
 func (bigcacheProvider *BigCacheProvider) Set(key string, value []byte) error {
-	return bigcacheProvider.cacheStorage.Set(key, value)
+	return bigcacheProvider.SetContext(context.Background(), key, value)
+}
+
+// SetContext adds a new value to cache or updates if it already exists
+func (bigcacheProvider *BigCacheProvider) SetContext(ctx context.Context, key string, value []byte) error {
+	return bigcacheProvider.SetWithTTLContext(ctx, key, value, 0)
+}
+
+// SetWithTTL adds a new value to cache, or updates it, expiring after ttl
+func (bigcacheProvider *BigCacheProvider) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return bigcacheProvider.SetWithTTLContext(context.Background(), key, value, ttl)
+}
+
+// SetWithTTLContext adds a new value to cache, or updates it, expiring after ttl
+func (bigcacheProvider *BigCacheProvider) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = bigcacheProvider.now().Add(ttl).UnixNano()
+	}
+
+	entry := make([]byte, expirationHeaderSize+len(value))
+	binary.BigEndian.PutUint64(entry, uint64(expiresAt))
+	copy(entry[expirationHeaderSize:], value)
+	return bigcacheProvider.cacheStorage.Set(key, entry)
 }
 
 // Get returns a cached value or error if it does not exist
 func (bigcacheProvider *BigCacheProvider) Get(key string) ([]byte, error) {
-	return bigcacheProvider.cacheStorage.Get(key)
-	// Output: Hello, nil
+	return bigcacheProvider.GetContext(context.Background(), key)
+}
+
+// GetContext returns a cached value or error if it does not exist
+func (bigcacheProvider *BigCacheProvider) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	entry, err := bigcacheProvider.cacheStorage.Get(key)
+	if err == bigcache.ErrEntryNotFound {
+		return nil, gcInterfaces.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entry) < expirationHeaderSize {
+		return nil, ErrInvalidEntry
+	}
+
+	expiresAt := int64(binary.BigEndian.Uint64(entry))
+	if expiresAt != 0 && bigcacheProvider.now().UnixNano() >= expiresAt {
+		// Expired entries are removed lazily on read
+		bigcacheProvider.cacheStorage.Delete(key)
+		return nil, gcInterfaces.ErrNotFound
+	}
+	return entry[expirationHeaderSize:], nil
 }
 
 // Delete removes a value from the cache
 func (bigcacheProvider *BigCacheProvider) Delete(key string) error {
-	return bigcacheProvider.cacheStorage.Delete(key)
+	return bigcacheProvider.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext removes a value from the cache. Deleting a missing key is not an error.
+func (bigcacheProvider *BigCacheProvider) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := bigcacheProvider.cacheStorage.Delete(key)
+	if err == bigcache.ErrEntryNotFound {
+		return nil
+	}
+	return err
 }
 
 // Reset empties cache storage
 func (bigcacheProvider *BigCacheProvider) Reset() error {
+	return bigcacheProvider.ResetContext(context.Background())
+}
+
+// ResetContext empties cache storage
+func (bigcacheProvider *BigCacheProvider) ResetContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return bigcacheProvider.cacheStorage.Reset()
 }
 
 // HasKey checks if the key exists
 func (bigcacheProvider *BigCacheProvider) HasKey(key string) bool {
-	_, err := bigcacheProvider.cacheStorage.Get(key)
+	return bigcacheProvider.HasKeyContext(context.Background(), key)
+}
+
+// HasKeyContext checks if the key exists
+func (bigcacheProvider *BigCacheProvider) HasKeyContext(ctx context.Context, key string) bool {
+	_, err := bigcacheProvider.GetContext(ctx, key)
 	return err == nil
 }
+
+// Close stops the background cleanup of bigcache storage, so that its memory
+// can be released once the provider is no longer used
+func (bigcacheProvider *BigCacheProvider) Close() error {
+	if bigcacheProvider.cacheStorage == nil {
+		return nil
+	}
+	return bigcacheProvider.cacheStorage.Close()
+}
@@ -1,11 +1,14 @@
 package bigcache
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/allegro/bigcache"
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	"github.com/josemiguelmelo/gocacheable/providertest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -58,6 +61,12 @@ func TestBigCacheInit(t *testing.T) {
 	assert.NotNil(t, cacheProvider.cacheStorage)
 	// cache storage must be empty
 	assert.Equal(t, 0, cacheProvider.cacheStorage.Len())
+	assert.Nil(t, cacheProvider.Close())
+}
+
+func TestBigCacheCloseNotInitialized(t *testing.T) {
+	cacheProvider := BigCacheProvider{Lifetime: 2}
+	assert.Nil(t, cacheProvider.Close())
 }
 
 func TestBigCacheStorageAddAndGetMethods(t *testing.T) {
@@ -115,3 +124,51 @@ func TestBigCacheStorageReset(t *testing.T) {
 	_, err = bigcacheStorage.Get(cacheKey)
 	assert.NotNil(t, err)
 }
+
+func TestBigCacheStorageContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := bigcacheStorage.SetContext(ctx, cacheKey, []byte(initialExpectedValue))
+	assert.Equal(t, context.Canceled, err)
+	_, err = bigcacheStorage.GetContext(ctx, cacheKey)
+	assert.Equal(t, context.Canceled, err)
+	err = bigcacheStorage.DeleteContext(ctx, cacheKey)
+	assert.Equal(t, context.Canceled, err)
+	err = bigcacheStorage.ResetContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, false, bigcacheStorage.HasKeyContext(ctx, cacheKey))
+}
+
+func TestBigCacheStorageSetWithTTL(t *testing.T) {
+	err := bigcacheStorage.SetWithTTL(cacheKey, []byte(initialExpectedValue), 50*time.Millisecond)
+	assert.Nil(t, err)
+
+	value, err := bigcacheStorage.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, initialExpectedValue, string(value))
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = bigcacheStorage.Get(cacheKey)
+	assert.Equal(t, gcInterfaces.ErrNotFound, err)
+	assert.Equal(t, false, bigcacheStorage.HasKey(cacheKey))
+
+	// Values set without ttl do not expire
+	err = bigcacheStorage.Set(cacheKey, []byte(initialExpectedValue))
+	assert.Nil(t, err)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, true, bigcacheStorage.HasKey(cacheKey))
+}
+
+func TestBigCacheConformance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) providertest.Fixture {
+		clock := providertest.NewClock()
+		bigcacheProvider := &BigCacheProvider{Lifetime: 2, Clock: clock.Now}
+		assert.Nil(t, bigcacheProvider.Init())
+		t.Cleanup(func() { bigcacheProvider.Close() })
+		return providertest.Fixture{
+			Provider: bigcacheProvider,
+			Advance:  clock.Advance,
+		}
+	})
+}
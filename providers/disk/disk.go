@@ -0,0 +1,365 @@
+package disk
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+)
+
+var (
+	// ErrNotFound is returned when a key is not cached or expired
+	ErrNotFound = gcInterfaces.ErrNotFound
+	// ErrEntryTooLarge is returned when a single entry exceeds MaxBytes
+	ErrEntryTooLarge = errors.New("Entry is larger than the cache maximum size")
+	// ErrInvalidEntry is returned when an entry file is corrupted
+	ErrInvalidEntry = errors.New("Invalid cache entry")
+	// ErrNoDirectory is returned by Init when Dir is not set
+	ErrNoDirectory = errors.New("Cache directory is not set")
+)
+
+// NewDiskProvider returns a DiskProvider storing entries under dir, indexing
+// the entries left there by a previous run
+func NewDiskProvider(dir string, maxBytes int64) (*DiskProvider, error) {
+	diskProvider := &DiskProvider{
+		Dir:      dir,
+		MaxBytes: maxBytes,
+	}
+
+	err := diskProvider.Init()
+	if err != nil {
+		return nil, err
+	}
+	return diskProvider, nil
+}
+
+type diskEntry struct {
+	key       string
+	path      string
+	size      int64
+	expiresAt time.Time
+}
+
+// DiskProvider is a storage provider keeping every entry in its own file
+// under Dir, so the cache survives restarts and can be larger than memory.
+// Files are written atomically, and the least recently used entries are
+// removed once the files take more than MaxBytes. An in memory index of the
+// keys, rebuilt from the files by Init, is used for eviction and expiry.
+//
+// A directory must not be used by several providers at the same time.
+type DiskProvider struct {
+	// Dir is the directory holding the entry files, created if missing
+	Dir string
+	// MaxBytes is the maximum size of the entry files, zero means no limit
+	MaxBytes int64
+	// Clock returns the current time, time.Now when nil
+	Clock func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int64
+}
+
+// Init initializes disk storage
+func (diskProvider *DiskProvider) Init() error {
+	return diskProvider.InitContext(context.Background())
+}
+
+// InitContext initializes disk storage, creating Dir if needed and indexing
+// the entries it already holds. Expired and corrupted entries, as well as
+// temporary files left by an interrupted write, are removed. Entries are
+// ordered by modification time, so the least recently written are evicted first.
+func (diskProvider *DiskProvider) InitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if diskProvider.Dir == "" {
+		return ErrNoDirectory
+	}
+	if err := os.MkdirAll(diskProvider.Dir, 0o755); err != nil {
+		return err
+	}
+
+	type indexedFile struct {
+		entry   *diskEntry
+		modTime time.Time
+	}
+	var files []indexedFile
+
+	now := diskProvider.now()
+	err := filepath.WalkDir(diskProvider.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		name := d.Name()
+		if strings.HasPrefix(name, tempFilePrefix) {
+			return os.Remove(path)
+		}
+		if !strings.HasSuffix(name, fileExtension) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		key, expiresAt, err := readHeader(path)
+		if err != nil || (!expiresAt.IsZero() && !now.Before(expiresAt)) {
+			return os.Remove(path)
+		}
+
+		files = append(files, indexedFile{
+			entry:   &diskEntry{key: key, path: path, size: info.Size(), expiresAt: expiresAt},
+			modTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	diskProvider.mu.Lock()
+	defer diskProvider.mu.Unlock()
+
+	diskProvider.entries = map[string]*list.Element{}
+	diskProvider.order = list.New()
+	diskProvider.size = 0
+	for _, file := range files {
+		diskProvider.entries[file.entry.key] = diskProvider.order.PushFront(file.entry)
+		diskProvider.size += file.entry.size
+	}
+	return diskProvider.evict()
+}
+
+func (diskProvider *DiskProvider) now() time.Time {
+	if diskProvider.Clock != nil {
+		return diskProvider.Clock()
+	}
+	return time.Now()
+}
+
+func (diskProvider *DiskProvider) path(key string) string {
+	return filepath.Join(diskProvider.Dir, fileName(key))
+}
+
+// Set adds a new value to cache or updates if it already exists
+func (diskProvider *DiskProvider) Set(key string, value []byte) error {
+	return diskProvider.SetWithTTLContext(context.Background(), key, value, 0)
+}
+
+// SetContext adds a new value to cache or updates if it already exists
+func (diskProvider *DiskProvider) SetContext(ctx context.Context, key string, value []byte) error {
+	return diskProvider.SetWithTTLContext(ctx, key, value, 0)
+}
+
+// SetWithTTL adds a new value to cache, or updates it, expiring after ttl
+func (diskProvider *DiskProvider) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return diskProvider.SetWithTTLContext(context.Background(), key, value, ttl)
+}
+
+// SetWithTTLContext adds a new value to cache, or updates it, expiring after
+// ttl. The value is on disk when it returns.
+func (diskProvider *DiskProvider) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = diskProvider.now().Add(ttl)
+	}
+
+	data := encodeFile(key, value, expiresAt)
+	if diskProvider.MaxBytes > 0 && int64(len(data)) > diskProvider.MaxBytes {
+		return ErrEntryTooLarge
+	}
+
+	diskProvider.mu.Lock()
+	defer diskProvider.mu.Unlock()
+
+	e := &diskEntry{key: key, path: diskProvider.path(key), size: int64(len(data)), expiresAt: expiresAt}
+	if err := writeFile(e.path, data); err != nil {
+		return err
+	}
+
+	if element, ok := diskProvider.entries[key]; ok {
+		diskProvider.size -= element.Value.(*diskEntry).size
+		diskProvider.order.Remove(element)
+	}
+	diskProvider.entries[key] = diskProvider.order.PushFront(e)
+	diskProvider.size += e.size
+	return diskProvider.evict()
+}
+
+// evict removes least recently used entries until MaxBytes is respected.
+// Must be called with mu held.
+func (diskProvider *DiskProvider) evict() error {
+	for diskProvider.MaxBytes > 0 && diskProvider.size > diskProvider.MaxBytes {
+		if err := diskProvider.remove(diskProvider.order.Back()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove deletes the file of element and drops it from the index. Must be
+// called with mu held.
+func (diskProvider *DiskProvider) remove(element *list.Element) error {
+	e := element.Value.(*diskEntry)
+	if err := os.Remove(e.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	diskProvider.order.Remove(element)
+	delete(diskProvider.entries, e.key)
+	diskProvider.size -= e.size
+	return nil
+}
+
+// lookup returns the element of key, removing it if expired. Must be called with mu held.
+func (diskProvider *DiskProvider) lookup(key string) *list.Element {
+	element, ok := diskProvider.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := element.Value.(*diskEntry)
+	if !e.expiresAt.IsZero() && !diskProvider.now().Before(e.expiresAt) {
+		diskProvider.remove(element)
+		return nil
+	}
+	return element
+}
+
+// Get returns a cached value or error if it does not exist
+func (diskProvider *DiskProvider) Get(key string) ([]byte, error) {
+	return diskProvider.GetContext(context.Background(), key)
+}
+
+// GetContext returns a cached value or error if it does not exist. Corrupted
+// entries are removed and reported as ErrInvalidEntry.
+func (diskProvider *DiskProvider) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	diskProvider.mu.Lock()
+	defer diskProvider.mu.Unlock()
+
+	element := diskProvider.lookup(key)
+	if element == nil {
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(element.Value.(*diskEntry).path)
+	if errors.Is(err, fs.ErrNotExist) {
+		diskProvider.remove(element)
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	storedKey, value, _, err := decodeFile(data)
+	if err == nil && storedKey != key {
+		err = ErrInvalidEntry
+	}
+	if err != nil {
+		diskProvider.remove(element)
+		return nil, err
+	}
+
+	diskProvider.order.MoveToFront(element)
+	return value, nil
+}
+
+// Delete removes a value from the cache
+func (diskProvider *DiskProvider) Delete(key string) error {
+	return diskProvider.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext removes a value from the cache. Deleting a missing key is not an error.
+func (diskProvider *DiskProvider) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	diskProvider.mu.Lock()
+	defer diskProvider.mu.Unlock()
+	if element, ok := diskProvider.entries[key]; ok {
+		return diskProvider.remove(element)
+	}
+	return nil
+}
+
+// Reset empties cache storage
+func (diskProvider *DiskProvider) Reset() error {
+	return diskProvider.ResetContext(context.Background())
+}
+
+// ResetContext empties cache storage, removing every entry file. Other files
+// in Dir are left untouched.
+func (diskProvider *DiskProvider) ResetContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	diskProvider.mu.Lock()
+	defer diskProvider.mu.Unlock()
+	for diskProvider.order.Len() > 0 {
+		if err := diskProvider.remove(diskProvider.order.Back()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasKey checks if the key exists
+func (diskProvider *DiskProvider) HasKey(key string) bool {
+	return diskProvider.HasKeyContext(context.Background(), key)
+}
+
+// HasKeyContext checks if the key exists, without reading its file or
+// affecting the eviction order
+func (diskProvider *DiskProvider) HasKeyContext(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	diskProvider.mu.Lock()
+	defer diskProvider.mu.Unlock()
+	return diskProvider.lookup(key) != nil
+}
+
+// Len returns the number of entries, including expired ones not yet removed
+func (diskProvider *DiskProvider) Len() int {
+	diskProvider.mu.Lock()
+	defer diskProvider.mu.Unlock()
+	return len(diskProvider.entries)
+}
+
+// Size returns the total size of the entry files
+func (diskProvider *DiskProvider) Size() int64 {
+	diskProvider.mu.Lock()
+	defer diskProvider.mu.Unlock()
+	return diskProvider.size
+}
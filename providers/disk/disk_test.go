@@ -0,0 +1,227 @@
+package disk
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/josemiguelmelo/gocacheable/providertest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cacheKey   = "key"
+	cacheValue = "value"
+)
+
+func NewCacheableStorage(dir string, maxBytes int64, clock *providertest.Clock) *DiskProvider {
+	diskProvider := &DiskProvider{Dir: dir, MaxBytes: maxBytes, Clock: clock.Now}
+	if err := diskProvider.Init(); err != nil {
+		return nil
+	}
+	return diskProvider
+}
+
+// entrySize returns the size of the file of an entry
+func entrySize(key string, value string) int64 {
+	return int64(len(encodeFile(key, []byte(value), time.Time{})))
+}
+
+func TestDiskSetAndGet(t *testing.T) {
+	diskProvider, err := NewDiskProvider(t.TempDir(), 0)
+	assert.Nil(t, err)
+
+	_, err = diskProvider.Get(cacheKey)
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.Nil(t, diskProvider.Set(cacheKey, []byte(cacheValue)))
+	value, err := diskProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(value))
+
+	assert.Nil(t, diskProvider.Set(cacheKey, []byte("updated")))
+	value, err = diskProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", string(value))
+	assert.Equal(t, 1, diskProvider.Len())
+	assert.Equal(t, entrySize(cacheKey, "updated"), diskProvider.Size())
+}
+
+func TestDiskNoDirectory(t *testing.T) {
+	_, err := NewDiskProvider("", 0)
+	assert.Equal(t, ErrNoDirectory, err)
+}
+
+func TestDiskSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	clock := providertest.NewClock()
+	diskProvider := NewCacheableStorage(dir, 0, clock)
+
+	assert.Nil(t, diskProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, diskProvider.SetWithTTL("expiring", []byte(cacheValue), time.Minute))
+	assert.Nil(t, diskProvider.SetWithTTL("long", []byte(cacheValue), time.Hour))
+
+	clock.Advance(time.Minute)
+	restarted := NewCacheableStorage(dir, 0, clock)
+	assert.Equal(t, 2, restarted.Len())
+
+	value, err := restarted.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(value))
+	assert.Equal(t, true, restarted.HasKey("long"))
+	assert.Equal(t, false, restarted.HasKey("expiring"))
+
+	clock.Advance(time.Hour)
+	_, err = restarted.Get("long")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestDiskInitCleansDirectory(t *testing.T) {
+	dir := t.TempDir()
+	diskProvider := NewCacheableStorage(dir, 0, providertest.NewClock())
+	assert.Nil(t, diskProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, diskProvider.Set("corrupted", []byte(cacheValue)))
+
+	// Leftovers of a write interrupted by a crash, and a corrupted entry
+	tempPath := filepath.Join(dir, "00", tempFilePrefix+"123")
+	assert.Nil(t, os.MkdirAll(filepath.Dir(tempPath), 0o755))
+	assert.Nil(t, os.WriteFile(tempPath, []byte("partial"), 0o644))
+	assert.Nil(t, os.WriteFile(diskProvider.path("corrupted"), []byte("garbage"), 0o644))
+
+	restarted := NewCacheableStorage(dir, 0, providertest.NewClock())
+	assert.Equal(t, 1, restarted.Len())
+	assert.Equal(t, true, restarted.HasKey(cacheKey))
+	_, err := os.Stat(tempPath)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(diskProvider.path("corrupted"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskDetectsCorruption(t *testing.T) {
+	diskProvider := NewCacheableStorage(t.TempDir(), 0, providertest.NewClock())
+	assert.Nil(t, diskProvider.Set(cacheKey, []byte(cacheValue)))
+
+	path := diskProvider.path(cacheKey)
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	data[len(data)-fileTrailerSize-1] ^= 0xFF
+	assert.Nil(t, os.WriteFile(path, data, 0o644))
+
+	_, err = diskProvider.Get(cacheKey)
+	assert.Equal(t, ErrInvalidEntry, err)
+	assert.Equal(t, false, diskProvider.HasKey(cacheKey))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskRejectsCorruptedHeader(t *testing.T) {
+	dir := t.TempDir()
+	data := encodeFile(cacheKey, []byte(cacheValue), time.Time{})
+
+	// A key length beyond the end of the file
+	tooLong := append([]byte{}, data...)
+	binary.BigEndian.PutUint32(tooLong[12:16], math.MaxUint32)
+	// A file which is not an entry
+	badMagic := append([]byte{}, data...)
+	copy(badMagic, "XXXX")
+
+	for i, corrupted := range [][]byte{tooLong, badMagic} {
+		path := filepath.Join(dir, fmt.Sprintf("corrupted_%d", i))
+		assert.Nil(t, os.WriteFile(path, corrupted, 0o644))
+		_, _, err := readHeader(path)
+		assert.Equal(t, ErrInvalidEntry, err)
+	}
+
+	path := filepath.Join(dir, "valid")
+	assert.Nil(t, os.WriteFile(path, data, 0o644))
+	key, _, err := readHeader(path)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheKey, key)
+}
+
+func TestDiskEvictsLeastRecentlyUsed(t *testing.T) {
+	diskProvider := NewCacheableStorage(t.TempDir(), 3*entrySize("key0", cacheValue), providertest.NewClock())
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, diskProvider.Set(fmt.Sprintf("key%d", i), []byte(cacheValue)))
+	}
+	_, err := diskProvider.Get("key0")
+	assert.Nil(t, err)
+
+	assert.Nil(t, diskProvider.Set("key3", []byte(cacheValue)))
+	assert.Equal(t, 3, diskProvider.Len())
+	assert.Equal(t, false, diskProvider.HasKey("key1"))
+	_, err = os.Stat(diskProvider.path("key1"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, true, diskProvider.HasKey("key0"))
+
+	assert.Equal(t, ErrEntryTooLarge, diskProvider.Set("large", make([]byte, 1024)))
+}
+
+func TestDiskTimeToLive(t *testing.T) {
+	clock := providertest.NewClock()
+	diskProvider := NewCacheableStorage(t.TempDir(), 0, clock)
+
+	assert.Nil(t, diskProvider.SetWithTTL(cacheKey, []byte(cacheValue), time.Minute))
+	assert.Nil(t, diskProvider.Set("no_ttl", []byte(cacheValue)))
+
+	clock.Advance(59 * time.Second)
+	assert.Equal(t, true, diskProvider.HasKey(cacheKey))
+
+	clock.Advance(time.Second)
+	_, err := diskProvider.Get(cacheKey)
+	assert.Equal(t, ErrNotFound, err)
+	_, err = os.Stat(diskProvider.path(cacheKey))
+	assert.True(t, os.IsNotExist(err))
+
+	clock.Advance(24 * time.Hour)
+	assert.Equal(t, true, diskProvider.HasKey("no_ttl"))
+}
+
+func TestDiskDeleteAndReset(t *testing.T) {
+	dir := t.TempDir()
+	diskProvider := NewCacheableStorage(dir, 0, providertest.NewClock())
+
+	assert.Nil(t, diskProvider.Delete(cacheKey))
+	assert.Nil(t, diskProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, diskProvider.Delete(cacheKey))
+	assert.Equal(t, false, diskProvider.HasKey(cacheKey))
+	assert.Equal(t, int64(0), diskProvider.Size())
+
+	otherFile := filepath.Join(dir, "other")
+	assert.Nil(t, os.WriteFile(otherFile, []byte(cacheValue), 0o644))
+	assert.Nil(t, diskProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, diskProvider.Reset())
+	assert.Equal(t, 0, diskProvider.Len())
+	assert.Equal(t, int64(0), diskProvider.Size())
+	_, err := os.Stat(otherFile)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 0, NewCacheableStorage(dir, 0, providertest.NewClock()).Len())
+}
+
+func TestDiskContextCanceled(t *testing.T) {
+	diskProvider := NewCacheableStorage(t.TempDir(), 0, providertest.NewClock())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, diskProvider.InitContext(ctx))
+	assert.Equal(t, context.Canceled, diskProvider.SetContext(ctx, cacheKey, []byte(cacheValue)))
+	_, err := diskProvider.GetContext(ctx, cacheKey)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, diskProvider.DeleteContext(ctx, cacheKey))
+	assert.Equal(t, context.Canceled, diskProvider.ResetContext(ctx))
+	assert.Equal(t, false, diskProvider.HasKeyContext(ctx, cacheKey))
+}
+
+func TestDiskConformance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) providertest.Fixture {
+		clock := providertest.NewClock()
+		return providertest.Fixture{Provider: NewCacheableStorage(t.TempDir(), 0, clock), Advance: clock.Advance}
+	})
+}
@@ -0,0 +1,159 @@
+package disk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Entry file layout:
+//
+//	magic(4) | expiresAt int64 | keyLength uint32 | key | value | crc32(4)
+//
+// expiresAt is in unix nanoseconds, zero when the entry never expires. The
+// checksum covers everything before it.
+var fileMagic = []byte("GCD1")
+
+const (
+	fileHeaderSize  = 4 + 8 + 4
+	fileTrailerSize = 4
+	fileExtension   = ".entry"
+	tempFilePrefix  = ".tmp-"
+)
+
+// fileName returns the path of the file of key, relative to the storage
+// directory. Files are spread over 256 subdirectories.
+func fileName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(hash[:])
+	return filepath.Join(name[:2], name+fileExtension)
+}
+
+func encodeFile(key string, value []byte, expiresAt time.Time) []byte {
+	data := make([]byte, fileHeaderSize, fileHeaderSize+len(key)+len(value)+fileTrailerSize)
+	copy(data, fileMagic)
+	if !expiresAt.IsZero() {
+		binary.BigEndian.PutUint64(data[4:12], uint64(expiresAt.UnixNano()))
+	}
+	binary.BigEndian.PutUint32(data[12:16], uint32(len(key)))
+	data = append(data, key...)
+	data = append(data, value...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+}
+
+// decodeFile returns the key, value and expiry of an entry file content
+func decodeFile(data []byte) (string, []byte, time.Time, error) {
+	if len(data) < fileHeaderSize+fileTrailerSize {
+		return "", nil, time.Time{}, ErrInvalidEntry
+	}
+
+	body, trailer := data[:len(data)-fileTrailerSize], data[len(data)-fileTrailerSize:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(trailer) {
+		return "", nil, time.Time{}, ErrInvalidEntry
+	}
+
+	key, expiresAt, err := decodeHeader(body)
+	if err != nil {
+		return "", nil, time.Time{}, err
+	}
+	return key, body[fileHeaderSize+len(key):], expiresAt, nil
+}
+
+// decodeHeader returns the key and expiry of an entry, from the start of its file
+func decodeHeader(data []byte) (string, time.Time, error) {
+	if len(data) < fileHeaderSize || !bytes.Equal(data[:4], fileMagic) {
+		return "", time.Time{}, ErrInvalidEntry
+	}
+
+	var expiresAt time.Time
+	if nanoseconds := int64(binary.BigEndian.Uint64(data[4:12])); nanoseconds != 0 {
+		expiresAt = time.Unix(0, nanoseconds)
+	}
+
+	keyLength := int(binary.BigEndian.Uint32(data[12:16]))
+	if len(data) < fileHeaderSize+keyLength {
+		return "", time.Time{}, ErrInvalidEntry
+	}
+	return string(data[fileHeaderSize : fileHeaderSize+keyLength]), expiresAt, nil
+}
+
+// readHeader returns the key and expiry of the entry stored in path, without
+// reading its value
+func readHeader(path string) (string, time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer file.Close()
+
+	header := make([]byte, fileHeaderSize)
+	if _, err = io.ReadFull(file, header); err != nil || !bytes.Equal(header[:4], fileMagic) {
+		return "", time.Time{}, ErrInvalidEntry
+	}
+
+	// A corrupted key length must not allocate more than the file holds
+	info, err := file.Stat()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	keyLength := int64(binary.BigEndian.Uint32(header[12:16]))
+	if keyLength > info.Size()-fileHeaderSize {
+		return "", time.Time{}, ErrInvalidEntry
+	}
+	data := make([]byte, fileHeaderSize+keyLength)
+	copy(data, header)
+	if _, err = io.ReadFull(file, data[fileHeaderSize:]); err != nil {
+		return "", time.Time{}, ErrInvalidEntry
+	}
+	return decodeHeader(data)
+}
+
+// writeFile atomically replaces path with data. The data is written to a
+// temporary file in the same directory, flushed to disk and renamed over path,
+// so a crash leaves either the old or the new content, never a partial one.
+func writeFile(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(dir, tempFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	tempPath := file.Name()
+
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes a directory so that a rename in it survives a crash
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Some platforms do not support syncing directories
+	file.Sync()
+	return nil
+}
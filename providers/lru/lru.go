@@ -0,0 +1,241 @@
+package lru
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+)
+
+var (
+	// ErrNotFound is returned when a key is not cached or expired
+	ErrNotFound = gcInterfaces.ErrNotFound
+	// ErrEntryTooLarge is returned when a single entry exceeds MaxBytes
+	ErrEntryTooLarge = errors.New("Entry is larger than the cache maximum size")
+)
+
+// NewLRUProvider returns an initialized LRUProvider
+func NewLRUProvider(maxEntries int, maxBytes int64) *LRUProvider {
+	lruProvider := &LRUProvider{
+		MaxEntries: maxEntries,
+		MaxBytes:   maxBytes,
+	}
+	lruProvider.Init()
+	return lruProvider
+}
+
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e *lruEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// LRUProvider is an in process storage provider evicting the least recently
+// used entries once it holds MaxEntries entries or MaxBytes bytes of keys and
+// values. Every operation runs in constant time.
+type LRUProvider struct {
+	// MaxEntries is the maximum number of entries, zero means no limit
+	MaxEntries int
+	// MaxBytes is the maximum size of keys and values, zero means no limit
+	MaxBytes int64
+	// Clock returns the current time, time.Now when nil
+	Clock func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int64
+}
+
+// Init initializes lru storage
+func (lruProvider *LRUProvider) Init() error {
+	return lruProvider.InitContext(context.Background())
+}
+
+// InitContext initializes lru storage unless ctx is already done
+func (lruProvider *LRUProvider) InitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	lruProvider.mu.Lock()
+	defer lruProvider.mu.Unlock()
+	lruProvider.entries = map[string]*list.Element{}
+	lruProvider.order = list.New()
+	lruProvider.size = 0
+	return nil
+}
+
+func (lruProvider *LRUProvider) now() time.Time {
+	if lruProvider.Clock != nil {
+		return lruProvider.Clock()
+	}
+	return time.Now()
+}
+
+// Set adds a new value to cache or updates if it already exists
+func (lruProvider *LRUProvider) Set(key string, value []byte) error {
+	return lruProvider.SetWithTTLContext(context.Background(), key, value, 0)
+}
+
+// SetContext adds a new value to cache or updates if it already exists
+func (lruProvider *LRUProvider) SetContext(ctx context.Context, key string, value []byte) error {
+	return lruProvider.SetWithTTLContext(ctx, key, value, 0)
+}
+
+// SetWithTTL adds a new value to cache, or updates it, expiring after ttl
+func (lruProvider *LRUProvider) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return lruProvider.SetWithTTLContext(context.Background(), key, value, ttl)
+}
+
+// SetWithTTLContext adds a new value to cache, or updates it, expiring after ttl
+func (lruProvider *LRUProvider) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	e := &lruEntry{key: key, value: append([]byte{}, value...)}
+	if ttl > 0 {
+		e.expiresAt = lruProvider.now().Add(ttl)
+	}
+	if lruProvider.MaxBytes > 0 && e.size() > lruProvider.MaxBytes {
+		return ErrEntryTooLarge
+	}
+
+	lruProvider.mu.Lock()
+	defer lruProvider.mu.Unlock()
+
+	if element, ok := lruProvider.entries[key]; ok {
+		lruProvider.size -= element.Value.(*lruEntry).size()
+		element.Value = e
+		lruProvider.order.MoveToFront(element)
+	} else {
+		lruProvider.entries[key] = lruProvider.order.PushFront(e)
+	}
+	lruProvider.size += e.size()
+
+	lruProvider.evict()
+	return nil
+}
+
+// evict removes least recently used entries until the limits are respected.
+// Must be called with mu held.
+func (lruProvider *LRUProvider) evict() {
+	for lruProvider.order.Len() > 0 &&
+		((lruProvider.MaxEntries > 0 && lruProvider.order.Len() > lruProvider.MaxEntries) ||
+			(lruProvider.MaxBytes > 0 && lruProvider.size > lruProvider.MaxBytes)) {
+		lruProvider.remove(lruProvider.order.Back())
+	}
+}
+
+// remove deletes element from the cache. Must be called with mu held.
+func (lruProvider *LRUProvider) remove(element *list.Element) {
+	e := element.Value.(*lruEntry)
+	lruProvider.order.Remove(element)
+	delete(lruProvider.entries, e.key)
+	lruProvider.size -= e.size()
+}
+
+// Get returns a cached value or error if it does not exist
+func (lruProvider *LRUProvider) Get(key string) ([]byte, error) {
+	return lruProvider.GetContext(context.Background(), key)
+}
+
+// GetContext returns a cached value or error if it does not exist, marking it
+// as the most recently used
+func (lruProvider *LRUProvider) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	lruProvider.mu.Lock()
+	defer lruProvider.mu.Unlock()
+
+	element := lruProvider.lookup(key)
+	if element == nil {
+		return nil, ErrNotFound
+	}
+	lruProvider.order.MoveToFront(element)
+	return append([]byte{}, element.Value.(*lruEntry).value...), nil
+}
+
+// lookup returns the element of key, removing it if expired. Must be called with mu held.
+func (lruProvider *LRUProvider) lookup(key string) *list.Element {
+	element, ok := lruProvider.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := element.Value.(*lruEntry)
+	if !e.expiresAt.IsZero() && !lruProvider.now().Before(e.expiresAt) {
+		lruProvider.remove(element)
+		return nil
+	}
+	return element
+}
+
+// Delete removes a value from the cache
+func (lruProvider *LRUProvider) Delete(key string) error {
+	return lruProvider.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext removes a value from the cache. Deleting a missing key is not an error.
+func (lruProvider *LRUProvider) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	lruProvider.mu.Lock()
+	defer lruProvider.mu.Unlock()
+	if element, ok := lruProvider.entries[key]; ok {
+		lruProvider.remove(element)
+	}
+	return nil
+}
+
+// Reset empties cache storage
+func (lruProvider *LRUProvider) Reset() error {
+	return lruProvider.ResetContext(context.Background())
+}
+
+// ResetContext empties cache storage
+func (lruProvider *LRUProvider) ResetContext(ctx context.Context) error {
+	return lruProvider.InitContext(ctx)
+}
+
+// HasKey checks if the key exists
+func (lruProvider *LRUProvider) HasKey(key string) bool {
+	return lruProvider.HasKeyContext(context.Background(), key)
+}
+
+// HasKeyContext checks if the key exists, without marking it as recently used
+func (lruProvider *LRUProvider) HasKeyContext(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	lruProvider.mu.Lock()
+	defer lruProvider.mu.Unlock()
+	return lruProvider.lookup(key) != nil
+}
+
+// Len returns the number of entries, including expired ones not yet removed
+func (lruProvider *LRUProvider) Len() int {
+	lruProvider.mu.Lock()
+	defer lruProvider.mu.Unlock()
+	return lruProvider.order.Len()
+}
+
+// Size returns the size in bytes of the cached keys and values
+func (lruProvider *LRUProvider) Size() int64 {
+	lruProvider.mu.Lock()
+	defer lruProvider.mu.Unlock()
+	return lruProvider.size
+}
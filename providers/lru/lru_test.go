@@ -0,0 +1,153 @@
+package lru
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/josemiguelmelo/gocacheable/providertest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cacheKey   = "key"
+	cacheValue = "value"
+)
+
+func NewCacheableStorage(maxEntries int, maxBytes int64) (*LRUProvider, *providertest.Clock) {
+	clock := providertest.NewClock()
+	lruProvider := &LRUProvider{MaxEntries: maxEntries, MaxBytes: maxBytes, Clock: clock.Now}
+	lruProvider.Init()
+	return lruProvider, clock
+}
+
+func TestLRUSetAndGet(t *testing.T) {
+	lruProvider := NewLRUProvider(0, 0)
+
+	_, err := lruProvider.Get(cacheKey)
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.Nil(t, lruProvider.Set(cacheKey, []byte(cacheValue)))
+	value, err := lruProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(value))
+
+	assert.Nil(t, lruProvider.Set(cacheKey, []byte("updated")))
+	value, err = lruProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", string(value))
+	assert.Equal(t, 1, lruProvider.Len())
+	assert.Equal(t, int64(len(cacheKey)+len("updated")), lruProvider.Size())
+}
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	lruProvider, _ := NewCacheableStorage(3, 0)
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, lruProvider.Set(fmt.Sprintf("key%d", i), []byte(cacheValue)))
+	}
+
+	// key0 becomes the most recently used, key1 the least
+	_, err := lruProvider.Get("key0")
+	assert.Nil(t, err)
+	// HasKey does not change the order
+	assert.Equal(t, true, lruProvider.HasKey("key1"))
+
+	assert.Nil(t, lruProvider.Set("key3", []byte(cacheValue)))
+	assert.Equal(t, 3, lruProvider.Len())
+	assert.Equal(t, false, lruProvider.HasKey("key1"))
+	assert.Equal(t, true, lruProvider.HasKey("key0"))
+	assert.Equal(t, true, lruProvider.HasKey("key2"))
+	assert.Equal(t, true, lruProvider.HasKey("key3"))
+}
+
+func TestLRUMaxBytes(t *testing.T) {
+	lruProvider, _ := NewCacheableStorage(0, 20)
+
+	// Each entry takes 10 bytes
+	assert.Nil(t, lruProvider.Set("key0", []byte("value0")))
+	assert.Nil(t, lruProvider.Set("key1", []byte("value1")))
+	assert.Nil(t, lruProvider.Set("key2", []byte("value2")))
+	assert.Equal(t, int64(20), lruProvider.Size())
+	assert.Equal(t, false, lruProvider.HasKey("key0"))
+
+	// Growing an entry evicts others
+	assert.Nil(t, lruProvider.Set("key2", []byte("value2value2")))
+	assert.Equal(t, 1, lruProvider.Len())
+	assert.Equal(t, int64(16), lruProvider.Size())
+
+	err := lruProvider.Set("large", make([]byte, 20))
+	assert.Equal(t, ErrEntryTooLarge, err)
+	assert.Equal(t, true, lruProvider.HasKey("key2"))
+}
+
+func TestLRUTimeToLive(t *testing.T) {
+	lruProvider, clock := NewCacheableStorage(0, 0)
+
+	assert.Nil(t, lruProvider.SetWithTTL(cacheKey, []byte(cacheValue), time.Minute))
+	assert.Nil(t, lruProvider.Set("no_ttl", []byte(cacheValue)))
+
+	clock.Advance(59 * time.Second)
+	assert.Equal(t, true, lruProvider.HasKey(cacheKey))
+
+	clock.Advance(time.Second)
+	_, err := lruProvider.Get(cacheKey)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, 1, lruProvider.Len())
+
+	clock.Advance(24 * time.Hour)
+	assert.Equal(t, true, lruProvider.HasKey("no_ttl"))
+}
+
+func TestLRUDeleteAndReset(t *testing.T) {
+	lruProvider, _ := NewCacheableStorage(0, 0)
+
+	assert.Nil(t, lruProvider.Delete(cacheKey))
+	assert.Nil(t, lruProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, lruProvider.Delete(cacheKey))
+	assert.Equal(t, false, lruProvider.HasKey(cacheKey))
+	assert.Equal(t, int64(0), lruProvider.Size())
+
+	assert.Nil(t, lruProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, lruProvider.Reset())
+	assert.Equal(t, 0, lruProvider.Len())
+	assert.Equal(t, int64(0), lruProvider.Size())
+}
+
+func TestLRUReturnsCopies(t *testing.T) {
+	lruProvider, _ := NewCacheableStorage(0, 0)
+
+	value := []byte(cacheValue)
+	assert.Nil(t, lruProvider.Set(cacheKey, value))
+	value[0] = 'V'
+
+	cached, err := lruProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(cached))
+	cached[0] = 'V'
+
+	cached, err = lruProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(cached))
+}
+
+func TestLRUContextCanceled(t *testing.T) {
+	lruProvider, _ := NewCacheableStorage(0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, lruProvider.SetContext(ctx, cacheKey, []byte(cacheValue)))
+	_, err := lruProvider.GetContext(ctx, cacheKey)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, lruProvider.DeleteContext(ctx, cacheKey))
+	assert.Equal(t, context.Canceled, lruProvider.ResetContext(ctx))
+	assert.Equal(t, false, lruProvider.HasKeyContext(ctx, cacheKey))
+}
+
+func TestLRUConformance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) providertest.Fixture {
+		lruProvider, clock := NewCacheableStorage(0, 0)
+		return providertest.Fixture{Provider: lruProvider, Advance: clock.Advance}
+	})
+}
@@ -0,0 +1,187 @@
+package memcached
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type fakeItem struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// fakeServer is an in process memcached server implementing the subset of the
+// text protocol used by the provider
+type fakeServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	items    map[string]fakeItem
+	now      time.Time
+	commands []string
+	stall    bool
+}
+
+func newFakeServer() (*fakeServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	server := &fakeServer{listener: listener, items: map[string]fakeItem{}, now: time.Now()}
+	go server.serve()
+	return server, nil
+}
+
+func (s *fakeServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeServer) Close() {
+	s.listener.Close()
+}
+
+// FastForward moves the server clock forward
+func (s *fakeServer) FastForward(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
+
+// Stall makes the server read commands without ever answering them
+func (s *fakeServer) Stall(stall bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stall = stall
+}
+
+// Commands returns the command lines received so far
+func (s *fakeServer) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.commands...)
+}
+
+// Keys returns the keys stored and not expired
+func (s *fakeServer) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key := range s.items {
+		if _, ok := s.lookup(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var data []byte
+		if fields[0] == "set" && len(fields) == 5 {
+			size, _ := strconv.Atoi(fields[4])
+			data = make([]byte, size+2)
+			if _, err = io.ReadFull(reader, data); err != nil {
+				return
+			}
+			data = data[:size]
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.TrimSpace(line))
+		stall := s.stall
+		s.mu.Unlock()
+		if stall {
+			continue
+		}
+
+		if _, err = conn.Write([]byte(s.execute(fields, data))); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) execute(fields []string, data []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case fields[0] == "get" && len(fields) >= 2:
+		var reply strings.Builder
+		for _, key := range fields[1:] {
+			if item, ok := s.lookup(key); ok {
+				fmt.Fprintf(&reply, "VALUE %s 0 %d\r\n%s\r\n", key, len(item.value), item.value)
+			}
+		}
+		return reply.String() + "END\r\n"
+	case fields[0] == "set" && len(fields) == 5:
+		exptime, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return "CLIENT_ERROR bad command line format\r\n"
+		}
+		s.items[fields[1]] = fakeItem{value: data, expiresAt: s.expiresAt(exptime)}
+		return "STORED\r\n"
+	case fields[0] == "delete" && len(fields) == 2:
+		if _, ok := s.lookup(fields[1]); !ok {
+			return "NOT_FOUND\r\n"
+		}
+		delete(s.items, fields[1])
+		return "DELETED\r\n"
+	case fields[0] == "flush_all":
+		s.items = map[string]fakeItem{}
+		return "OK\r\n"
+	case fields[0] == "version":
+		return "VERSION 1.6.0-fake\r\n"
+	}
+	return "ERROR\r\n"
+}
+
+// expiresAt interprets exptime like memcached: seconds from now up to 30
+// days, a unix timestamp above. Must be called with mu held.
+func (s *fakeServer) expiresAt(exptime int64) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime > int64(maxRelativeExpiration/time.Second):
+		return time.Unix(exptime, 0)
+	}
+	return s.now.Add(time.Duration(exptime) * time.Second)
+}
+
+// lookup returns the item of key unless expired. Must be called with mu held.
+func (s *fakeServer) lookup(key string) (fakeItem, bool) {
+	item, ok := s.items[key]
+	if !ok || (!item.expiresAt.IsZero() && !s.now.Before(item.expiresAt)) {
+		return fakeItem{}, false
+	}
+	return item, true
+}
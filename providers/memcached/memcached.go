@@ -0,0 +1,294 @@
+package memcached
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+)
+
+var (
+	// ErrNotFound is returned when a key is not cached or expired
+	ErrNotFound = gcInterfaces.ErrNotFound
+	// ErrNoServers is returned by Init when no server is configured
+	ErrNoServers = errors.New("No memcached server configured")
+	// ErrInvalidKey is returned for keys memcached does not accept: empty,
+	// longer than 250 bytes, or containing whitespace or control characters
+	ErrInvalidKey = errors.New("Invalid memcached key")
+	// ErrUnknownCommand is returned when the server does not know a command
+	ErrUnknownCommand = errors.New("Unknown memcached command")
+	// ErrUnexpectedReply is returned when the server reply cannot be understood
+	ErrUnexpectedReply = errors.New("Unexpected memcached reply")
+	// ErrValueTooLarge is returned for values larger than MaxItemSize, sent
+	// or announced by the server
+	ErrValueTooLarge = errors.New("Memcached value too large")
+)
+
+// DefaultMaxItemSize is the item size limit of memcached servers by default
+const DefaultMaxItemSize = 1 << 20
+
+// ServerError is a CLIENT_ERROR or SERVER_ERROR reply of the server
+type ServerError struct {
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return e.Message
+}
+
+// NewMemcachedProvider returns a MemcachedProvider
+func NewMemcachedProvider(servers []string, maxIdle int) (*MemcachedProvider, error) {
+	return NewMemcachedProviderContext(context.Background(), servers, maxIdle)
+}
+
+// NewMemcachedProviderContext returns a MemcachedProvider, using ctx for the initial ping
+func NewMemcachedProviderContext(ctx context.Context, servers []string, maxIdle int) (*MemcachedProvider, error) {
+	memcachedProvider := &MemcachedProvider{
+		Servers: servers,
+		MaxIdle: maxIdle,
+	}
+
+	err := memcachedProvider.InitContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = memcachedProvider.PingContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return memcachedProvider, nil
+}
+
+// MemcachedProvider is a storage provider based on memcached, speaking its
+// text protocol. Keys are spread over Servers by consistent hashing, and
+// entries expire natively on the servers.
+type MemcachedProvider struct {
+	// Servers are the addresses of the memcached servers
+	Servers []string
+	// MaxIdle is the maximum number of idle connections kept per server
+	MaxIdle int
+	// Timeout bounds the dial and every command, zero means no timeout.
+	// A context deadline applies as well.
+	Timeout time.Duration
+	// VirtualNodes is the number of points of every server on the hash ring,
+	// 160 when zero
+	VirtualNodes int
+	// MaxItemSize is the largest value, in bytes, stored or read. Replies
+	// announcing larger values are rejected before allocating them. It is
+	// DefaultMaxItemSize when zero, and should match the -I option of the
+	// servers.
+	MaxItemSize int
+
+	ring  *ring
+	pools map[string]*pool
+}
+
+// Init initializes memcached storage
+func (memcachedProvider *MemcachedProvider) Init() error {
+	return memcachedProvider.InitContext(context.Background())
+}
+
+// InitContext initializes memcached storage unless ctx is already done
+func (memcachedProvider *MemcachedProvider) InitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(memcachedProvider.Servers) == 0 {
+		return ErrNoServers
+	}
+
+	virtualNodes := memcachedProvider.VirtualNodes
+	if virtualNodes <= 0 {
+		virtualNodes = defaultVirtualNodes
+	}
+
+	if memcachedProvider.pools != nil {
+		memcachedProvider.Close()
+	}
+	memcachedProvider.ring = newRing(memcachedProvider.Servers, virtualNodes)
+	memcachedProvider.pools = map[string]*pool{}
+	for _, server := range memcachedProvider.Servers {
+		memcachedProvider.pools[server] = newPool(server, memcachedProvider.MaxIdle, memcachedProvider.Timeout)
+	}
+	return nil
+}
+
+// maxItemSize returns MaxItemSize, or DefaultMaxItemSize when it is not set
+func (memcachedProvider *MemcachedProvider) maxItemSize() int {
+	if memcachedProvider.MaxItemSize <= 0 {
+		return DefaultMaxItemSize
+	}
+	return memcachedProvider.MaxItemSize
+}
+
+// Close closes the idle connections to the servers
+func (memcachedProvider *MemcachedProvider) Close() error {
+	for _, p := range memcachedProvider.pools {
+		p.close()
+	}
+	return nil
+}
+
+// do runs command on a pooled connection to server, giving up as soon as ctx
+// is done. Connections are only reused after a well formed reply.
+func (memcachedProvider *MemcachedProvider) do(ctx context.Context, server string, command func(*conn) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	p := memcachedProvider.pools[server]
+	c, err := p.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	var deadline time.Time
+	if memcachedProvider.Timeout > 0 {
+		deadline = time.Now().Add(memcachedProvider.Timeout)
+	}
+	ctxDeadline, hasCtxDeadline := ctx.Deadline()
+	hasCtxDeadline = hasCtxDeadline && (deadline.IsZero() || ctxDeadline.Before(deadline))
+	if hasCtxDeadline {
+		deadline = ctxDeadline
+	}
+	c.SetDeadline(deadline)
+
+	// A cancellation interrupts the I/O in progress by expiring the deadline
+	stop := context.AfterFunc(ctx, func() {
+		c.SetDeadline(time.Unix(1, 0))
+	})
+
+	err = command(c)
+	interrupted := !stop()
+	if interrupted || (err != nil && ctx.Err() != nil) {
+		c.Close()
+		return ctx.Err()
+	}
+	// The connection deadline can fire just before the context one
+	if hasCtxDeadline && errors.Is(err, os.ErrDeadlineExceeded) {
+		c.Close()
+		return context.DeadlineExceeded
+	}
+	if err != nil && !isReplyError(err) {
+		c.Close()
+		return err
+	}
+
+	p.put(c)
+	return err
+}
+
+// Ping checks every server answers
+func (memcachedProvider *MemcachedProvider) Ping() error {
+	return memcachedProvider.PingContext(context.Background())
+}
+
+// PingContext checks every server answers, giving up as soon as ctx is done
+func (memcachedProvider *MemcachedProvider) PingContext(ctx context.Context) error {
+	for _, server := range memcachedProvider.Servers {
+		if err := memcachedProvider.do(ctx, server, (*conn).version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set adds a new value to cache or updates if it already exists
+func (memcachedProvider *MemcachedProvider) Set(key string, value []byte) error {
+	return memcachedProvider.SetWithTTLContext(context.Background(), key, value, 0)
+}
+
+// SetContext adds a new value to cache or updates if it already exists
+func (memcachedProvider *MemcachedProvider) SetContext(ctx context.Context, key string, value []byte) error {
+	return memcachedProvider.SetWithTTLContext(ctx, key, value, 0)
+}
+
+// SetWithTTL adds a new value to cache, or updates it, expiring after ttl
+func (memcachedProvider *MemcachedProvider) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return memcachedProvider.SetWithTTLContext(context.Background(), key, value, ttl)
+}
+
+// SetWithTTLContext adds a new value to cache, or updates it, expiring after
+// ttl. memcached expires entries with a second resolution, so ttl is rounded up.
+func (memcachedProvider *MemcachedProvider) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+	if len(value) > memcachedProvider.maxItemSize() {
+		return ErrValueTooLarge
+	}
+
+	exptime := expiration(ttl, time.Now())
+	return memcachedProvider.do(ctx, memcachedProvider.ring.server(key), func(c *conn) error {
+		return c.set(key, value, exptime)
+	})
+}
+
+// Get returns a cached value or error if it does not exist
+func (memcachedProvider *MemcachedProvider) Get(key string) ([]byte, error) {
+	return memcachedProvider.GetContext(context.Background(), key)
+}
+
+// GetContext returns a cached value or error if it does not exist
+func (memcachedProvider *MemcachedProvider) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if !validKey(key) {
+		return nil, ErrInvalidKey
+	}
+
+	var value []byte
+	maxSize := memcachedProvider.maxItemSize()
+	err := memcachedProvider.do(ctx, memcachedProvider.ring.server(key), func(c *conn) error {
+		var err error
+		value, err = c.get(key, maxSize)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Delete removes a value from the cache
+func (memcachedProvider *MemcachedProvider) Delete(key string) error {
+	return memcachedProvider.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext removes a value from the cache. Deleting a missing key is not an error.
+func (memcachedProvider *MemcachedProvider) DeleteContext(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	return memcachedProvider.do(ctx, memcachedProvider.ring.server(key), func(c *conn) error {
+		return c.delete(key)
+	})
+}
+
+// Reset empties cache storage
+func (memcachedProvider *MemcachedProvider) Reset() error {
+	return memcachedProvider.ResetContext(context.Background())
+}
+
+// ResetContext empties cache storage, flushing every server
+func (memcachedProvider *MemcachedProvider) ResetContext(ctx context.Context) error {
+	for _, server := range memcachedProvider.Servers {
+		if err := memcachedProvider.do(ctx, server, (*conn).flushAll); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasKey checks if the key exists
+func (memcachedProvider *MemcachedProvider) HasKey(key string) bool {
+	return memcachedProvider.HasKeyContext(context.Background(), key)
+}
+
+// HasKeyContext checks if the key exists
+func (memcachedProvider *MemcachedProvider) HasKeyContext(ctx context.Context, key string) bool {
+	_, err := memcachedProvider.GetContext(ctx, key)
+	return err == nil
+}
@@ -0,0 +1,247 @@
+package memcached
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josemiguelmelo/gocacheable/providertest"
+	"github.com/stretchr/testify/assert"
+)
+
+var servers []*fakeServer
+
+func setup() {
+	for i := 0; i < 2; i++ {
+		server, err := newFakeServer()
+		if err != nil {
+			panic(err)
+		}
+		servers = append(servers, server)
+	}
+}
+
+func shutdown() {
+	for _, server := range servers {
+		server.Close()
+	}
+}
+
+func TestMain(m *testing.M) {
+	setup()
+	code := m.Run()
+	shutdown()
+	os.Exit(code)
+}
+
+func NewCacheableStorage(t *testing.T) *MemcachedProvider {
+	var addrs []string
+	for _, server := range servers {
+		server.Stall(false)
+		addrs = append(addrs, server.Addr())
+	}
+
+	memcachedProvider, err := NewMemcachedProvider(addrs, 2)
+	if err != nil {
+		return nil
+	}
+	assert.Nil(t, memcachedProvider.Reset())
+	t.Cleanup(func() { memcachedProvider.Close() })
+	return memcachedProvider
+}
+
+func TestMemcachedSetAndGet(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+
+	_, err := memcachedProvider.Get("key")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, false, memcachedProvider.HasKey("key"))
+
+	assert.Nil(t, memcachedProvider.Set("key", []byte("value\r\nwith separators")))
+	value, err := memcachedProvider.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "value\r\nwith separators", string(value))
+	assert.Equal(t, true, memcachedProvider.HasKey("key"))
+
+	assert.Nil(t, memcachedProvider.Set("empty", []byte{}))
+	value, err = memcachedProvider.Get("empty")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(value))
+}
+
+func TestMemcachedDeleteAndReset(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+
+	assert.Nil(t, memcachedProvider.Delete("key"))
+	assert.Nil(t, memcachedProvider.Set("key", []byte("value")))
+	assert.Nil(t, memcachedProvider.Delete("key"))
+	assert.Equal(t, false, memcachedProvider.HasKey("key"))
+
+	for i := 0; i < 20; i++ {
+		assert.Nil(t, memcachedProvider.Set(fmt.Sprintf("key%d", i), []byte("value")))
+	}
+	assert.Nil(t, memcachedProvider.Reset())
+	for _, server := range servers {
+		assert.Equal(t, 0, len(server.Keys()))
+	}
+}
+
+func TestMemcachedSpreadsKeysOverServers(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, memcachedProvider.Set(fmt.Sprintf("key%d", i), []byte("value")))
+	}
+	for _, server := range servers {
+		assert.True(t, len(server.Keys()) > 20)
+		for _, key := range server.Keys() {
+			assert.Equal(t, server.Addr(), memcachedProvider.ring.server(key))
+		}
+	}
+}
+
+func TestMemcachedTimeToLive(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+
+	assert.Nil(t, memcachedProvider.SetWithTTL("key", []byte("value"), 1500*time.Millisecond))
+	server := servers[0]
+	if memcachedProvider.ring.server("key") != server.Addr() {
+		server = servers[1]
+	}
+	assert.Equal(t, "set key 0 2 5", server.Commands()[len(server.Commands())-1])
+
+	server.FastForward(time.Second)
+	assert.Equal(t, true, memcachedProvider.HasKey("key"))
+	server.FastForward(time.Second)
+	assert.Equal(t, false, memcachedProvider.HasKey("key"))
+}
+
+func TestMemcachedLongTimeToLive(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+
+	assert.Nil(t, memcachedProvider.SetWithTTL("key", []byte("value"), 60*24*time.Hour))
+	assert.Equal(t, true, memcachedProvider.HasKey("key"))
+
+	exptime := expiration(60*24*time.Hour, time.Unix(1557833581, 0))
+	assert.Equal(t, int64(1557833581+60*24*3600), exptime)
+	assert.Equal(t, int64(0), expiration(0, time.Now()))
+	assert.Equal(t, int64(1), expiration(time.Millisecond, time.Now()))
+}
+
+func TestMemcachedInvalidKeys(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+
+	for _, key := range []string{"", "with space", "with\nnewline", strings.Repeat("k", 251)} {
+		assert.Equal(t, ErrInvalidKey, memcachedProvider.Set(key, []byte("value")))
+		_, err := memcachedProvider.Get(key)
+		assert.Equal(t, ErrInvalidKey, err)
+		assert.Equal(t, ErrInvalidKey, memcachedProvider.Delete(key))
+	}
+}
+
+func TestMemcachedMaxItemSize(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+	memcachedProvider.MaxItemSize = 10
+
+	assert.Equal(t, ErrValueTooLarge, memcachedProvider.Set("key", []byte("larger than ten bytes")))
+	assert.Nil(t, memcachedProvider.Set("key", []byte("ten bytes!")))
+
+	// Values stored by clients with a higher limit are not read
+	memcachedProvider.MaxItemSize = 0
+	assert.Nil(t, memcachedProvider.Set("key", []byte("larger than ten bytes")))
+	memcachedProvider.MaxItemSize = 10
+	_, err := memcachedProvider.Get("key")
+	assert.Equal(t, ErrValueTooLarge, err)
+
+	// The connection left with the unread value is not reused
+	assert.Nil(t, memcachedProvider.Delete("key"))
+	_, err = memcachedProvider.Get("key")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestMemcachedNoServers(t *testing.T) {
+	_, err := NewMemcachedProvider(nil, 2)
+	assert.Equal(t, ErrNoServers, err)
+}
+
+func TestMemcachedReusesConnections(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+
+	for i := 0; i < 10; i++ {
+		memcachedProvider.Get("key")
+	}
+	pool := memcachedProvider.pools[memcachedProvider.ring.server("key")]
+	assert.Equal(t, 1, len(pool.idle))
+}
+
+func TestMemcachedContextCanceled(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, memcachedProvider.InitContext(ctx))
+	assert.Equal(t, context.Canceled, memcachedProvider.SetContext(ctx, "key", []byte("value")))
+	_, err := memcachedProvider.GetContext(ctx, "key")
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, memcachedProvider.DeleteContext(ctx, "key"))
+	assert.Equal(t, context.Canceled, memcachedProvider.ResetContext(ctx))
+	assert.Equal(t, false, memcachedProvider.HasKeyContext(ctx, "key"))
+}
+
+func TestMemcachedContextInterruptsCommand(t *testing.T) {
+	memcachedProvider := NewCacheableStorage(t)
+	for _, server := range servers {
+		server.Stall(true)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := memcachedProvider.GetContext(ctx, "key")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = memcachedProvider.GetContext(ctx, "key")
+	assert.Equal(t, context.Canceled, err)
+
+	memcachedProvider.Timeout = 50 * time.Millisecond
+	_, err = memcachedProvider.Get("key")
+	assert.NotNil(t, err)
+
+	// Interrupted connections are not reused
+	pool := memcachedProvider.pools[memcachedProvider.ring.server("key")]
+	assert.Equal(t, 0, len(pool.idle))
+}
+
+func TestRingMovesFewKeysWhenAddingServer(t *testing.T) {
+	before := newRing([]string{"a:11211", "b:11211", "c:11211"}, defaultVirtualNodes)
+	after := newRing([]string{"a:11211", "b:11211", "c:11211", "d:11211"}, defaultVirtualNodes)
+
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if before.server(key) != after.server(key) {
+			assert.Equal(t, "d:11211", after.server(key))
+			moved++
+		}
+	}
+	// About a quarter of the keys move to the new server
+	assert.True(t, moved > 1500 && moved < 3500)
+}
+
+func TestMemcachedConformance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) providertest.Fixture {
+		return providertest.Fixture{
+			Provider: NewCacheableStorage(t),
+			Advance: func(d time.Duration) {
+				for _, server := range servers {
+					server.FastForward(d)
+				}
+			},
+			TTLResolution: time.Second,
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package memcached
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// conn is a connection to a memcached server
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// pool keeps idle connections to a server for reuse
+type pool struct {
+	addr    string
+	maxIdle int
+	timeout time.Duration
+
+	mu   sync.Mutex
+	idle []*conn
+}
+
+func newPool(addr string, maxIdle int, timeout time.Duration) *pool {
+	return &pool{addr: addr, maxIdle: maxIdle, timeout: timeout}
+}
+
+// get returns an idle connection, or dials a new one
+func (p *pool) get(ctx context.Context) (*conn, error) {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	dialer := net.Dialer{Timeout: p.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: netConn, reader: bufio.NewReader(netConn), writer: bufio.NewWriter(netConn)}, nil
+}
+
+// put returns a healthy connection to the pool, closing it if the pool is full
+func (p *pool) put(c *conn) {
+	p.mu.Lock()
+	if len(p.idle) < p.maxIdle {
+		p.idle = append(p.idle, c)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	c.Close()
+}
+
+// close closes every idle connection
+func (p *pool) close() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, c := range idle {
+		c.Close()
+	}
+}
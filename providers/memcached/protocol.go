@@ -0,0 +1,181 @@
+package memcached
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxKeyLength is the longest key accepted by memcached
+	maxKeyLength = 250
+	// maxRelativeExpiration is the longest expiration memcached reads as a
+	// number of seconds, longer ones must be sent as a unix timestamp
+	maxRelativeExpiration = 30 * 24 * time.Hour
+)
+
+var (
+	replyStored   = []byte("STORED\r\n")
+	replyDeleted  = []byte("DELETED\r\n")
+	replyNotFound = []byte("NOT_FOUND\r\n")
+	replyOK       = []byte("OK\r\n")
+	replyEnd      = []byte("END\r\n")
+	prefixValue   = []byte("VALUE ")
+	prefixVersion = []byte("VERSION ")
+)
+
+// validKey checks a key is accepted by the text protocol: at most 250 bytes,
+// without whitespace or control characters
+func validKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// expiration returns the exptime of an entry living for ttl. Zero means no
+// expiry, and durations over 30 days are sent as an absolute unix timestamp.
+func expiration(ttl time.Duration, now time.Time) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ttl > maxRelativeExpiration {
+		return now.Add(ttl).Unix()
+	}
+	// memcached has a second resolution, round up so entries never expire early
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+// readLine reads a reply line, turning error replies into errors
+func (c *conn) readLine() ([]byte, error) {
+	line, err := c.reader.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.Equal(line, []byte("ERROR\r\n")):
+		return nil, ErrUnknownCommand
+	case bytes.HasPrefix(line, []byte("CLIENT_ERROR ")), bytes.HasPrefix(line, []byte("SERVER_ERROR ")):
+		return nil, &ServerError{Message: strings.TrimSpace(string(line))}
+	}
+	return line, nil
+}
+
+// expect reads a reply line and checks it is one of replies
+func (c *conn) expect(replies ...[]byte) ([]byte, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		if bytes.Equal(line, reply) {
+			return reply, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnexpectedReply, line)
+}
+
+func (c *conn) set(key string, value []byte, exptime int64) error {
+	fmt.Fprintf(c.writer, "set %s 0 %d %d\r\n", key, exptime, len(value))
+	c.writer.Write(value)
+	c.writer.WriteString("\r\n")
+	if err := c.writer.Flush(); err != nil {
+		return err
+	}
+	_, err := c.expect(replyStored)
+	return err
+}
+
+// get returns the value of key, or ErrNotFound. Values announced larger than
+// maxSize fail with ErrValueTooLarge, leaving the connection unusable.
+func (c *conn) get(key string, maxSize int) ([]byte, error) {
+	fmt.Fprintf(c.writer, "get %s\r\n", key)
+	if err := c.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(line, replyEnd) {
+		return nil, ErrNotFound
+	}
+
+	// VALUE <key> <flags> <bytes>
+	fields := strings.Fields(string(line))
+	if !bytes.HasPrefix(line, prefixValue) || len(fields) < 4 || fields[1] != key {
+		return nil, fmt.Errorf("%w: %q", ErrUnexpectedReply, line)
+	}
+	size, err := strconv.Atoi(fields[3])
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnexpectedReply, line)
+	}
+	if size > maxSize {
+		return nil, ErrValueTooLarge
+	}
+
+	value := make([]byte, size+2)
+	if _, err = io.ReadFull(c.reader, value); err != nil {
+		return nil, err
+	}
+	if !bytes.HasSuffix(value, []byte("\r\n")) {
+		return nil, fmt.Errorf("%w: value not terminated", ErrUnexpectedReply)
+	}
+	if _, err = c.expect(replyEnd); err != nil {
+		return nil, err
+	}
+	return value[:size], nil
+}
+
+// delete removes key, a missing key is not an error
+func (c *conn) delete(key string) error {
+	fmt.Fprintf(c.writer, "delete %s\r\n", key)
+	if err := c.writer.Flush(); err != nil {
+		return err
+	}
+	_, err := c.expect(replyDeleted, replyNotFound)
+	return err
+}
+
+func (c *conn) flushAll() error {
+	c.writer.WriteString("flush_all\r\n")
+	if err := c.writer.Flush(); err != nil {
+		return err
+	}
+	_, err := c.expect(replyOK)
+	return err
+}
+
+func (c *conn) version() error {
+	c.writer.WriteString("version\r\n")
+	if err := c.writer.Flush(); err != nil {
+		return err
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(line, prefixVersion) {
+		return fmt.Errorf("%w: %q", ErrUnexpectedReply, line)
+	}
+	return nil
+}
+
+// isReplyError returns true for errors reported by the server in a well formed
+// reply, after which the connection can still be used
+func isReplyError(err error) bool {
+	var serverError *ServerError
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnknownCommand) || errors.As(err, &serverError)
+}
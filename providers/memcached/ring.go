@@ -0,0 +1,43 @@
+package memcached
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// defaultVirtualNodes is the number of points of every server on the ring
+const defaultVirtualNodes = 160
+
+// ring maps keys to servers by consistent hashing, so that adding or removing
+// a server only moves the keys of that server
+type ring struct {
+	points  []uint32
+	servers map[uint32]string
+}
+
+func newRing(servers []string, virtualNodes int) *ring {
+	r := &ring{servers: map[uint32]string{}}
+	for _, server := range servers {
+		for i := 0; i < virtualNodes; i++ {
+			point := crc32.ChecksumIEEE([]byte(server + "-" + strconv.Itoa(i)))
+			if _, ok := r.servers[point]; ok {
+				continue
+			}
+			r.servers[point] = server
+			r.points = append(r.points, point)
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// server returns the server owning key, the first one clockwise from its hash
+func (r *ring) server(key string) string {
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= hash })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.servers[r.points[i]]
+}
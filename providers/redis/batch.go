@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ACTION_MGET redis multiple keys get action
+const ACTION_MGET = "MGET"
+
+// GetMany returns the values of the keys found
+func (redisProvider *RedisProvider) GetMany(keys []string) (map[string][]byte, error) {
+	return redisProvider.GetManyContext(context.Background(), keys)
+}
+
+// GetManyContext returns the values of the keys found with a single MGET, or
+// one per hash slot in cluster mode
+func (redisProvider *RedisProvider) GetManyContext(ctx context.Context, keys []string) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	values := map[string][]byte{}
+	for _, group := range redisProvider.slotGroups(keys) {
+		prefixed := redisProvider.prefixKeys(group)
+		replies, err := redis.ByteSlices(redisProvider.router.do(ctx, prefixed[0], ACTION_MGET, redis.Args{}.AddFlat(prefixed)...))
+		if err != nil {
+			return nil, err
+		}
+		for i, value := range replies {
+			if value != nil {
+				values[group[i]] = value
+			}
+		}
+	}
+	return values, nil
+}
+
+// SetMany stores values, expiring after ttl
+func (redisProvider *RedisProvider) SetMany(values map[string][]byte, ttl time.Duration) error {
+	return redisProvider.SetManyContext(context.Background(), values, ttl)
+}
+
+// SetManyContext stores values, expiring after ttl, with pipelined SET
+// commands on a single connection, or one per hash slot in cluster mode
+func (redisProvider *RedisProvider) SetManyContext(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	for _, group := range redisProvider.slotGroups(keys) {
+		prefixed := redisProvider.prefixKeys(group)
+		commands := make([]command, len(group))
+		for i, key := range group {
+			args := []interface{}{prefixed[i], values[key]}
+			if ttl > 0 {
+				args = append(args, OPTION_EXPIRE_MILLISECONDS, ttlMilliseconds(ttl))
+			}
+			commands[i] = command{name: ACTION_SET, args: args}
+		}
+
+		replies, err := redisProvider.router.pipeline(ctx, prefixed[0], commands)
+		if err != nil {
+			return err
+		}
+		for _, reply := range replies {
+			if replyErr, ok := reply.(redis.Error); ok {
+				return replyErr
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteMany removes keys
+func (redisProvider *RedisProvider) DeleteMany(keys []string) error {
+	return redisProvider.DeleteManyContext(context.Background(), keys)
+}
+
+// DeleteManyContext removes keys with a single DEL, or one per hash slot in
+// cluster mode
+func (redisProvider *RedisProvider) DeleteManyContext(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, group := range redisProvider.slotGroups(keys) {
+		prefixed := redisProvider.prefixKeys(group)
+		if _, err := redisProvider.router.do(ctx, prefixed[0], ACTION_DELETE, redis.Args{}.AddFlat(prefixed)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prefixKeys returns keys prefixed by the namespace
+func (redisProvider *RedisProvider) prefixKeys(keys []string) []string {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = redisProvider.Namespace + key
+	}
+	return prefixed
+}
+
+// slotGroups splits keys into the groups a multiple keys command accepts: all
+// of them, or the keys of every hash slot in cluster mode
+func (redisProvider *RedisProvider) slotGroups(keys []string) [][]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	if _, cluster := redisProvider.router.(*clusterRouter); !cluster {
+		return [][]string{keys}
+	}
+
+	var groups [][]string
+	index := map[int]int{}
+	for _, key := range keys {
+		slot := keySlot(redisProvider.Namespace + key)
+		i, ok := index[slot]
+		if !ok {
+			i = len(groups)
+			index[slot] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
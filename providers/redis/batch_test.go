@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetManySetManyDeleteMany(t *testing.T) {
+	server := miniredis.RunT(t)
+	namespacedProvider := newNamespacedProvider(t, server.Addr(), "manager:module:")
+
+	err := namespacedProvider.SetMany(map[string][]byte{
+		"a": []byte("value a"),
+		"b": []byte("value b"),
+	}, time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, namespacedProvider.SetMany(map[string][]byte{"c": []byte("value c")}, 0))
+
+	stored, err := server.Get("manager:module:a")
+	assert.Nil(t, err)
+	assert.Equal(t, "value a", stored)
+	assert.Equal(t, time.Minute, server.TTL("manager:module:b"))
+	assert.Equal(t, time.Duration(0), server.TTL("manager:module:c"))
+
+	// Every key is read with a single command
+	commands := server.CommandCount()
+	values, err := namespacedProvider.GetMany([]string{"a", "missing", "c"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, server.CommandCount()-commands)
+	assert.Equal(t, map[string][]byte{"a": []byte("value a"), "c": []byte("value c")}, values)
+
+	assert.Nil(t, namespacedProvider.DeleteMany([]string{"a", "missing", "b"}))
+	assert.False(t, server.Exists("manager:module:a"))
+	assert.False(t, server.Exists("manager:module:b"))
+	assert.True(t, server.Exists("manager:module:c"))
+
+	values, err = namespacedProvider.GetMany(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(values))
+	assert.Nil(t, namespacedProvider.SetMany(nil, time.Minute))
+	assert.Nil(t, namespacedProvider.DeleteMany(nil))
+}
+
+func TestSetManyReturnsReplyError(t *testing.T) {
+	server := miniredis.RunT(t)
+	batchProvider := newNamespacedProvider(t, server.Addr(), "")
+
+	server.SetError("ERR server failure")
+	err := batchProvider.SetMany(map[string][]byte{"a": []byte("value a")}, time.Minute)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "server failure")
+}
+
+func TestBatchContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := redisProvider.GetManyContext(ctx, []string{existingKey})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, redisProvider.SetManyContext(ctx, map[string][]byte{existingKey: []byte(existingKeyValue)}, 0))
+	assert.Equal(t, context.Canceled, redisProvider.DeleteManyContext(ctx, []string{existingKey}))
+}
+
+func TestClusterBatchGroupsKeysBySlot(t *testing.T) {
+	cluster, clusterProvider := newClusterSetup(t)
+
+	values := map[string][]byte{}
+	keys := []string{}
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("key%d", i)
+		values[key] = []byte(key)
+		keys = append(keys, key)
+	}
+	assert.Nil(t, clusterProvider.SetMany(values, time.Minute))
+	for _, key := range keys {
+		stored, ok := cluster.Owner(key).Value(key)
+		assert.True(t, ok)
+		assert.Equal(t, key, stored)
+	}
+
+	found, err := clusterProvider.GetMany(append(keys, "missing"))
+	assert.Nil(t, err)
+	assert.Equal(t, values, found)
+
+	assert.Nil(t, clusterProvider.DeleteMany(keys))
+	for _, node := range cluster.nodes {
+		assert.Equal(t, 0, node.Len())
+	}
+}
+
+func TestSlotGroups(t *testing.T) {
+	assert.Nil(t, redisProvider.slotGroups(nil))
+	assert.Equal(t, [][]string{{"a", "b", "c"}}, redisProvider.slotGroups([]string{"a", "b", "c"}))
+
+	_, clusterProvider := newClusterSetup(t)
+	groups := clusterProvider.slotGroups([]string{"{user}a", "other", "{user}b"})
+	assert.Equal(t, [][]string{{"{user}a", "{user}b"}, {"other"}}, groups)
+}
+
+func TestSentinelSetManyFollowsFailover(t *testing.T) {
+	primary, replica, sentinel := newSentinelSetup(t)
+
+	sentinelProvider, err := NewRedisSentinelProvider([]string{sentinel.Addr()}, "mymaster", 10, 100)
+	assert.Nil(t, err)
+	defer sentinelProvider.Close()
+	assert.Nil(t, sentinelProvider.SetMany(map[string][]byte{"a": []byte("value a")}, time.Minute))
+
+	primary.SetRole("slave")
+	replica.SetRole("master")
+	sentinel.SetPrimaryAddr(replica.Addr())
+
+	assert.Nil(t, sentinelProvider.SetMany(map[string][]byte{"a": []byte("updated"), "b": []byte("value b")}, time.Minute))
+	values, err := sentinelProvider.GetMany([]string{"a", "b"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]byte{"a": []byte("updated"), "b": []byte("value b")}, values)
+}
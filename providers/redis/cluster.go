@@ -0,0 +1,361 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// ACTION_CLUSTER redis cluster command
+	ACTION_CLUSTER = "CLUSTER"
+	// ACTION_ASKING redis command allowing the next command on a slot being imported
+	ACTION_ASKING = "ASKING"
+)
+
+const (
+	// clusterSlots is the number of hash slots of a redis cluster
+	clusterSlots = 16384
+	// maxRedirects bounds the MOVED and ASK redirections followed by a command
+	maxRedirects = 5
+	// clusterRefreshTimeout bounds a background reload of the slot map, so
+	// that an unresponsive node does not block the next ones
+	clusterRefreshTimeout = 5 * time.Second
+)
+
+// ErrNoClusterNode is returned when no cluster node could describe the slots
+var ErrNoClusterNode = errors.New("No redis cluster node is reachable")
+
+// clusterRouter sends commands to the cluster node serving the hash slot of
+// their key. The slot map is loaded with CLUSTER SLOTS and reloaded when a
+// node answers MOVED, while ASK redirections are followed for a single command.
+type clusterRouter struct {
+	seeds   []string
+	newPool func(addr string) *redis.Pool
+	// refreshing is set while a background refresh is in flight
+	refreshing atomic.Bool
+
+	mu          sync.RWMutex
+	slots       [clusterSlots]string
+	primaryList []string
+	pools       map[string]*redis.Pool
+}
+
+func newClusterRouter(seeds []string, newPool func(addr string) *redis.Pool) *clusterRouter {
+	return &clusterRouter{
+		seeds:   seeds,
+		newPool: newPool,
+		pools:   map[string]*redis.Pool{},
+	}
+}
+
+// pool returns the pool of addr, creating it if needed
+func (r *clusterRouter) pool(addr string) *redis.Pool {
+	r.mu.RLock()
+	pool, ok := r.pools[addr]
+	r.mu.RUnlock()
+	if ok {
+		return pool
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pool, ok = r.pools[addr]; !ok {
+		pool = r.newPool(addr)
+		r.pools[addr] = pool
+	}
+	return pool
+}
+
+// refresh reloads the slot map from the first node answering CLUSTER SLOTS,
+// trying the known nodes before the seeds
+func (r *clusterRouter) refresh(ctx context.Context) error {
+	r.mu.RLock()
+	addrs := append([]string{}, r.primaryList...)
+	r.mu.RUnlock()
+	addrs = append(addrs, r.seeds...)
+
+	err := ErrNoClusterNode
+	for _, addr := range addrs {
+		var value interface{}
+		if value, err = doPool(ctx, r.pool(addr), ACTION_CLUSTER, "SLOTS"); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			continue
+		}
+
+		var slots [clusterSlots]string
+		var primaries []string
+		if primaries, err = parseClusterSlots(value, addr, &slots); err != nil {
+			continue
+		}
+
+		r.mu.Lock()
+		r.slots = slots
+		r.primaryList = primaries
+		r.mu.Unlock()
+		return nil
+	}
+	return err
+}
+
+// refreshInBackground reloads the slot map in background, unless a background
+// reload is already in flight, so that a burst of MOVED replies triggers a
+// single one
+func (r *clusterRouter) refreshInBackground(ctx context.Context) {
+	if !r.refreshing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer r.refreshing.Store(false)
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), clusterRefreshTimeout)
+		defer cancel()
+		r.refresh(ctx)
+	}()
+}
+
+// parseClusterSlots fills slots with the primary address of every slot range
+// of a CLUSTER SLOTS reply, and returns the primary addresses. Nodes announced
+// without host are reached on the host of addr, the node that replied.
+func parseClusterSlots(value interface{}, addr string, slots *[clusterSlots]string) ([]string, error) {
+	ranges, err := redis.Values(value, nil)
+	if err != nil {
+		return nil, err
+	}
+	replyHost, _, _ := net.SplitHostPort(addr)
+
+	var primaries []string
+	seen := map[string]bool{}
+	for _, slotRange := range ranges {
+		// start, end, primary [host, port, ...], replicas...
+		fields, err := redis.Values(slotRange, nil)
+		if err != nil || len(fields) < 3 {
+			return nil, errors.New("Invalid CLUSTER SLOTS reply")
+		}
+		start, err1 := redis.Int(fields[0], nil)
+		end, err2 := redis.Int(fields[1], nil)
+		node, err3 := redis.Values(fields[2], nil)
+		if err1 != nil || err2 != nil || err3 != nil || len(node) < 2 || start < 0 || end >= clusterSlots || start > end {
+			return nil, errors.New("Invalid CLUSTER SLOTS reply")
+		}
+		host, err1 := redis.String(node[0], nil)
+		port, err2 := redis.Int(node[1], nil)
+		if err1 != nil || err2 != nil {
+			return nil, errors.New("Invalid CLUSTER SLOTS reply")
+		}
+		if host == "" {
+			host = replyHost
+		}
+
+		primary := net.JoinHostPort(host, strconv.Itoa(port))
+		for slot := start; slot <= end; slot++ {
+			slots[slot] = primary
+		}
+		if !seen[primary] {
+			seen[primary] = true
+			primaries = append(primaries, primary)
+		}
+	}
+	return primaries, nil
+}
+
+// slotAddr returns the address of the node serving slot, loading the slot map if needed
+func (r *clusterRouter) slotAddr(ctx context.Context, slot int) (string, error) {
+	r.mu.RLock()
+	addr := r.slots[slot]
+	r.mu.RUnlock()
+	if addr != "" {
+		return addr, nil
+	}
+
+	if err := r.refresh(ctx); err != nil {
+		return "", err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.slots[slot] == "" {
+		return "", ErrNoClusterNode
+	}
+	return r.slots[slot], nil
+}
+
+func (r *clusterRouter) do(ctx context.Context, key string, commandName string, args ...interface{}) (interface{}, error) {
+	slot := keySlot(key)
+	addr, err := r.slotAddr(ctx, slot)
+	if err != nil {
+		return nil, err
+	}
+
+	asking := false
+	refreshed := false
+	for redirects := 0; ; redirects++ {
+		var value interface{}
+		if asking {
+			value, err = doAsking(ctx, r.pool(addr), commandName, args...)
+		} else {
+			value, err = doPool(ctx, r.pool(addr), commandName, args...)
+		}
+		if err == nil || ctx.Err() != nil || redirects == maxRedirects {
+			return value, err
+		}
+
+		var redisErr redis.Error
+		if !errors.As(err, &redisErr) {
+			// The node may have failed, reload the slot map once to find its replacement
+			if refreshed || r.refresh(ctx) != nil {
+				return value, err
+			}
+			refreshed = true
+			if addr, err = r.slotAddr(ctx, slot); err != nil {
+				return nil, err
+			}
+			asking = false
+			continue
+		}
+
+		kind, redirectAddr, ok := parseRedirect(string(redisErr))
+		if !ok {
+			return value, err
+		}
+		addr = redirectAddr
+		asking = kind == "ASK"
+		if kind == "MOVED" {
+			// The slot changed owner, others probably did as well
+			r.mu.Lock()
+			r.slots[slot] = addr
+			r.mu.Unlock()
+			r.refreshInBackground(ctx)
+		}
+	}
+}
+
+// pipeline sends commands to the node serving the slot of key. When the slot
+// moved, the commands are run one by one so that every redirection is followed.
+func (r *clusterRouter) pipeline(ctx context.Context, key string, commands []command) ([]interface{}, error) {
+	addr, err := r.slotAddr(ctx, keySlot(key))
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := pipelinePool(ctx, r.pool(addr), commands)
+	if err != nil {
+		return nil, err
+	}
+	for _, reply := range replies {
+		if replyErr, ok := reply.(redis.Error); ok {
+			if _, _, redirected := parseRedirect(string(replyErr)); redirected {
+				return r.doEach(ctx, key, commands)
+			}
+		}
+	}
+	return replies, nil
+}
+
+// doEach runs commands one by one, returning their replies like pipeline
+func (r *clusterRouter) doEach(ctx context.Context, key string, commands []command) ([]interface{}, error) {
+	replies := make([]interface{}, len(commands))
+	for i, c := range commands {
+		value, err := r.do(ctx, key, c.name, c.args...)
+		if redisErr, ok := err.(redis.Error); ok {
+			value = redisErr
+		} else if err != nil {
+			return nil, err
+		}
+		replies[i] = value
+	}
+	return replies, nil
+}
+
+// doAsking runs ASKING followed by a command on the same connection of pool,
+// as required to reach a slot being migrated to the node
+func doAsking(ctx context.Context, pool *redis.Pool, commandName string, args ...interface{}) (interface{}, error) {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := redis.DoContext(conn, ctx, ACTION_ASKING); err != nil {
+		return nil, err
+	}
+	return redis.DoContext(conn, ctx, commandName, args...)
+}
+
+// parseRedirect parses a "MOVED <slot> <addr>" or "ASK <slot> <addr>" error
+func parseRedirect(message string) (string, string, bool) {
+	fields := strings.Fields(message)
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return "", "", false
+	}
+	return fields[0], fields[2], true
+}
+
+func (r *clusterRouter) primaries(ctx context.Context) ([]*redis.Pool, error) {
+	r.mu.RLock()
+	addrs := r.primaryList
+	r.mu.RUnlock()
+
+	if len(addrs) == 0 {
+		if err := r.refresh(ctx); err != nil {
+			return nil, err
+		}
+		r.mu.RLock()
+		addrs = r.primaryList
+		r.mu.RUnlock()
+	}
+
+	pools := make([]*redis.Pool, 0, len(addrs))
+	for _, addr := range addrs {
+		pools = append(pools, r.pool(addr))
+	}
+	return pools, nil
+}
+
+func (r *clusterRouter) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var err error
+	for _, pool := range r.pools {
+		if closeErr := pool.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// keySlot returns the hash slot of key. When the key contains a non empty
+// hash tag, such as "{user1000}.following", only the tag is hashed so that
+// related keys share a slot.
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by redis cluster
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,160 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func newClusterSetup(t *testing.T) (*fakeCluster, *RedisProvider) {
+	cluster, err := newFakeCluster(3)
+	assert.Nil(t, err)
+	t.Cleanup(cluster.Close)
+
+	clusterProvider, err := NewRedisClusterProvider([]string{"127.0.0.1:1", cluster.nodes[0].Addr()}, 10, 100)
+	assert.Nil(t, err)
+	t.Cleanup(func() { clusterProvider.Close() })
+	return cluster, clusterProvider
+}
+
+func TestClusterRoutesKeysToSlotOwner(t *testing.T) {
+	cluster, clusterProvider := newClusterSetup(t)
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		assert.Nil(t, clusterProvider.Set(key, []byte(existingKeyValue)))
+
+		stored, ok := cluster.Owner(key).Value(key)
+		assert.True(t, ok)
+		assert.Equal(t, existingKeyValue, stored)
+
+		value, err := clusterProvider.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, existingKeyValue, string(value))
+	}
+	for _, node := range cluster.nodes {
+		assert.True(t, node.Len() > 0)
+		assert.NotContains(t, node.Commands(), "ASKING")
+	}
+
+	assert.Nil(t, clusterProvider.Delete("key0"))
+	assert.Equal(t, false, clusterProvider.HasKey("key0"))
+
+	assert.Nil(t, clusterProvider.Reset())
+	for _, node := range cluster.nodes {
+		assert.Equal(t, 0, node.Len())
+	}
+}
+
+func TestClusterFollowsMoved(t *testing.T) {
+	cluster, clusterProvider := newClusterSetup(t)
+	assert.Nil(t, clusterProvider.Set(existingKey, []byte(existingKeyValue)))
+
+	// Move the slot of the key to another node
+	owner := cluster.Owner(existingKey)
+	newOwner := cluster.nodes[0]
+	if owner == newOwner {
+		newOwner = cluster.nodes[1]
+	}
+	cluster.MoveSlot(keySlot(existingKey), newOwner)
+
+	assert.Nil(t, clusterProvider.Set(existingKey, []byte("updated")))
+	stored, _ := newOwner.Value(existingKey)
+	assert.Equal(t, "updated", stored)
+
+	// The slot map was updated, the old owner is not asked anymore
+	commands := len(owner.Commands())
+	_, err := clusterProvider.Get(existingKey)
+	assert.Nil(t, err)
+	assert.Equal(t, commands, len(owner.Commands()))
+}
+
+func TestClusterCoalescesBackgroundRefreshes(t *testing.T) {
+	cluster, err := newFakeCluster(1)
+	assert.Nil(t, err)
+	defer cluster.Close()
+	node := cluster.nodes[0]
+
+	router := newClusterRouter([]string{node.Addr()}, func(addr string) *redis.Pool {
+		return newRedisPool(addr, 10, 100)
+	})
+	defer router.close()
+
+	// While a refresh is in flight, MOVED replies do not start another one
+	router.refreshing.Store(true)
+	for i := 0; i < 10; i++ {
+		router.refreshInBackground(context.Background())
+	}
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, countCommand(node.Commands(), "CLUSTER"))
+
+	router.refreshing.Store(false)
+	router.refreshInBackground(context.Background())
+	assert.Eventually(t, func() bool { return !router.refreshing.Load() }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, countCommand(node.Commands(), "CLUSTER"))
+}
+
+func TestClusterFollowsAsk(t *testing.T) {
+	cluster, clusterProvider := newClusterSetup(t)
+
+	owner := cluster.Owner(existingKey)
+	target := cluster.nodes[0]
+	if owner == target {
+		target = cluster.nodes[1]
+	}
+
+	// The key was already migrated, while the slot still belongs to its owner
+	cluster.Migrate(keySlot(existingKey), target)
+	target.mu.Lock()
+	target.data[existingKey] = existingKeyValue
+	target.mu.Unlock()
+
+	value, err := clusterProvider.Get(existingKey)
+	assert.Nil(t, err)
+	assert.Equal(t, existingKeyValue, string(value))
+	assert.Contains(t, target.Commands(), "ASKING")
+
+	// ASK only redirects a single command
+	_, err = clusterProvider.Get(existingKey)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, countCommand(owner.Commands(), "GET"))
+}
+
+func TestClusterContextCanceled(t *testing.T) {
+	_, clusterProvider := newClusterSetup(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, clusterProvider.SetContext(ctx, existingKey, []byte(existingKeyValue)))
+	assert.Equal(t, context.Canceled, clusterProvider.ResetContext(ctx))
+	assert.Equal(t, context.Canceled, clusterProvider.PingContext(ctx))
+}
+
+func TestClusterNoReachableNode(t *testing.T) {
+	_, err := NewRedisClusterProvider([]string{"127.0.0.1:1"}, 10, 100)
+	assert.NotNil(t, err)
+}
+
+func TestKeySlot(t *testing.T) {
+	assert.Equal(t, 12739, keySlot("123456789"))
+	assert.Equal(t, 12182, keySlot("foo"))
+	assert.Equal(t, keySlot("user1000"), keySlot("{user1000}.following"))
+	assert.Equal(t, keySlot("{user1000}.followers"), keySlot("{user1000}.following"))
+	// Empty hash tags are ignored, and only the first tag counts
+	assert.Equal(t, int(crc16("foo{}{bar}")%clusterSlots), keySlot("foo{}{bar}"))
+	assert.Equal(t, keySlot("{bar"), keySlot("foo{{bar}}zap"))
+}
+
+func countCommand(commands []string, name string) int {
+	count := 0
+	for _, command := range commands {
+		if command == name {
+			count++
+		}
+	}
+	return count
+}
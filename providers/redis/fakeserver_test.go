@@ -0,0 +1,368 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// respStatus is a simple string reply
+type respStatus string
+
+// respError is an error reply
+type respError string
+
+// fakeNode is an in process redis server implementing the commands used by
+// the provider, with the replication, sentinel and cluster behaviours needed
+// to test failovers and redirections
+type fakeNode struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	data     map[string]string
+	role     string
+	commands []string
+	// primaryAddr is returned to SENTINEL get-master-addr-by-name when set
+	primaryAddr string
+	// cluster is the cluster the node belongs to, if any
+	cluster *fakeCluster
+}
+
+func newFakeNode() (*fakeNode, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	node := &fakeNode{listener: listener, conns: map[net.Conn]bool{}, data: map[string]string{}, role: "master"}
+	go node.serve()
+	return node, nil
+}
+
+func (n *fakeNode) Addr() string {
+	return n.listener.Addr().String()
+}
+
+// Close stops the node, closing its connections
+func (n *fakeNode) Close() {
+	n.listener.Close()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for conn := range n.conns {
+		conn.Close()
+	}
+}
+
+func (n *fakeNode) SetRole(role string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.role = role
+}
+
+func (n *fakeNode) SetPrimaryAddr(addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.primaryAddr = addr
+}
+
+func (n *fakeNode) Value(key string) (string, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	value, ok := n.data[key]
+	return value, ok
+}
+
+func (n *fakeNode) Len() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.data)
+}
+
+// Commands returns the names of the commands received so far
+func (n *fakeNode) Commands() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string{}, n.commands...)
+}
+
+func (n *fakeNode) serve() {
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		n.mu.Lock()
+		n.conns[conn] = true
+		n.mu.Unlock()
+		go n.handle(conn)
+	}
+}
+
+func (n *fakeNode) handle(conn net.Conn) {
+	defer func() {
+		n.mu.Lock()
+		delete(n.conns, conn)
+		n.mu.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	asking := false
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		var reply interface{}
+		reply, asking = n.execute(args, asking)
+		writeReply(writer, reply)
+		if writer.Flush() != nil {
+			return
+		}
+	}
+}
+
+// execute runs a command, asking telling whether ASKING was sent just before
+func (n *fakeNode) execute(args []string, asking bool) (interface{}, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	command := strings.ToUpper(args[0])
+	n.commands = append(n.commands, command)
+
+	switch command {
+	case "PING":
+		return respStatus("PONG"), false
+	case "ASKING":
+		return respStatus("OK"), true
+	case "ROLE":
+		return []interface{}{n.role, int64(0), []interface{}{}}, false
+	case "FLUSHDB":
+		n.data = map[string]string{}
+		return respStatus("OK"), false
+	case "SENTINEL":
+		if n.primaryAddr == "" {
+			return nil, false
+		}
+		host, port, _ := net.SplitHostPort(n.primaryAddr)
+		return []interface{}{host, port}, false
+	case "CLUSTER":
+		return n.cluster.slotsReply(), false
+	case "SCAN":
+		// Only prefix patterns are supported, and every key is returned at once
+		prefix := strings.TrimSuffix(args[3], "*")
+		prefix = strings.NewReplacer(`\*`, "*", `\?`, "?", `\[`, "[", `\]`, "]", `\\`, `\`).Replace(prefix)
+		keys := []interface{}{}
+		for key := range n.data {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+		return []interface{}{"0", keys}, false
+	}
+
+	if len(args) < 2 {
+		return respError("ERR wrong number of arguments"), false
+	}
+	key := args[1]
+	if n.cluster != nil {
+		if command == "MGET" || command == "DEL" {
+			for _, other := range args[2:] {
+				if keySlot(other) != keySlot(key) {
+					return respError("CROSSSLOT Keys in request don't hash to the same slot"), false
+				}
+			}
+		}
+		if redirect := n.cluster.redirect(n, key, asking); redirect != "" {
+			return respError(redirect), false
+		}
+	}
+
+	switch command {
+	case "GET":
+		value, ok := n.data[key]
+		if !ok {
+			return nil, false
+		}
+		return value, false
+	case "MGET":
+		values := make([]interface{}, len(args)-1)
+		for i, key := range args[1:] {
+			if value, ok := n.data[key]; ok {
+				values[i] = value
+			}
+		}
+		return values, false
+	case "SET":
+		if n.role != "master" {
+			return respError("READONLY You can't write against a read only replica."), false
+		}
+		n.data[key] = args[2]
+		return respStatus("OK"), false
+	case "DEL":
+		deleted := int64(0)
+		for _, key := range args[1:] {
+			if _, ok := n.data[key]; ok {
+				delete(n.data, key)
+				deleted++
+			}
+		}
+		return deleted, false
+	}
+	return respError("ERR unknown command '" + args[0] + "'"), false
+}
+
+// fakeCluster assigns the hash slots to nodes
+type fakeCluster struct {
+	nodes []*fakeNode
+
+	mu    sync.Mutex
+	slots [clusterSlots]*fakeNode
+	// migrating are the slots moving to another node, which answers ASK for
+	// the keys already moved
+	migrating map[int]*fakeNode
+}
+
+func newFakeCluster(size int) (*fakeCluster, error) {
+	cluster := &fakeCluster{migrating: map[int]*fakeNode{}}
+	for i := 0; i < size; i++ {
+		node, err := newFakeNode()
+		if err != nil {
+			return nil, err
+		}
+		node.cluster = cluster
+		cluster.nodes = append(cluster.nodes, node)
+	}
+
+	for slot := range cluster.slots {
+		cluster.slots[slot] = cluster.nodes[slot*size/clusterSlots]
+	}
+	return cluster, nil
+}
+
+func (c *fakeCluster) Close() {
+	for _, node := range c.nodes {
+		node.Close()
+	}
+}
+
+// Owner returns the node serving key
+func (c *fakeCluster) Owner(key string) *fakeNode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slots[keySlot(key)]
+}
+
+// MoveSlot makes node serve slot
+func (c *fakeCluster) MoveSlot(slot int, node *fakeNode) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slots[slot] = node
+}
+
+// Migrate starts moving slot to node
+func (c *fakeCluster) Migrate(slot int, node *fakeNode) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.migrating[slot] = node
+}
+
+// redirect returns the MOVED or ASK error node must answer for key, if any.
+// Must be called with the node mutex held.
+func (c *fakeCluster) redirect(node *fakeNode, key string, asking bool) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	slot := keySlot(key)
+	owner := c.slots[slot]
+	target := c.migrating[slot]
+	if owner == node {
+		if _, ok := node.data[key]; !ok && target != nil {
+			return fmt.Sprintf("ASK %d %s", slot, target.Addr())
+		}
+		return ""
+	}
+	if target == node && asking {
+		return ""
+	}
+	return fmt.Sprintf("MOVED %d %s", slot, owner.Addr())
+}
+
+func (c *fakeCluster) slotsReply() interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var ranges []interface{}
+	start := 0
+	for slot := 1; slot <= clusterSlots; slot++ {
+		if slot < clusterSlots && c.slots[slot] == c.slots[start] {
+			continue
+		}
+		host, port, _ := net.SplitHostPort(c.slots[start].Addr())
+		portNumber, _ := strconv.Atoi(port)
+		ranges = append(ranges, []interface{}{int64(start), int64(slot - 1), []interface{}{host, int64(portNumber)}})
+		start = slot
+	}
+	return ranges
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected request %q", line)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || count < 1 {
+		return nil, fmt.Errorf("unexpected request %q", line)
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func writeReply(writer *bufio.Writer, reply interface{}) {
+	switch reply := reply.(type) {
+	case nil:
+		writer.WriteString("$-1\r\n")
+	case respStatus:
+		fmt.Fprintf(writer, "+%s\r\n", reply)
+	case respError:
+		fmt.Fprintf(writer, "-%s\r\n", reply)
+	case int64:
+		fmt.Fprintf(writer, ":%d\r\n", reply)
+	case string:
+		fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(reply), reply)
+	case []interface{}:
+		fmt.Fprintf(writer, "*%d\r\n", len(reply))
+		for _, item := range reply {
+			writeReply(writer, item)
+		}
+	}
+}
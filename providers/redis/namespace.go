@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+)
+
+const (
+	// ACTION_SCAN redis incremental key iteration action
+	ACTION_SCAN = "SCAN"
+	// ACTION_UNLINK redis non blocking delete action
+	ACTION_UNLINK = "UNLINK"
+	// OPTION_MATCH redis scan option filtering keys by a glob pattern
+	OPTION_MATCH = "MATCH"
+	// OPTION_COUNT redis scan option hinting the number of keys per iteration
+	OPTION_COUNT = "COUNT"
+)
+
+const (
+	// scanCount is the number of keys examined by every SCAN iteration
+	scanCount = 1000
+	// maxResetPasses bounds the SCAN passes of a namespace reset
+	maxResetPasses = 10
+)
+
+// SetNamespace sets the prefix of every key, scoping Reset to the keys of the
+// namespace. A provider with another namespace is left as is, as it is
+// probably used by another module.
+func (redisProvider *RedisProvider) SetNamespace(namespace string) error {
+	if redisProvider.Namespace != "" && redisProvider.Namespace != namespace {
+		return gcInterfaces.ErrNamespaceSet
+	}
+	redisProvider.Namespace = namespace
+	return nil
+}
+
+// resetNamespace removes the keys of the namespace from every primary,
+// iterating over them with SCAN so that the server is never blocked. Passes
+// are repeated until one finds no key, to also remove the keys written
+// during the reset, up to maxResetPasses.
+func (redisProvider *RedisProvider) resetNamespace(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pools, err := redisProvider.router.primaries(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, cluster := redisProvider.router.(*clusterRouter)
+	pattern := escapeGlob(redisProvider.Namespace) + "*"
+	for _, pool := range pools {
+		for pass := 0; pass < maxResetPasses; pass++ {
+			removed, err := scanAndUnlink(ctx, pool, pattern, cluster)
+			if err != nil {
+				return err
+			}
+			if removed == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// scanAndUnlink removes the keys matching pattern in a full SCAN iteration,
+// and returns the number of keys found
+func scanAndUnlink(ctx context.Context, pool *redis.Pool, pattern string, cluster bool) (int, error) {
+	removed := 0
+	cursor := "0"
+	for {
+		values, err := redis.Values(doPool(ctx, pool, ACTION_SCAN, cursor, OPTION_MATCH, pattern, OPTION_COUNT, scanCount))
+		if err != nil {
+			return removed, err
+		}
+		var keys []string
+		if _, err = redis.Scan(values, &cursor, &keys); err != nil {
+			return removed, err
+		}
+
+		if err = unlinkKeys(ctx, pool, keys, cluster); err != nil {
+			return removed, err
+		}
+		removed += len(keys)
+		if cursor == "0" {
+			return removed, nil
+		}
+	}
+}
+
+// unlinkKeys removes keys with UNLINK, or DEL on servers older than redis 4.
+// A cluster only accepts keys of the same hash slot in a single command.
+func unlinkKeys(ctx context.Context, pool *redis.Pool, keys []string, cluster bool) error {
+	batches := [][]string{keys}
+	if cluster {
+		bySlot := map[int][]string{}
+		for _, key := range keys {
+			bySlot[keySlot(key)] = append(bySlot[keySlot(key)], key)
+		}
+		batches = batches[:0]
+		for _, batch := range bySlot {
+			batches = append(batches, batch)
+		}
+	}
+
+	for _, batch := range batches {
+		if len(batch) == 0 {
+			continue
+		}
+
+		args := redis.Args{}.AddFlat(batch)
+		_, err := doPool(ctx, pool, ACTION_UNLINK, args...)
+		if isUnknownCommand(err) {
+			_, err = doPool(ctx, pool, ACTION_DELETE, args...)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isUnknownCommand(err error) bool {
+	var redisErr redis.Error
+	return errors.As(err, &redisErr) && strings.HasPrefix(string(redisErr), "ERR unknown command")
+}
+
+// escapeGlob escapes the characters having a meaning in redis glob patterns
+func escapeGlob(s string) string {
+	var escaped strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(c)
+	}
+	return escaped.String()
+}
@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func newNamespacedProvider(t *testing.T, addr string, namespace string) *RedisProvider {
+	namespacedProvider := &RedisProvider{Addr: addr, MaxIdle: 10, MaxActive: 100}
+	assert.Nil(t, namespacedProvider.SetNamespace(namespace))
+	assert.Nil(t, namespacedProvider.Init())
+	t.Cleanup(func() { namespacedProvider.Close() })
+	return namespacedProvider
+}
+
+func TestNamespacePrefixesKeys(t *testing.T) {
+	server := miniredis.RunT(t)
+	namespacedProvider := newNamespacedProvider(t, server.Addr(), "manager:module:")
+
+	assert.Nil(t, namespacedProvider.Set(existingKey, []byte(existingKeyValue)))
+	stored, err := server.Get("manager:module:" + existingKey)
+	assert.Nil(t, err)
+	assert.Equal(t, existingKeyValue, stored)
+	assert.False(t, server.Exists(existingKey))
+
+	value, err := namespacedProvider.Get(existingKey)
+	assert.Nil(t, err)
+	assert.Equal(t, existingKeyValue, string(value))
+
+	assert.Nil(t, namespacedProvider.Delete(existingKey))
+	assert.False(t, server.Exists("manager:module:"+existingKey))
+}
+
+func TestNamespaceSetOnce(t *testing.T) {
+	namespacedProvider := &RedisProvider{}
+	assert.Nil(t, namespacedProvider.SetNamespace("manager:module:"))
+	assert.Nil(t, namespacedProvider.SetNamespace("manager:module:"))
+	assert.Equal(t, gcInterfaces.ErrNamespaceSet, namespacedProvider.SetNamespace("manager:other:"))
+	assert.Equal(t, "manager:module:", namespacedProvider.Namespace)
+}
+
+func TestNamespaceReset(t *testing.T) {
+	server := miniredis.RunT(t)
+	first := newNamespacedProvider(t, server.Addr(), "manager:first:")
+	second := newNamespacedProvider(t, server.Addr(), "manager:second:")
+	server.Set("other_service", existingKeyValue)
+
+	// More keys than a single SCAN iteration returns
+	for i := 0; i < 2500; i++ {
+		assert.Nil(t, first.Set(fmt.Sprintf("key%d", i), []byte(existingKeyValue)))
+	}
+	assert.Nil(t, second.Set(existingKey, []byte(existingKeyValue)))
+
+	assert.Nil(t, first.Reset())
+	assert.Equal(t, []string{"manager:second:" + existingKey, "other_service"}, server.Keys())
+	assert.Equal(t, true, second.HasKey(existingKey))
+}
+
+func TestNamespaceResetEscapesPattern(t *testing.T) {
+	server := miniredis.RunT(t)
+	globbing := newNamespacedProvider(t, server.Addr(), "manager:[a-z]*:")
+	literal := newNamespacedProvider(t, server.Addr(), "manager:ab:")
+
+	assert.Nil(t, globbing.Set(existingKey, []byte(existingKeyValue)))
+	assert.Nil(t, literal.Set(existingKey, []byte(existingKeyValue)))
+
+	assert.Nil(t, globbing.Reset())
+	assert.Equal(t, false, globbing.HasKey(existingKey))
+	assert.Equal(t, true, literal.HasKey(existingKey))
+}
+
+func TestNamespaceResetFallsBackToDel(t *testing.T) {
+	// The fake node does not know UNLINK, like redis before 4.0
+	node, err := newFakeNode()
+	assert.Nil(t, err)
+	defer node.Close()
+
+	namespacedProvider := newNamespacedProvider(t, node.Addr(), "manager:module:")
+	assert.Nil(t, namespacedProvider.Set(existingKey, []byte(existingKeyValue)))
+	assert.Nil(t, namespacedProvider.Set(notExistingKey, []byte(existingKeyValue)))
+
+	assert.Nil(t, namespacedProvider.Reset())
+	assert.Equal(t, 0, node.Len())
+	assert.Contains(t, node.Commands(), "UNLINK")
+	assert.Contains(t, node.Commands(), "DEL")
+	assert.NotContains(t, node.Commands(), "FLUSHDB")
+}
+
+func TestNamespaceResetCluster(t *testing.T) {
+	cluster, err := newFakeCluster(3)
+	assert.Nil(t, err)
+	defer cluster.Close()
+
+	clusterProvider := &RedisProvider{ClusterAddrs: []string{cluster.nodes[0].Addr()}, MaxIdle: 10, MaxActive: 100}
+	assert.Nil(t, clusterProvider.SetNamespace("manager:module:"))
+	assert.Nil(t, clusterProvider.Init())
+	defer clusterProvider.Close()
+
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, clusterProvider.Set(fmt.Sprintf("key%d", i), []byte(existingKeyValue)))
+	}
+	owner := cluster.Owner("other_service")
+	owner.mu.Lock()
+	owner.data["other_service"] = existingKeyValue
+	owner.mu.Unlock()
+
+	assert.Nil(t, clusterProvider.Reset())
+	total := 0
+	for _, node := range cluster.nodes {
+		total += node.Len()
+	}
+	assert.Equal(t, 1, total)
+	_, ok := owner.Value("other_service")
+	assert.True(t, ok)
+}
+
+func TestEscapeGlob(t *testing.T) {
+	assert.Equal(t, `a\*b\?c\[d\]e\\f`, escapeGlob(`a*b?c[d]e\f`))
+}
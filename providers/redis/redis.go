@@ -1,6 +1,13 @@
 package redis
 
-import "github.com/gomodule/redigo/redis"
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+)
 
 const (
 	// ACTION_SET redis set key value action
@@ -13,6 +20,8 @@ const (
 	ACTION_RESET = "FLUSHDB"
 	// ACTION_PING redis ping action
 	ACTION_PING = "PING"
+	// OPTION_EXPIRE_MILLISECONDS redis set option to expire the key after a number of milliseconds
+	OPTION_EXPIRE_MILLISECONDS = "PX"
 )
 
 func newRedisPool(addr string, maxIdle int, maxActive int) *redis.Pool {
@@ -21,99 +30,253 @@ func newRedisPool(addr string, maxIdle int, maxActive int) *redis.Pool {
 		MaxIdle: maxIdle,
 		// max number of connections
 		MaxActive: maxActive,
-		// Dial is an application supplied function for creating and
-		// configuring a connection.
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp", addr)
+		// DialContext is an application supplied function for creating and
+		// configuring a connection, bounded by the context of the command.
+		DialContext: func(ctx context.Context) (redis.Conn, error) {
+			return dialContext(ctx, addr)
 		},
 	}
 }
 
 // NewRedisProvider returns a RedisProvider
 func NewRedisProvider(addr string, maxIdle int, maxActive int) (*RedisProvider, error) {
+	return NewRedisProviderContext(context.Background(), addr, maxIdle, maxActive)
+}
+
+// NewRedisProviderContext returns a RedisProvider, using ctx for the initial ping
+func NewRedisProviderContext(ctx context.Context, addr string, maxIdle int, maxActive int) (*RedisProvider, error) {
 	redisProvider := &RedisProvider{
 		Addr:      addr,
 		MaxIdle:   maxIdle,
 		MaxActive: maxActive,
 	}
 
-	err := redisProvider.Init()
+	return newRedisProvider(ctx, redisProvider)
+}
+
+// NewRedisSentinelProvider returns a RedisProvider connected to the primary
+// named masterName, as known by the sentinels at sentinelAddrs
+func NewRedisSentinelProvider(sentinelAddrs []string, masterName string, maxIdle int, maxActive int) (*RedisProvider, error) {
+	return NewRedisSentinelProviderContext(context.Background(), sentinelAddrs, masterName, maxIdle, maxActive)
+}
+
+// NewRedisSentinelProviderContext returns a RedisProvider connected through
+// sentinels, using ctx for the initial ping
+func NewRedisSentinelProviderContext(ctx context.Context, sentinelAddrs []string, masterName string, maxIdle int, maxActive int) (*RedisProvider, error) {
+	return newRedisProvider(ctx, &RedisProvider{
+		SentinelAddrs: sentinelAddrs,
+		MasterName:    masterName,
+		MaxIdle:       maxIdle,
+		MaxActive:     maxActive,
+	})
+}
+
+// NewRedisClusterProvider returns a RedisProvider routing commands to the
+// nodes of the cluster reachable at clusterAddrs
+func NewRedisClusterProvider(clusterAddrs []string, maxIdle int, maxActive int) (*RedisProvider, error) {
+	return NewRedisClusterProviderContext(context.Background(), clusterAddrs, maxIdle, maxActive)
+}
+
+// NewRedisClusterProviderContext returns a RedisProvider routing commands to
+// the nodes of a cluster, using ctx for the initial ping
+func NewRedisClusterProviderContext(ctx context.Context, clusterAddrs []string, maxIdle int, maxActive int) (*RedisProvider, error) {
+	return newRedisProvider(ctx, &RedisProvider{
+		ClusterAddrs: clusterAddrs,
+		MaxIdle:      maxIdle,
+		MaxActive:    maxActive,
+	})
+}
+
+func newRedisProvider(ctx context.Context, redisProvider *RedisProvider) (*RedisProvider, error) {
+	err := redisProvider.InitContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	err = redisProvider.Ping()
+	err = redisProvider.PingContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return redisProvider, nil
 }
 
-// RedisProvider is a storage provider based on redis caching system
+// RedisProvider is a storage provider based on redis caching system. It
+// connects to the server at Addr, to the primary known by the sentinels at
+// SentinelAddrs when MasterName is set, or to a cluster when ClusterAddrs is set.
 type RedisProvider struct {
-	redisPool *redis.Pool
+	router    router
 	Addr      string
 	MaxIdle   int
 	MaxActive int
+	// SentinelAddrs are the sentinels asked for the address of the primary
+	// named MasterName. The primary is resolved again after a failover.
+	SentinelAddrs []string
+	MasterName    string
+	// ClusterAddrs are nodes of a redis cluster, used to discover the others.
+	// MaxIdle and MaxActive apply to every node.
+	ClusterAddrs []string
+	// Namespace prefixes every key. When set, Reset only removes the keys of
+	// the namespace instead of flushing the database.
+	Namespace string
 }
 
 // Init initializes redis storage
 func (redisProvider *RedisProvider) Init() error {
-	redisProvider.redisPool = newRedisPool(redisProvider.Addr, redisProvider.MaxIdle, redisProvider.MaxActive)
+	return redisProvider.InitContext(context.Background())
+}
+
+// InitContext initializes redis storage unless ctx is already done
+func (redisProvider *RedisProvider) InitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if redisProvider.router != nil {
+		redisProvider.router.close()
+	}
+
+	switch {
+	case len(redisProvider.ClusterAddrs) > 0:
+		redisProvider.router = newClusterRouter(redisProvider.ClusterAddrs, func(addr string) *redis.Pool {
+			return newRedisPool(addr, redisProvider.MaxIdle, redisProvider.MaxActive)
+		})
+	case redisProvider.MasterName != "":
+		redisProvider.router = newSentinelRouter(redisProvider.SentinelAddrs, redisProvider.MasterName, redisProvider.MaxIdle, redisProvider.MaxActive)
+	default:
+		redisProvider.router = &standaloneRouter{pool: newRedisPool(redisProvider.Addr, redisProvider.MaxIdle, redisProvider.MaxActive)}
+	}
+	return nil
+}
+
+// Close closes the connections to the redis servers
+func (redisProvider *RedisProvider) Close() error {
+	if redisProvider.router == nil {
+		return nil
+	}
+	return redisProvider.router.close()
+}
+
+// do runs a command about the key given as first argument, prefixed by the
+// namespace, giving up as soon as ctx is done
+func (redisProvider *RedisProvider) do(ctx context.Context, commandName string, args ...interface{}) (interface{}, error) {
+	var key string
+	if len(args) > 0 {
+		key, _ = args[0].(string)
+		if redisProvider.Namespace != "" {
+			key = redisProvider.Namespace + key
+			args = append([]interface{}{key}, args[1:]...)
+		}
+	}
+	return redisProvider.router.do(ctx, key, commandName, args...)
+}
+
+// doAll runs a keyless command on every primary, which is only one server
+// unless in cluster mode
+func (redisProvider *RedisProvider) doAll(ctx context.Context, commandName string, args ...interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	pools, err := redisProvider.router.primaries(ctx)
+	if err != nil {
+		return err
+	}
+	for _, pool := range pools {
+		if _, err = doPool(ctx, pool, commandName, args...); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // Set adds a new value to cache or updates if it already exists
 func (redisProvider *RedisProvider) Set(key string, value []byte) error {
-	conn := redisProvider.redisPool.Get()
-	defer conn.Close()
+	return redisProvider.SetContext(context.Background(), key, value)
+}
+
+// SetContext adds a new value to cache or updates if it already exists
+func (redisProvider *RedisProvider) SetContext(ctx context.Context, key string, value []byte) error {
+	_, err := redisProvider.do(ctx, ACTION_SET, key, string(value))
+	return err
+}
+
+// SetWithTTL adds a new value to cache, or updates it, expiring after ttl
+func (redisProvider *RedisProvider) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return redisProvider.SetWithTTLContext(context.Background(), key, value, ttl)
+}
+
+// SetWithTTLContext adds a new value to cache, or updates it, expiring after ttl
+func (redisProvider *RedisProvider) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return redisProvider.SetContext(ctx, key, value)
+	}
 
-	_, err := conn.Do(ACTION_SET, key, string(value))
+	_, err := redisProvider.do(ctx, ACTION_SET, key, string(value), OPTION_EXPIRE_MILLISECONDS, ttlMilliseconds(ttl))
 	return err
 }
 
+// ttlMilliseconds rounds ttl up to the millisecond so that it never becomes zero
+func ttlMilliseconds(ttl time.Duration) int64 {
+	return int64((ttl + time.Millisecond - 1) / time.Millisecond)
+}
+
 // Get returns a cached value or error if it does not exist
 func (redisProvider *RedisProvider) Get(key string) ([]byte, error) {
-	conn := redisProvider.redisPool.Get()
-	defer conn.Close()
+	return redisProvider.GetContext(context.Background(), key)
+}
 
-	s, err := redis.String(conn.Do(ACTION_GET, key))
+// GetContext returns a cached value or error if it does not exist
+func (redisProvider *RedisProvider) GetContext(ctx context.Context, key string) ([]byte, error) {
+	s, err := redis.String(redisProvider.do(ctx, ACTION_GET, key))
+	if err == redis.ErrNil {
+		return nil, gcInterfaces.ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 	return []byte(s), nil
-
 }
 
 // Delete removes a value from the cache
 func (redisProvider *RedisProvider) Delete(key string) error {
-	conn := redisProvider.redisPool.Get()
-	defer conn.Close()
+	return redisProvider.DeleteContext(context.Background(), key)
+}
 
-	_, err := conn.Do(ACTION_DELETE, key)
+// DeleteContext removes a value from the cache
+func (redisProvider *RedisProvider) DeleteContext(ctx context.Context, key string) error {
+	_, err := redisProvider.do(ctx, ACTION_DELETE, key)
 	return err
 }
 
 // Reset empties cache storage
 func (redisProvider *RedisProvider) Reset() error {
-	conn := redisProvider.redisPool.Get()
-	defer conn.Close()
+	return redisProvider.ResetContext(context.Background())
+}
 
-	_, err := conn.Do(ACTION_RESET)
-	return err
+// ResetContext empties cache storage, or the namespace when one is set
+func (redisProvider *RedisProvider) ResetContext(ctx context.Context) error {
+	if redisProvider.Namespace != "" {
+		return redisProvider.resetNamespace(ctx)
+	}
+	return redisProvider.doAll(ctx, ACTION_RESET)
 }
 
 // Ping checks redis connection
 func (redisProvider *RedisProvider) Ping() error {
-	conn := redisProvider.redisPool.Get()
-	defer conn.Close()
+	return redisProvider.PingContext(context.Background())
+}
 
-	_, err := redis.String(conn.Do(ACTION_PING))
-	return err
+// PingContext checks redis connection, to every primary in cluster mode
+func (redisProvider *RedisProvider) PingContext(ctx context.Context) error {
+	return redisProvider.doAll(ctx, ACTION_PING)
 }
 
 // HasKey checks if the key exists
 func (redisProvider *RedisProvider) HasKey(key string) bool {
-	_, err := redisProvider.Get(key)
+	return redisProvider.HasKeyContext(context.Background(), key)
+}
+
+// HasKeyContext checks if the key exists
+func (redisProvider *RedisProvider) HasKeyContext(ctx context.Context, key string) bool {
+	_, err := redisProvider.GetContext(ctx, key)
 	return err == nil
 }
@@ -1,10 +1,15 @@
 package redis
 
 import (
+	"context"
+	"io"
+	"net"
 	"os"
 	"testing"
+	"time"
 
-	"github.com/alicebob/miniredis"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/josemiguelmelo/gocacheable/providertest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -123,3 +128,137 @@ func TestResetKeyCache(t *testing.T) {
 	_, err = redisProvider.Get(notExistingKey)
 	assert.NotNil(t, err)
 }
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := redisProvider.SetContext(ctx, existingKey, []byte(existingKeyValue))
+	assert.Equal(t, context.Canceled, err)
+	_, err = redisProvider.GetContext(ctx, existingKey)
+	assert.Equal(t, context.Canceled, err)
+	err = redisProvider.DeleteContext(ctx, existingKey)
+	assert.Equal(t, context.Canceled, err)
+	err = redisProvider.ResetContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, false, redisProvider.HasKeyContext(ctx, existingKey))
+
+	_, err = NewRedisProviderContext(ctx, redisServer.Addr(), 10, 100)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestContextDeadlineAbortsCommand(t *testing.T) {
+	// A listener that accepts connections but never replies
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	stalled := &RedisProvider{Addr: listener.Addr().String(), MaxIdle: 1, MaxActive: 1}
+	assert.Nil(t, stalled.Init())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = stalled.GetContext(ctx, existingKey)
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestDialRespectsContext(t *testing.T) {
+	// A listener whose connections are never accepted
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = NewRedisProviderContext(ctx, listener.Addr().String(), 1, 1)
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestContextCancelClosesConnection(t *testing.T) {
+	// A listener that accepts connections but never replies, reporting when
+	// the client closes them
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	closed := make(chan struct{}, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(io.Discard, conn)
+		closed <- struct{}{}
+	}()
+
+	stalled := &RedisProvider{Addr: listener.Addr().String(), MaxIdle: 1, MaxActive: 1}
+	assert.Nil(t, stalled.Init())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err = stalled.GetContext(ctx, existingKey)
+	assert.Equal(t, context.Canceled, err)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("connection still open after the context was canceled")
+	}
+	pool := stalled.router.(*standaloneRouter).pool
+	assert.Eventually(t, func() bool { return pool.ActiveCount() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestSetWithTTLCache(t *testing.T) {
+	err := redisProvider.SetWithTTL(existingKey, []byte(existingKeyValue), 100*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 100*time.Millisecond, redisServer.TTL(existingKey))
+
+	val, err := redisProvider.Get(existingKey)
+	assert.Nil(t, err)
+	assert.Equal(t, existingKeyValue, string(val))
+
+	redisServer.FastForward(200 * time.Millisecond)
+	_, err = redisProvider.Get(existingKey)
+	assert.NotNil(t, err)
+
+	// No ttl means no expiration
+	err = redisProvider.SetWithTTL(existingKey, []byte(existingKeyValue), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), redisServer.TTL(existingKey))
+}
+
+func TestConformance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) providertest.Fixture {
+		server := miniredis.RunT(t)
+		conformingProvider, err := NewRedisProvider(server.Addr(), 10, 100)
+		assert.Nil(t, err)
+		t.Cleanup(func() { conformingProvider.Close() })
+		return providertest.Fixture{Provider: conformingProvider, Advance: server.FastForward}
+	})
+}
+
+func TestNamespaceConformance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) providertest.Fixture {
+		server := miniredis.RunT(t)
+		return providertest.Fixture{
+			Provider: newNamespacedProvider(t, server.Addr(), "manager:module:"),
+			Advance:  server.FastForward,
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// router picks the server a command runs on
+type router interface {
+	// do runs a command about key
+	do(ctx context.Context, key string, commandName string, args ...interface{}) (interface{}, error)
+	// pipeline sends commands about keys of the hash slot of key on a single
+	// connection, returning their replies in order. Error replies are returned
+	// as redis.Error values.
+	pipeline(ctx context.Context, key string, commands []command) ([]interface{}, error)
+	// primaries returns the pools of every primary, on which keyless commands
+	// such as FLUSHDB must run
+	primaries(ctx context.Context) ([]*redis.Pool, error)
+	close() error
+}
+
+// command is a command of a pipeline
+type command struct {
+	name string
+	args []interface{}
+}
+
+// standaloneRouter sends every command to a single server
+type standaloneRouter struct {
+	pool *redis.Pool
+}
+
+func (r *standaloneRouter) do(ctx context.Context, key string, commandName string, args ...interface{}) (interface{}, error) {
+	return doPool(ctx, r.pool, commandName, args...)
+}
+
+func (r *standaloneRouter) pipeline(ctx context.Context, key string, commands []command) ([]interface{}, error) {
+	return pipelinePool(ctx, r.pool, commands)
+}
+
+func (r *standaloneRouter) primaries(ctx context.Context) ([]*redis.Pool, error) {
+	return []*redis.Pool{r.pool}, nil
+}
+
+func (r *standaloneRouter) close() error {
+	return r.pool.Close()
+}
+
+// doPool runs a command on a connection of pool, giving up as soon as ctx is done
+func doPool(ctx context.Context, pool *redis.Pool, commandName string, args ...interface{}) (interface{}, error) {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return doContext(ctx, conn, commandName, args...)
+}
+
+// pipelinePool sends commands on a connection of pool and reads their replies,
+// giving up as soon as ctx is done
+func pipelinePool(ctx context.Context, pool *redis.Pool, commands []command) ([]interface{}, error) {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	for _, c := range commands {
+		if err := conn.Send(c.name, c.args...); err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(commands))
+	for i := range replies {
+		var err error
+		replies[i], err = redis.ReceiveContext(conn, ctx)
+		if redisErr, ok := err.(redis.Error); ok {
+			replies[i] = redisErr
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return replies, nil
+}
+
+// doContext runs a command on conn and closes it afterwards. When ctx is done
+// before the reply is read, the network connection is closed so that the
+// command stops blocking on it, and the pool discards the connection.
+func doContext(ctx context.Context, conn redis.Conn, commandName string, args ...interface{}) (interface{}, error) {
+	defer conn.Close()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return redis.DoContext(conn, ctx, commandName, args...)
+}
+
+// dialContext connects to the server at addr, giving up when ctx is done
+func dialContext(ctx context.Context, addr string) (redis.Conn, error) {
+	return redis.DialContext(ctx, "tcp", addr)
+}
@@ -0,0 +1,258 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// ACTION_SENTINEL redis sentinel command
+	ACTION_SENTINEL = "SENTINEL"
+	// ACTION_ROLE redis command returning the replication role of a server
+	ACTION_ROLE = "ROLE"
+)
+
+var (
+	// ErrNoPrimary is returned when no sentinel knows the address of the primary
+	ErrNoPrimary = errors.New("No sentinel knows the redis primary")
+	// ErrNotPrimary is returned when the server given by the sentinels is not a
+	// primary, usually because a failover is in progress
+	ErrNotPrimary = errors.New("Redis server is not a primary")
+)
+
+// sentinelDialTimeout bounds the exchange with a sentinel, so that an
+// unresponsive one does not delay asking the next, and the role check of a
+// primary
+const sentinelDialTimeout = 2 * time.Second
+
+// sentinelRouter sends every command to the primary known by the sentinels.
+// The primary is resolved when a pool is created, and the pool is replaced
+// when the server stops being a primary or becomes unreachable, so commands
+// follow a failover.
+type sentinelRouter struct {
+	sentinelAddrs []string
+	masterName    string
+	maxIdle       int
+	maxActive     int
+
+	mu   sync.Mutex
+	pool *redis.Pool
+	// resolving is closed when the primary being resolved is known, and is
+	// nil when no resolution is in flight
+	resolving chan struct{}
+}
+
+func newSentinelRouter(sentinelAddrs []string, masterName string, maxIdle int, maxActive int) *sentinelRouter {
+	return &sentinelRouter{
+		sentinelAddrs: sentinelAddrs,
+		masterName:    masterName,
+		maxIdle:       maxIdle,
+		maxActive:     maxActive,
+	}
+}
+
+// newPool returns a pool of connections to the primary at addr
+func (r *sentinelRouter) newPool(addr string) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:   r.maxIdle,
+		MaxActive: r.maxActive,
+		DialContext: func(ctx context.Context) (redis.Conn, error) {
+			return dialPrimary(ctx, addr)
+		},
+	}
+}
+
+// resolvePrimary asks the sentinels, in order, for the address of the primary,
+// giving up as soon as ctx is done
+func (r *sentinelRouter) resolvePrimary(ctx context.Context) (string, error) {
+	for _, sentinelAddr := range r.sentinelAddrs {
+		addr, err := r.askSentinel(ctx, sentinelAddr)
+		if err == nil {
+			return addr, nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		// The read timeout set from the deadline may fire before ctx is done
+		if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			return "", context.DeadlineExceeded
+		}
+	}
+	return "", ErrNoPrimary
+}
+
+// askSentinel asks the sentinel at sentinelAddr for the address of the primary
+func (r *sentinelRouter) askSentinel(ctx context.Context, sentinelAddr string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, sentinelDialTimeout)
+	defer cancel()
+
+	conn, err := dialContext(ctx, sentinelAddr)
+	if err != nil {
+		return "", err
+	}
+	addr, err := redis.Strings(doContext(ctx, conn, ACTION_SENTINEL, "get-master-addr-by-name", r.masterName))
+	if err != nil {
+		return "", err
+	}
+	if len(addr) != 2 {
+		return "", ErrNoPrimary
+	}
+	return net.JoinHostPort(addr[0], addr[1]), nil
+}
+
+// dialPrimary connects to the primary at addr and checks its role, as the
+// sentinels may not have noticed a failover yet. Both are bounded by ctx.
+func dialPrimary(ctx context.Context, addr string) (redis.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, sentinelDialTimeout)
+	defer cancel()
+
+	conn, err := dialContext(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := redis.Values(redis.DoContext(conn, ctx, ACTION_ROLE))
+	if err == nil && len(role) > 0 {
+		var name string
+		if name, err = redis.String(role[0], nil); err == nil && name != "master" {
+			err = ErrNotPrimary
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// currentPool returns the pool of the primary, asking the sentinels for it
+// when there is none. The sentinels are asked without holding the lock, and
+// concurrent callers wait for the same resolution.
+func (r *sentinelRouter) currentPool(ctx context.Context) (*redis.Pool, error) {
+	for {
+		r.mu.Lock()
+		if r.pool != nil {
+			pool := r.pool
+			r.mu.Unlock()
+			return pool, nil
+		}
+		if resolving := r.resolving; resolving != nil {
+			r.mu.Unlock()
+			select {
+			case <-resolving:
+				// Look again, asking the sentinels if the resolution failed
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		resolving := make(chan struct{})
+		r.resolving = resolving
+		r.mu.Unlock()
+
+		addr, err := r.resolvePrimary(ctx)
+
+		r.mu.Lock()
+		r.resolving = nil
+		close(resolving)
+		if err != nil {
+			r.mu.Unlock()
+			return nil, err
+		}
+		r.pool = r.newPool(addr)
+		pool := r.pool
+		r.mu.Unlock()
+		return pool, nil
+	}
+}
+
+// replacePool drops the connections of pool, unless it was already replaced.
+// The primary is resolved again for the next command.
+func (r *sentinelRouter) replacePool(pool *redis.Pool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pool == pool {
+		r.pool = nil
+		pool.Close()
+	}
+}
+
+func (r *sentinelRouter) do(ctx context.Context, key string, commandName string, args ...interface{}) (interface{}, error) {
+	pool, err := r.currentPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	value, err := doPool(ctx, pool, commandName, args...)
+	if err == nil || !isFailover(ctx, err) {
+		return value, err
+	}
+
+	r.replacePool(pool)
+	if pool, err = r.currentPool(ctx); err != nil {
+		return nil, err
+	}
+	return doPool(ctx, pool, commandName, args...)
+}
+
+func (r *sentinelRouter) pipeline(ctx context.Context, key string, commands []command) ([]interface{}, error) {
+	pool, err := r.currentPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	replies, err := pipelinePool(ctx, pool, commands)
+	if err == nil {
+		for _, reply := range replies {
+			if replyErr, ok := reply.(redis.Error); ok && isFailover(ctx, replyErr) {
+				err = replyErr
+				break
+			}
+		}
+	}
+	if err == nil || !isFailover(ctx, err) {
+		return replies, err
+	}
+
+	r.replacePool(pool)
+	if pool, err = r.currentPool(ctx); err != nil {
+		return nil, err
+	}
+	return pipelinePool(ctx, pool, commands)
+}
+
+func (r *sentinelRouter) primaries(ctx context.Context) ([]*redis.Pool, error) {
+	pool, err := r.currentPool(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []*redis.Pool{pool}, nil
+}
+
+func (r *sentinelRouter) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pool == nil {
+		return nil
+	}
+	return r.pool.Close()
+}
+
+// isFailover returns true if err means the server is no longer the primary:
+// it refuses writes or cannot be reached
+func isFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		return strings.HasPrefix(string(redisErr), "READONLY")
+	}
+	return !errors.Is(err, redis.ErrPoolExhausted)
+}
@@ -0,0 +1,180 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSentinelSetup returns a primary, a replica and a sentinel pointing at the primary
+func newSentinelSetup(t *testing.T) (*fakeNode, *fakeNode, *fakeNode) {
+	var nodes []*fakeNode
+	for i := 0; i < 3; i++ {
+		node, err := newFakeNode()
+		assert.Nil(t, err)
+		t.Cleanup(node.Close)
+		nodes = append(nodes, node)
+	}
+
+	primary, replica, sentinel := nodes[0], nodes[1], nodes[2]
+	replica.SetRole("slave")
+	sentinel.SetPrimaryAddr(primary.Addr())
+	return primary, replica, sentinel
+}
+
+func TestSentinelResolvesPrimary(t *testing.T) {
+	primary, replica, sentinel := newSentinelSetup(t)
+
+	sentinelProvider, err := NewRedisSentinelProvider([]string{"127.0.0.1:1", sentinel.Addr()}, "mymaster", 10, 100)
+	assert.Nil(t, err)
+	defer sentinelProvider.Close()
+
+	assert.Nil(t, sentinelProvider.Set(existingKey, []byte(existingKeyValue)))
+	value, err := sentinelProvider.Get(existingKey)
+	assert.Nil(t, err)
+	assert.Equal(t, existingKeyValue, string(value))
+
+	stored, _ := primary.Value(existingKey)
+	assert.Equal(t, existingKeyValue, stored)
+	assert.Equal(t, 0, replica.Len())
+}
+
+func TestSentinelFollowsFailoverOfReadOnlyPrimary(t *testing.T) {
+	primary, replica, sentinel := newSentinelSetup(t)
+
+	sentinelProvider, err := NewRedisSentinelProvider([]string{sentinel.Addr()}, "mymaster", 10, 100)
+	assert.Nil(t, err)
+	defer sentinelProvider.Close()
+	assert.Nil(t, sentinelProvider.Set(existingKey, []byte(existingKeyValue)))
+
+	// The old primary is demoted, its pooled connections now get READONLY
+	primary.SetRole("slave")
+	replica.SetRole("master")
+	sentinel.SetPrimaryAddr(replica.Addr())
+
+	assert.Nil(t, sentinelProvider.Set(existingKey, []byte("updated")))
+	stored, _ := replica.Value(existingKey)
+	assert.Equal(t, "updated", stored)
+}
+
+func TestSentinelFollowsFailoverOfUnreachablePrimary(t *testing.T) {
+	primary, replica, sentinel := newSentinelSetup(t)
+
+	sentinelProvider, err := NewRedisSentinelProvider([]string{sentinel.Addr()}, "mymaster", 10, 100)
+	assert.Nil(t, err)
+	defer sentinelProvider.Close()
+	assert.Nil(t, sentinelProvider.Set(existingKey, []byte(existingKeyValue)))
+
+	primary.Close()
+	replica.SetRole("master")
+	sentinel.SetPrimaryAddr(replica.Addr())
+
+	_, err = sentinelProvider.Get(existingKey)
+	assert.NotNil(t, err)
+	assert.Contains(t, replica.Commands(), "GET")
+	assert.Nil(t, sentinelProvider.Set(existingKey, []byte("updated")))
+}
+
+func TestSentinelChecksPrimaryRole(t *testing.T) {
+	_, replica, sentinel := newSentinelSetup(t)
+
+	// The sentinels did not notice the failover yet
+	sentinel.SetPrimaryAddr(replica.Addr())
+	_, err := NewRedisSentinelProvider([]string{sentinel.Addr()}, "mymaster", 10, 100)
+	assert.True(t, errors.Is(err, ErrNotPrimary))
+
+	sentinel.SetPrimaryAddr("")
+	_, err = NewRedisSentinelProvider([]string{sentinel.Addr()}, "mymaster", 10, 100)
+	assert.Equal(t, ErrNoPrimary, err)
+}
+
+func TestSentinelResolveRespectsContext(t *testing.T) {
+	// A sentinel that accepts connections but never replies
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = NewRedisSentinelProviderContext(ctx, []string{listener.Addr().String()}, "mymaster", 10, 100)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < sentinelDialTimeout)
+}
+
+func TestSentinelPrimaryDialRespectsContext(t *testing.T) {
+	_, _, sentinel := newSentinelSetup(t)
+
+	// A primary whose connections are never accepted, so ROLE gets no reply
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	sentinel.SetPrimaryAddr(listener.Addr().String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = NewRedisSentinelProviderContext(ctx, []string{sentinel.Addr()}, "mymaster", 10, 100)
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < sentinelDialTimeout)
+}
+
+func TestSentinelCoalescesResolutions(t *testing.T) {
+	_, _, sentinel := newSentinelSetup(t)
+
+	router := newSentinelRouter([]string{sentinel.Addr()}, "mymaster", 10, 100)
+	defer router.close()
+
+	var wg sync.WaitGroup
+	pools := make([]*redis.Pool, 10)
+	for i := range pools {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pool, err := router.currentPool(context.Background())
+			assert.Nil(t, err)
+			pools[i] = pool
+		}(i)
+	}
+	wg.Wait()
+
+	for _, pool := range pools {
+		assert.Equal(t, pools[0], pool)
+	}
+	assert.Equal(t, []string{"SENTINEL"}, sentinel.Commands())
+}
+
+func TestSentinelResolvesWithoutLocking(t *testing.T) {
+	// A sentinel whose connections are never accepted
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	router := newSentinelRouter([]string{listener.Addr().String()}, "mymaster", 10, 100)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	go router.currentPool(ctx)
+	time.Sleep(50 * time.Millisecond)
+
+	// Closing does not wait for the sentinel to reply
+	start := time.Now()
+	assert.Nil(t, router.close())
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
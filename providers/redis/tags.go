@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// ACTION_EVAL redis script evaluation action
+const ACTION_EVAL = "EVAL"
+
+// tagKeyPrefix prefixes the set holding the keys carrying a tag. It starts
+// with a NUL byte so that tag sets do not share the key space of the cached
+// entries, whose keys are readable strings.
+const tagKeyPrefix = "\x00tag:"
+
+// addTagScript adds a key to the set of a tag, keeping the set at least as
+// long as the key: sets only expire once every key added may have expired.
+const addTagScript = `
+local current = redis.call('PTTL', KEYS[1])
+redis.call('SADD', KEYS[1], ARGV[1])
+local ttl = tonumber(ARGV[2])
+if ttl <= 0 then
+	redis.call('PERSIST', KEYS[1])
+elseif current == -2 or (current >= 0 and current < ttl) then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return 1
+`
+
+// popTagScript removes the set of a tag and returns its keys
+const popTagScript = `
+local keys = redis.call('SMEMBERS', KEYS[1])
+redis.call('DEL', KEYS[1])
+return keys
+`
+
+// AddTags adds key to the set of every tag
+func (redisProvider *RedisProvider) AddTags(key string, tags []string, ttl time.Duration) error {
+	return redisProvider.AddTagsContext(context.Background(), key, tags, ttl)
+}
+
+// AddTagsContext adds key to the set of every tag. A set expires once every
+// key added may have expired, and never if one of them has no time to live.
+func (redisProvider *RedisProvider) AddTagsContext(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	milliseconds := int64(0)
+	if ttl > 0 {
+		milliseconds = ttlMilliseconds(ttl)
+	}
+	for _, tag := range tags {
+		tagKey := redisProvider.tagKey(tag)
+		if _, err := redisProvider.router.do(ctx, tagKey, ACTION_EVAL, addTagScript, 1, tagKey, key, milliseconds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PopTag removes the set of tag and returns its keys
+func (redisProvider *RedisProvider) PopTag(tag string) ([]string, error) {
+	return redisProvider.PopTagContext(context.Background(), tag)
+}
+
+// PopTagContext removes the set of tag and returns its keys, atomically so
+// that no key added concurrently is lost
+func (redisProvider *RedisProvider) PopTagContext(ctx context.Context, tag string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tagKey := redisProvider.tagKey(tag)
+	return redis.Strings(redisProvider.router.do(ctx, tagKey, ACTION_EVAL, popTagScript, 1, tagKey))
+}
+
+// tagKey returns the key of the set of tag, in the namespace so that Reset
+// removes it with the keys
+func (redisProvider *RedisProvider) tagKey(tag string) string {
+	return redisProvider.Namespace + tagKeyPrefix + tag
+}
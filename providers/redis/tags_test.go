@@ -0,0 +1,85 @@
+package redis
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddTagsAndPopTag(t *testing.T) {
+	server := miniredis.RunT(t)
+	taggedProvider := newNamespacedProvider(t, server.Addr(), "manager:module:")
+
+	assert.Nil(t, taggedProvider.AddTags("a", []string{"first", "second"}, time.Minute))
+	assert.Nil(t, taggedProvider.AddTags("b", []string{"first"}, time.Minute))
+	members, err := server.Members("manager:module:" + tagKeyPrefix + "first")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, members)
+
+	keys, err := taggedProvider.PopTag("first")
+	assert.Nil(t, err)
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "b"}, keys)
+	assert.False(t, server.Exists("manager:module:"+tagKeyPrefix+"first"))
+
+	keys, err = taggedProvider.PopTag("first")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(keys))
+
+	// Tag sets are removed with the keys of the namespace
+	assert.Nil(t, taggedProvider.Reset())
+	assert.False(t, server.Exists("manager:module:"+tagKeyPrefix+"second"))
+}
+
+func TestTagSetsDoNotCollideWithEntries(t *testing.T) {
+	server := miniredis.RunT(t)
+	taggedProvider := newNamespacedProvider(t, server.Addr(), "manager:module:")
+
+	for _, key := range []string{"tag:first", "gocacheable:tag:first"} {
+		assert.Nil(t, taggedProvider.Set(key, []byte("value")))
+	}
+	assert.Nil(t, taggedProvider.AddTags("a", []string{"first"}, time.Minute))
+	for _, key := range []string{"tag:first", "gocacheable:tag:first"} {
+		value, err := taggedProvider.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, "value", string(value))
+	}
+}
+
+func TestTagSetOutlivesItsKeys(t *testing.T) {
+	server := miniredis.RunT(t)
+	taggedProvider := newNamespacedProvider(t, server.Addr(), "")
+	tagKey := tagKeyPrefix + "tag"
+
+	assert.Nil(t, taggedProvider.AddTags("a", []string{"tag"}, time.Minute))
+	assert.Equal(t, time.Minute, server.TTL(tagKey))
+
+	// A shorter time to live keeps the set, a longer one extends it
+	assert.Nil(t, taggedProvider.AddTags("b", []string{"tag"}, time.Second))
+	assert.Equal(t, time.Minute, server.TTL(tagKey))
+	assert.Nil(t, taggedProvider.AddTags("c", []string{"tag"}, time.Hour))
+	assert.Equal(t, time.Hour, server.TTL(tagKey))
+
+	// Keys without expiration keep the set forever
+	assert.Nil(t, taggedProvider.AddTags("d", []string{"tag"}, 0))
+	assert.Equal(t, time.Duration(0), server.TTL(tagKey))
+	assert.Nil(t, taggedProvider.AddTags("e", []string{"tag"}, time.Minute))
+	assert.Equal(t, time.Duration(0), server.TTL(tagKey))
+
+	assert.Nil(t, taggedProvider.AddTags("a", []string{"expiring"}, time.Minute))
+	server.FastForward(time.Minute)
+	assert.False(t, server.Exists(tagKeyPrefix+"expiring"))
+}
+
+func TestTagsContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, redisProvider.AddTagsContext(ctx, existingKey, []string{"tag"}, time.Minute))
+	_, err := redisProvider.PopTagContext(ctx, "tag")
+	assert.Equal(t, context.Canceled, err)
+}
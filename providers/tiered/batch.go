@@ -0,0 +1,137 @@
+package tiered
+
+import (
+	"context"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+)
+
+// GetMany returns the values of the keys found in any cache level
+func (tieredProvider *TieredProvider) GetMany(keys []string) (map[string][]byte, error) {
+	return tieredProvider.GetManyContext(context.Background(), keys)
+}
+
+// GetManyContext returns the values of the keys found in L1, and reads the
+// keys missing from L1 in a single L2 call, filling L1 with them
+func (tieredProvider *TieredProvider) GetManyContext(ctx context.Context, keys []string) (map[string][]byte, error) {
+	values, err := getMany(ctx, tieredProvider.L1, keys)
+	if err != nil {
+		// L1 failures fall back to L2, as for a single key
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		values = map[string][]byte{}
+	}
+
+	var missing []string
+	for _, key := range keys {
+		if _, ok := values[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return values, nil
+	}
+
+	found, err := getMany(ctx, tieredProvider.L2, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return values, nil
+	}
+
+	// A failure to fill L1 only costs future L2 reads
+	setMany(ctx, tieredProvider.L1, found, tieredProvider.l1TimeToLive(0))
+	for key, value := range found {
+		values[key] = value
+	}
+	return values, nil
+}
+
+// SetMany adds values to both cache levels or updates them
+func (tieredProvider *TieredProvider) SetMany(values map[string][]byte, ttl time.Duration) error {
+	return tieredProvider.SetManyContext(context.Background(), values, ttl)
+}
+
+// SetManyContext adds values to both cache levels, or updates them, expiring
+// after ttl. L1 keeps them for L1TimeToLive at most.
+func (tieredProvider *TieredProvider) SetManyContext(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+	if err := setMany(ctx, tieredProvider.L2, values, ttl); err != nil {
+		// Do not keep values in L1 that L2 may not have
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		deleteMany(ctx, tieredProvider.L1, keys)
+		return err
+	}
+	return setMany(ctx, tieredProvider.L1, values, tieredProvider.l1TimeToLive(ttl))
+}
+
+// DeleteMany removes keys from both cache levels
+func (tieredProvider *TieredProvider) DeleteMany(keys []string) error {
+	return tieredProvider.DeleteManyContext(context.Background(), keys)
+}
+
+// DeleteManyContext removes keys from both cache levels
+func (tieredProvider *TieredProvider) DeleteManyContext(ctx context.Context, keys []string) error {
+	l2Err := deleteMany(ctx, tieredProvider.L2, keys)
+	l1Err := deleteMany(ctx, tieredProvider.L1, keys)
+	if l2Err != nil {
+		return l2Err
+	}
+	return l1Err
+}
+
+// getMany returns the values of the keys found in level, with a single call
+// if it supports batches. Missing keys are not an error.
+func getMany(ctx context.Context, level gcInterfaces.CacheProviderInterface, keys []string) (map[string][]byte, error) {
+	if batch, ok := level.(gcInterfaces.BatchCacheProviderInterface); ok {
+		return batch.GetManyContext(ctx, keys)
+	}
+
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		value, err := level.GetContext(ctx, key)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			continue
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// setMany stores values in level, with a single call if it supports batches
+func setMany(ctx context.Context, level gcInterfaces.CacheProviderInterface, values map[string][]byte, ttl time.Duration) error {
+	if batch, ok := level.(gcInterfaces.BatchCacheProviderInterface); ok {
+		return batch.SetManyContext(ctx, values, ttl)
+	}
+	for key, value := range values {
+		if err := level.SetWithTTLContext(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteMany removes keys from level, with a single call if it supports batches
+func deleteMany(ctx context.Context, level gcInterfaces.CacheProviderInterface, keys []string) error {
+	if batch, ok := level.(gcInterfaces.BatchCacheProviderInterface); ok {
+		return batch.DeleteManyContext(ctx, keys)
+	}
+	for _, key := range keys {
+		// Some in memory providers fail to delete missing keys
+		if !level.HasKeyContext(ctx, key) {
+			continue
+		}
+		if err := level.DeleteContext(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package tiered
+
+import (
+	"context"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+)
+
+// AddTags adds key to the tag index of the cache levels keeping tags
+func (tieredProvider *TieredProvider) AddTags(key string, tags []string, ttl time.Duration) error {
+	return tieredProvider.AddTagsContext(context.Background(), key, tags, ttl)
+}
+
+// AddTagsContext adds key to the tag index of the cache levels keeping tags.
+// It returns ErrTagsNotSupported when no level does, so that modules keep the
+// index themselves.
+func (tieredProvider *TieredProvider) AddTagsContext(ctx context.Context, key string, tags []string, ttl time.Duration) error {
+	levels := tieredProvider.taggedLevels()
+	if len(levels) == 0 {
+		return gcInterfaces.ErrTagsNotSupported
+	}
+	for _, level := range levels {
+		if err := level.AddTagsContext(ctx, key, tags, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PopTag removes the index of tag from the cache levels keeping tags and
+// returns the keys they held
+func (tieredProvider *TieredProvider) PopTag(tag string) ([]string, error) {
+	return tieredProvider.PopTagContext(context.Background(), tag)
+}
+
+// PopTagContext removes the index of tag from the cache levels keeping tags
+// and returns the keys they held. It returns ErrTagsNotSupported when no level
+// does.
+func (tieredProvider *TieredProvider) PopTagContext(ctx context.Context, tag string) ([]string, error) {
+	levels := tieredProvider.taggedLevels()
+	if len(levels) == 0 {
+		return nil, gcInterfaces.ErrTagsNotSupported
+	}
+
+	var keys []string
+	seen := map[string]bool{}
+	for _, level := range levels {
+		levelKeys, err := level.PopTagContext(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range levelKeys {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// taggedLevels returns the cache levels keeping tags, L2 first
+func (tieredProvider *TieredProvider) taggedLevels() []gcInterfaces.TaggedCacheProviderInterface {
+	var levels []gcInterfaces.TaggedCacheProviderInterface
+	for _, level := range []gcInterfaces.CacheProviderInterface{tieredProvider.L2, tieredProvider.L1} {
+		if tagged, ok := level.(gcInterfaces.TaggedCacheProviderInterface); ok {
+			levels = append(levels, tagged)
+		}
+	}
+	return levels
+}
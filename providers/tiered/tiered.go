@@ -0,0 +1,173 @@
+package tiered
+
+import (
+	"context"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+)
+
+// DefaultL1TimeToLive is how long values are kept in L1 when L1TimeToLive is
+// not set
+const DefaultL1TimeToLive = 10 * time.Second
+
+// NewTieredProvider returns a TieredProvider with l1 in front of l2. An
+// l1TimeToLive lower or equal to zero is replaced by DefaultL1TimeToLive.
+func NewTieredProvider(l1 gcInterfaces.CacheProviderInterface, l2 gcInterfaces.CacheProviderInterface, l1TimeToLive time.Duration) *TieredProvider {
+	if l1TimeToLive <= 0 {
+		l1TimeToLive = DefaultL1TimeToLive
+	}
+	return &TieredProvider{
+		L1:           l1,
+		L2:           l2,
+		L1TimeToLive: l1TimeToLive,
+	}
+}
+
+// TieredProvider is a storage provider layering a near cache, usually in
+// process, in front of a shared one. Reads are served from L1 when possible
+// and fill it from L2, while writes and deletes go to both.
+type TieredProvider struct {
+	// L1 is the near cache, such as a BigCacheProvider
+	L1 gcInterfaces.CacheProviderInterface
+	// L2 is the shared cache, such as a RedisProvider
+	L2 gcInterfaces.CacheProviderInterface
+	// L1TimeToLive bounds how long values are kept in L1, and so how long it
+	// can serve a value changed in L2 by another instance. Values never stay
+	// in L1 without expiration: DefaultL1TimeToLive is used when it is lower
+	// or equal to zero.
+	L1TimeToLive time.Duration
+}
+
+// Init initializes both cache levels
+func (tieredProvider *TieredProvider) Init() error {
+	return tieredProvider.InitContext(context.Background())
+}
+
+// InitContext initializes both cache levels
+func (tieredProvider *TieredProvider) InitContext(ctx context.Context) error {
+	if err := tieredProvider.L1.InitContext(ctx); err != nil {
+		return err
+	}
+	return tieredProvider.L2.InitContext(ctx)
+}
+
+// SetNamespace sets the namespace of the cache levels supporting namespaces
+func (tieredProvider *TieredProvider) SetNamespace(namespace string) error {
+	for _, level := range []gcInterfaces.CacheProviderInterface{tieredProvider.L1, tieredProvider.L2} {
+		if namespaced, ok := level.(gcInterfaces.NamespacedCacheProviderInterface); ok {
+			if err := namespaced.SetNamespace(namespace); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Set adds a new value to both cache levels or updates it
+func (tieredProvider *TieredProvider) Set(key string, value []byte) error {
+	return tieredProvider.SetWithTTLContext(context.Background(), key, value, 0)
+}
+
+// SetContext adds a new value to both cache levels or updates it
+func (tieredProvider *TieredProvider) SetContext(ctx context.Context, key string, value []byte) error {
+	return tieredProvider.SetWithTTLContext(ctx, key, value, 0)
+}
+
+// SetWithTTL adds a new value to both cache levels, or updates it, expiring after ttl
+func (tieredProvider *TieredProvider) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return tieredProvider.SetWithTTLContext(context.Background(), key, value, ttl)
+}
+
+// SetWithTTLContext adds a new value to both cache levels, or updates it,
+// expiring after ttl. L1 keeps it for L1TimeToLive at most.
+func (tieredProvider *TieredProvider) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := tieredProvider.L2.SetWithTTLContext(ctx, key, value, ttl); err != nil {
+		// Do not keep a value in L1 that L2 does not have
+		tieredProvider.L1.DeleteContext(ctx, key)
+		return err
+	}
+	return tieredProvider.L1.SetWithTTLContext(ctx, key, value, tieredProvider.l1TimeToLive(ttl))
+}
+
+// l1TimeToLive returns the ttl of a value in L1 given its ttl in L2, or zero
+// when unknown. It is always greater than zero.
+func (tieredProvider *TieredProvider) l1TimeToLive(ttl time.Duration) time.Duration {
+	l1TimeToLive := tieredProvider.L1TimeToLive
+	if l1TimeToLive <= 0 {
+		l1TimeToLive = DefaultL1TimeToLive
+	}
+	if ttl <= 0 || ttl > l1TimeToLive {
+		return l1TimeToLive
+	}
+	return ttl
+}
+
+// Get returns a cached value or error if it does not exist
+func (tieredProvider *TieredProvider) Get(key string) ([]byte, error) {
+	return tieredProvider.GetContext(context.Background(), key)
+}
+
+// GetContext returns a cached value from L1, or from L2 filling L1 with it
+func (tieredProvider *TieredProvider) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if value, err := tieredProvider.L1.GetContext(ctx, key); err == nil {
+		return value, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	value, err := tieredProvider.L2.GetContext(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failure to fill L1 only costs a future L2 read
+	tieredProvider.L1.SetWithTTLContext(ctx, key, value, tieredProvider.l1TimeToLive(0))
+	return value, nil
+}
+
+// Delete removes a value from both cache levels
+func (tieredProvider *TieredProvider) Delete(key string) error {
+	return tieredProvider.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext removes a value from both cache levels
+func (tieredProvider *TieredProvider) DeleteContext(ctx context.Context, key string) error {
+	l2Err := tieredProvider.L2.DeleteContext(ctx, key)
+
+	// Some in memory providers fail to delete missing keys
+	var l1Err error
+	if tieredProvider.L1.HasKeyContext(ctx, key) {
+		l1Err = tieredProvider.L1.DeleteContext(ctx, key)
+	}
+	if l2Err != nil {
+		return l2Err
+	}
+	return l1Err
+}
+
+// Reset empties both cache levels
+func (tieredProvider *TieredProvider) Reset() error {
+	return tieredProvider.ResetContext(context.Background())
+}
+
+// ResetContext empties both cache levels
+func (tieredProvider *TieredProvider) ResetContext(ctx context.Context) error {
+	l2Err := tieredProvider.L2.ResetContext(ctx)
+	l1Err := tieredProvider.L1.ResetContext(ctx)
+	if l2Err != nil {
+		return l2Err
+	}
+	return l1Err
+}
+
+// HasKey checks if the key exists in any cache level
+func (tieredProvider *TieredProvider) HasKey(key string) bool {
+	return tieredProvider.HasKeyContext(context.Background(), key)
+}
+
+// HasKeyContext checks if the key exists in any cache level
+func (tieredProvider *TieredProvider) HasKeyContext(ctx context.Context, key string) bool {
+	return tieredProvider.L1.HasKeyContext(ctx, key) || tieredProvider.L2.HasKeyContext(ctx, key)
+}
@@ -0,0 +1,256 @@
+package tiered
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	bcProvider "github.com/josemiguelmelo/gocacheable/providers/bigcache"
+	redisProvider "github.com/josemiguelmelo/gocacheable/providers/redis"
+	"github.com/josemiguelmelo/gocacheable/providertest"
+	"github.com/stretchr/testify/assert"
+)
+
+var redisServer *miniredis.Miniredis
+
+const (
+	cacheKey   = "key"
+	cacheValue = "value"
+)
+
+func NewCacheableStorage(t *testing.T) (*TieredProvider, *bcProvider.BigCacheProvider, *redisProvider.RedisProvider) {
+	redisServer.FlushAll()
+
+	l1 := &bcProvider.BigCacheProvider{Lifetime: 2}
+	l2 := &redisProvider.RedisProvider{Addr: redisServer.Addr(), MaxIdle: 10, MaxActive: 100}
+	tieredProvider := NewTieredProvider(l1, l2, 50*time.Millisecond)
+	assert.Nil(t, tieredProvider.Init())
+	t.Cleanup(func() {
+		l1.Close()
+		l2.Close()
+	})
+	return tieredProvider, l1, l2
+}
+
+func setup() {
+	var err error
+	redisServer, err = miniredis.Run()
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestMain(m *testing.M) {
+	setup()
+	code := m.Run()
+	redisServer.Close()
+	os.Exit(code)
+}
+
+func TestTieredSetWritesBothLevels(t *testing.T) {
+	tieredProvider, l1, l2 := NewCacheableStorage(t)
+
+	err := tieredProvider.SetWithTTL(cacheKey, []byte(cacheValue), time.Minute)
+	assert.Nil(t, err)
+
+	value, err := l1.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(value))
+	value, err = l2.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(value))
+	assert.Equal(t, time.Minute, redisServer.TTL(cacheKey))
+}
+
+func TestTieredGetFillsL1(t *testing.T) {
+	tieredProvider, l1, l2 := NewCacheableStorage(t)
+
+	err := l2.Set(cacheKey, []byte(cacheValue))
+	assert.Nil(t, err)
+	assert.Equal(t, false, l1.HasKey(cacheKey))
+
+	value, err := tieredProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(value))
+	assert.Equal(t, true, l1.HasKey(cacheKey))
+
+	// Served from L1 while it holds the value
+	redisServer.FlushAll()
+	value, err = tieredProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(value))
+
+	_, err = tieredProvider.Get("not_key")
+	assert.NotNil(t, err)
+}
+
+func TestTieredL1TimeToLive(t *testing.T) {
+	tieredProvider, _, l2 := NewCacheableStorage(t)
+
+	err := tieredProvider.SetWithTTL(cacheKey, []byte(cacheValue), time.Minute)
+	assert.Nil(t, err)
+
+	// Another instance updates L2, the change is visible once L1 expires
+	err = l2.Set(cacheKey, []byte("updated"))
+	assert.Nil(t, err)
+	value, err := tieredProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(value))
+
+	time.Sleep(100 * time.Millisecond)
+	value, err = tieredProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", string(value))
+}
+
+// ttlRecorder is an L1 recording the time to live of the values it is given
+type ttlRecorder struct {
+	*bcProvider.BigCacheProvider
+	ttls []time.Duration
+}
+
+func (r *ttlRecorder) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	r.ttls = append(r.ttls, ttl)
+	return r.BigCacheProvider.SetWithTTLContext(ctx, key, value, ttl)
+}
+
+func TestTieredL1AlwaysExpires(t *testing.T) {
+	redisServer.FlushAll()
+
+	for _, l1TimeToLive := range []time.Duration{0, -time.Second} {
+		l1 := &ttlRecorder{BigCacheProvider: &bcProvider.BigCacheProvider{Lifetime: 2}}
+		l2 := &redisProvider.RedisProvider{Addr: redisServer.Addr(), MaxIdle: 10, MaxActive: 100}
+		tieredProvider := NewTieredProvider(l1, l2, l1TimeToLive)
+		assert.Equal(t, DefaultL1TimeToLive, tieredProvider.L1TimeToLive)
+		assert.Nil(t, tieredProvider.Init())
+		defer l1.Close()
+
+		// Written without expiration, and filled from L2
+		assert.Nil(t, tieredProvider.Set(cacheKey, []byte(cacheValue)))
+		assert.Nil(t, l2.Set("other", []byte(cacheValue)))
+		_, err := tieredProvider.Get("other")
+		assert.Nil(t, err)
+
+		assert.Equal(t, []time.Duration{DefaultL1TimeToLive, DefaultL1TimeToLive}, l1.ttls)
+	}
+
+	// Providers built without the constructor as well
+	l1 := &ttlRecorder{BigCacheProvider: &bcProvider.BigCacheProvider{Lifetime: 2}}
+	l2 := &redisProvider.RedisProvider{Addr: redisServer.Addr(), MaxIdle: 10, MaxActive: 100}
+	tieredProvider := &TieredProvider{L1: l1, L2: l2}
+	assert.Nil(t, tieredProvider.Init())
+	defer l1.Close()
+	_, err := tieredProvider.Get("other")
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Duration{DefaultL1TimeToLive}, l1.ttls)
+}
+
+func TestTieredDeleteAndReset(t *testing.T) {
+	tieredProvider, l1, l2 := NewCacheableStorage(t)
+
+	assert.Nil(t, tieredProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Equal(t, true, tieredProvider.HasKey(cacheKey))
+	assert.Nil(t, tieredProvider.Delete(cacheKey))
+	assert.Equal(t, false, l1.HasKey(cacheKey))
+	assert.Equal(t, false, l2.HasKey(cacheKey))
+
+	// Deleting a key only present in L2 succeeds
+	assert.Nil(t, l2.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, tieredProvider.Delete(cacheKey))
+	assert.Equal(t, false, tieredProvider.HasKey(cacheKey))
+
+	assert.Nil(t, tieredProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, tieredProvider.Reset())
+	assert.Equal(t, false, l1.HasKey(cacheKey))
+	assert.Equal(t, false, l2.HasKey(cacheKey))
+}
+
+func TestTieredConformance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) providertest.Fixture {
+		clock := providertest.NewClock()
+		server := miniredis.RunT(t)
+
+		l1 := &bcProvider.BigCacheProvider{Lifetime: 2, Clock: clock.Now}
+		l2 := &redisProvider.RedisProvider{Addr: server.Addr(), MaxIdle: 10, MaxActive: 100}
+		tieredProvider := NewTieredProvider(l1, l2, time.Minute)
+		assert.Nil(t, tieredProvider.Init())
+		t.Cleanup(func() {
+			l1.Close()
+			l2.Close()
+		})
+		return providertest.Fixture{
+			Provider: tieredProvider,
+			Advance: func(d time.Duration) {
+				clock.Advance(d)
+				server.FastForward(d)
+			},
+		}
+	})
+}
+
+// batchRecorder is an L2 recording the keys of its GetMany calls
+type batchRecorder struct {
+	*redisProvider.RedisProvider
+	gets [][]string
+}
+
+func (r *batchRecorder) GetManyContext(ctx context.Context, keys []string) (map[string][]byte, error) {
+	r.gets = append(r.gets, keys)
+	return r.RedisProvider.GetManyContext(ctx, keys)
+}
+
+func TestTieredBatch(t *testing.T) {
+	_, l1, l2 := NewCacheableStorage(t)
+	recorder := &batchRecorder{RedisProvider: l2}
+	tieredProvider := NewTieredProvider(l1, recorder, time.Minute)
+
+	values := map[string][]byte{"a": []byte("value a"), "b": []byte("value b")}
+	assert.Nil(t, tieredProvider.SetMany(values, time.Minute))
+	for key, value := range values {
+		stored, err := l1.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, value, stored)
+		stored, err = l2.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, value, stored)
+	}
+
+	// Keys missing from L1 are read from L2 in a single call, and fill L1
+	assert.Nil(t, l2.Set("c", []byte("value c")))
+	found, err := tieredProvider.GetMany([]string{"a", "c", "missing"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]byte{"a": []byte("value a"), "c": []byte("value c")}, found)
+	assert.Equal(t, [][]string{{"c", "missing"}}, recorder.gets)
+	assert.True(t, l1.HasKey("c"))
+
+	assert.Nil(t, tieredProvider.DeleteMany([]string{"a", "c", "missing"}))
+	for _, key := range []string{"a", "c"} {
+		assert.False(t, l1.HasKey(key))
+		assert.False(t, l2.HasKey(key))
+	}
+	assert.True(t, tieredProvider.HasKey("b"))
+}
+
+func TestTieredTags(t *testing.T) {
+	tieredProvider, l1, l2 := NewCacheableStorage(t)
+
+	assert.Nil(t, tieredProvider.AddTags("a", []string{"tag"}, time.Minute))
+	assert.Nil(t, tieredProvider.AddTags("b", []string{"tag"}, time.Minute))
+	keys, err := l2.PopTag("tag")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, keys)
+
+	assert.Nil(t, tieredProvider.AddTags("a", []string{"tag"}, time.Minute))
+	keys, err = tieredProvider.PopTag("tag")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, keys)
+
+	// Without a level keeping tags, modules keep the index themselves
+	untagged := NewTieredProvider(l1, l1, time.Minute)
+	assert.Equal(t, gcInterfaces.ErrTagsNotSupported, untagged.AddTags("a", []string{"tag"}, time.Minute))
+	_, err = untagged.PopTag("tag")
+	assert.Equal(t, gcInterfaces.ErrTagsNotSupported, err)
+}
@@ -0,0 +1,88 @@
+package tinylfu
+
+import "hash/maphash"
+
+const (
+	sketchDepth      = 4
+	sketchMaxCounter = 15
+	// sketchSampleFactor times the capacity is the number of recorded accesses
+	// after which counters are halved, so that old popularity fades away
+	sketchSampleFactor = 10
+	// sketchWidthFactor times the capacity is the minimum number of counters
+	// per row, keeping collisions between keys rare
+	sketchWidthFactor = 4
+)
+
+// countMinSketch estimates the access frequency of keys in constant space.
+// Counters saturate at 15 as in the TinyLFU paper.
+type countMinSketch struct {
+	seed      maphash.Seed
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := 16
+	for width < capacity*sketchWidthFactor {
+		width *= 2
+	}
+
+	sketch := &countMinSketch{
+		seed:    maphash.MakeSeed(),
+		mask:    uint64(width - 1),
+		resetAt: capacity * sketchSampleFactor,
+	}
+	for i := range sketch.rows {
+		sketch.rows[i] = make([]uint8, width)
+	}
+	return sketch
+}
+
+// indexes returns the counter index of key in every row, using double hashing
+func (s *countMinSketch) indexes(key string) [sketchDepth]uint64 {
+	hash := maphash.String(s.seed, key)
+	low, high := hash, hash>>32|hash<<32
+
+	var indexes [sketchDepth]uint64
+	for i := range indexes {
+		indexes[i] = (low + uint64(i)*high) & s.mask
+	}
+	return indexes
+}
+
+// increment records an access to key
+func (s *countMinSketch) increment(key string) {
+	for row, index := range s.indexes(key) {
+		if s.rows[row][index] < sketchMaxCounter {
+			s.rows[row][index]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+// estimate returns the estimated access frequency of key
+func (s *countMinSketch) estimate(key string) uint8 {
+	estimate := uint8(sketchMaxCounter)
+	for row, index := range s.indexes(key) {
+		if s.rows[row][index] < estimate {
+			estimate = s.rows[row][index]
+		}
+	}
+	return estimate
+}
+
+// reset halves every counter
+func (s *countMinSketch) reset() {
+	for _, row := range s.rows {
+		for i := range row {
+			row[i] /= 2
+		}
+	}
+	s.additions /= 2
+}
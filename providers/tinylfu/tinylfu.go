@@ -0,0 +1,340 @@
+package tinylfu
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+)
+
+// ErrNotFound is returned when a key is not cached or expired
+var ErrNotFound = gcInterfaces.ErrNotFound
+
+const (
+	// defaultMaxEntries is the capacity used when MaxEntries is not set
+	defaultMaxEntries = 10000
+	// windowPercent of the capacity is used by the window LRU
+	windowPercent = 1
+	// protectedPercent of the main region is used by its protected segment
+	protectedPercent = 80
+)
+
+// segments an entry can live in
+const (
+	window = iota
+	probation
+	protected
+)
+
+// NewTinyLFUProvider returns an initialized TinyLFUProvider
+func NewTinyLFUProvider(maxEntries int) *TinyLFUProvider {
+	tinyLFUProvider := &TinyLFUProvider{MaxEntries: maxEntries}
+	tinyLFUProvider.Init()
+	return tinyLFUProvider
+}
+
+// Stats holds the hit and miss counters of a TinyLFUProvider
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+	// Evictions is the number of entries removed to make room for others
+	Evictions uint64
+	// Rejections is the number of new entries the admission filter did not
+	// let into the main region because they were less popular than its victim
+	Rejections uint64
+}
+
+// HitRatio returns the ratio of reads that found the key
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type tinyLFUEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	segment   int
+}
+
+// TinyLFUProvider is an in process storage provider implementing the
+// W-TinyLFU policy. New entries go to a small window LRU and, when evicted
+// from it, are only admitted into the segmented LRU main region if a count-min
+// sketch estimates them more popular than the main region victim. This keeps
+// hot keys cached when many keys are only accessed once.
+type TinyLFUProvider struct {
+	// MaxEntries is the maximum number of entries
+	MaxEntries int
+	// Clock returns the current time, time.Now when nil
+	Clock func() time.Time
+
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	segments   [3]*list.List
+	capacities [3]int
+	sketch     *countMinSketch
+	stats      Stats
+}
+
+// Init initializes tinylfu storage
+func (tinyLFUProvider *TinyLFUProvider) Init() error {
+	return tinyLFUProvider.InitContext(context.Background())
+}
+
+// InitContext initializes tinylfu storage unless ctx is already done
+func (tinyLFUProvider *TinyLFUProvider) InitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tinyLFUProvider.mu.Lock()
+	defer tinyLFUProvider.mu.Unlock()
+
+	maxEntries := tinyLFUProvider.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+
+	windowCapacity := maxEntries * windowPercent / 100
+	if windowCapacity < 1 {
+		windowCapacity = 1
+	}
+	mainCapacity := maxEntries - windowCapacity
+	protectedCapacity := mainCapacity * protectedPercent / 100
+
+	tinyLFUProvider.capacities = [3]int{windowCapacity, mainCapacity - protectedCapacity, protectedCapacity}
+	tinyLFUProvider.entries = map[string]*list.Element{}
+	for i := range tinyLFUProvider.segments {
+		tinyLFUProvider.segments[i] = list.New()
+	}
+	tinyLFUProvider.sketch = newCountMinSketch(maxEntries)
+	tinyLFUProvider.stats = Stats{}
+	return nil
+}
+
+func (tinyLFUProvider *TinyLFUProvider) now() time.Time {
+	if tinyLFUProvider.Clock != nil {
+		return tinyLFUProvider.Clock()
+	}
+	return time.Now()
+}
+
+// Set adds a new value to cache or updates if it already exists
+func (tinyLFUProvider *TinyLFUProvider) Set(key string, value []byte) error {
+	return tinyLFUProvider.SetWithTTLContext(context.Background(), key, value, 0)
+}
+
+// SetContext adds a new value to cache or updates if it already exists
+func (tinyLFUProvider *TinyLFUProvider) SetContext(ctx context.Context, key string, value []byte) error {
+	return tinyLFUProvider.SetWithTTLContext(ctx, key, value, 0)
+}
+
+// SetWithTTL adds a new value to cache, or updates it, expiring after ttl
+func (tinyLFUProvider *TinyLFUProvider) SetWithTTL(key string, value []byte, ttl time.Duration) error {
+	return tinyLFUProvider.SetWithTTLContext(context.Background(), key, value, ttl)
+}
+
+// SetWithTTLContext adds a new value to cache, or updates it, expiring after
+// ttl. A new value may be dropped by the admission filter later on, when it
+// leaves the window.
+func (tinyLFUProvider *TinyLFUProvider) SetWithTTLContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = tinyLFUProvider.now().Add(ttl)
+	}
+
+	tinyLFUProvider.mu.Lock()
+	defer tinyLFUProvider.mu.Unlock()
+
+	if element, ok := tinyLFUProvider.entries[key]; ok {
+		e := element.Value.(*tinyLFUEntry)
+		e.value = append([]byte{}, value...)
+		e.expiresAt = expiresAt
+		tinyLFUProvider.sketch.increment(key)
+		tinyLFUProvider.touch(element)
+		return nil
+	}
+
+	tinyLFUProvider.sketch.increment(key)
+	e := &tinyLFUEntry{key: key, value: append([]byte{}, value...), expiresAt: expiresAt, segment: window}
+	tinyLFUProvider.entries[key] = tinyLFUProvider.segments[window].PushFront(e)
+
+	if tinyLFUProvider.segments[window].Len() > tinyLFUProvider.capacities[window] {
+		tinyLFUProvider.admit(tinyLFUProvider.segments[window].Back())
+	}
+	return nil
+}
+
+// admit moves the candidate evicted from the window into the main region, if
+// it has room or the candidate is more popular than the main region victim.
+// Must be called with mu held.
+func (tinyLFUProvider *TinyLFUProvider) admit(candidate *list.Element) {
+	mainCapacity := tinyLFUProvider.capacities[probation] + tinyLFUProvider.capacities[protected]
+	if tinyLFUProvider.segments[probation].Len()+tinyLFUProvider.segments[protected].Len() < mainCapacity {
+		tinyLFUProvider.move(candidate, probation)
+		return
+	}
+
+	victim := tinyLFUProvider.segments[probation].Back()
+	if victim == nil {
+		victim = tinyLFUProvider.segments[protected].Back()
+	}
+	if victim == nil {
+		// No main region at all, the window is the whole cache
+		tinyLFUProvider.remove(candidate)
+		tinyLFUProvider.stats.Evictions++
+		return
+	}
+
+	candidateKey := candidate.Value.(*tinyLFUEntry).key
+	victimKey := victim.Value.(*tinyLFUEntry).key
+	if tinyLFUProvider.sketch.estimate(candidateKey) > tinyLFUProvider.sketch.estimate(victimKey) {
+		tinyLFUProvider.remove(victim)
+		tinyLFUProvider.stats.Evictions++
+		tinyLFUProvider.move(candidate, probation)
+		return
+	}
+
+	tinyLFUProvider.remove(candidate)
+	tinyLFUProvider.stats.Rejections++
+}
+
+// touch records a hit on element. Probation entries are promoted to the
+// protected segment, whose overflow is demoted back to probation. Must be
+// called with mu held.
+func (tinyLFUProvider *TinyLFUProvider) touch(element *list.Element) {
+	e := element.Value.(*tinyLFUEntry)
+	switch e.segment {
+	case window, protected:
+		tinyLFUProvider.segments[e.segment].MoveToFront(element)
+	case probation:
+		tinyLFUProvider.move(element, protected)
+		if tinyLFUProvider.segments[protected].Len() > tinyLFUProvider.capacities[protected] {
+			tinyLFUProvider.move(tinyLFUProvider.segments[protected].Back(), probation)
+		}
+	}
+}
+
+// move places element at the front of segment. Must be called with mu held.
+func (tinyLFUProvider *TinyLFUProvider) move(element *list.Element, segment int) {
+	e := element.Value.(*tinyLFUEntry)
+	tinyLFUProvider.segments[e.segment].Remove(element)
+	e.segment = segment
+	tinyLFUProvider.entries[e.key] = tinyLFUProvider.segments[segment].PushFront(e)
+}
+
+// remove deletes element from the cache. Must be called with mu held.
+func (tinyLFUProvider *TinyLFUProvider) remove(element *list.Element) {
+	e := element.Value.(*tinyLFUEntry)
+	tinyLFUProvider.segments[e.segment].Remove(element)
+	delete(tinyLFUProvider.entries, e.key)
+}
+
+// lookup returns the element of key, removing it if expired. Must be called with mu held.
+func (tinyLFUProvider *TinyLFUProvider) lookup(key string) *list.Element {
+	element, ok := tinyLFUProvider.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := element.Value.(*tinyLFUEntry)
+	if !e.expiresAt.IsZero() && !tinyLFUProvider.now().Before(e.expiresAt) {
+		tinyLFUProvider.remove(element)
+		return nil
+	}
+	return element
+}
+
+// Get returns a cached value or error if it does not exist
+func (tinyLFUProvider *TinyLFUProvider) Get(key string) ([]byte, error) {
+	return tinyLFUProvider.GetContext(context.Background(), key)
+}
+
+// GetContext returns a cached value or error if it does not exist. Every read
+// is recorded by the frequency sketch, hit or miss.
+func (tinyLFUProvider *TinyLFUProvider) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tinyLFUProvider.mu.Lock()
+	defer tinyLFUProvider.mu.Unlock()
+
+	tinyLFUProvider.sketch.increment(key)
+	element := tinyLFUProvider.lookup(key)
+	if element == nil {
+		tinyLFUProvider.stats.Misses++
+		return nil, ErrNotFound
+	}
+
+	tinyLFUProvider.stats.Hits++
+	tinyLFUProvider.touch(element)
+	return append([]byte{}, element.Value.(*tinyLFUEntry).value...), nil
+}
+
+// Delete removes a value from the cache
+func (tinyLFUProvider *TinyLFUProvider) Delete(key string) error {
+	return tinyLFUProvider.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext removes a value from the cache. Deleting a missing key is not an error.
+func (tinyLFUProvider *TinyLFUProvider) DeleteContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tinyLFUProvider.mu.Lock()
+	defer tinyLFUProvider.mu.Unlock()
+	if element, ok := tinyLFUProvider.entries[key]; ok {
+		tinyLFUProvider.remove(element)
+	}
+	return nil
+}
+
+// Reset empties cache storage, forgetting the key frequencies and statistics
+func (tinyLFUProvider *TinyLFUProvider) Reset() error {
+	return tinyLFUProvider.ResetContext(context.Background())
+}
+
+// ResetContext empties cache storage, forgetting the key frequencies and statistics
+func (tinyLFUProvider *TinyLFUProvider) ResetContext(ctx context.Context) error {
+	return tinyLFUProvider.InitContext(ctx)
+}
+
+// HasKey checks if the key exists
+func (tinyLFUProvider *TinyLFUProvider) HasKey(key string) bool {
+	return tinyLFUProvider.HasKeyContext(context.Background(), key)
+}
+
+// HasKeyContext checks if the key exists, without recording an access
+func (tinyLFUProvider *TinyLFUProvider) HasKeyContext(ctx context.Context, key string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	tinyLFUProvider.mu.Lock()
+	defer tinyLFUProvider.mu.Unlock()
+	return tinyLFUProvider.lookup(key) != nil
+}
+
+// Len returns the number of entries, including expired ones not yet removed
+func (tinyLFUProvider *TinyLFUProvider) Len() int {
+	tinyLFUProvider.mu.Lock()
+	defer tinyLFUProvider.mu.Unlock()
+	return len(tinyLFUProvider.entries)
+}
+
+// Stats returns a snapshot of the hit and miss counters
+func (tinyLFUProvider *TinyLFUProvider) Stats() Stats {
+	tinyLFUProvider.mu.Lock()
+	defer tinyLFUProvider.mu.Unlock()
+	return tinyLFUProvider.stats
+}
@@ -0,0 +1,204 @@
+package tinylfu
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/josemiguelmelo/gocacheable/providers/lru"
+	"github.com/josemiguelmelo/gocacheable/providertest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	cacheKey   = "key"
+	cacheValue = "value"
+)
+
+func NewCacheableStorage(maxEntries int) (*TinyLFUProvider, *providertest.Clock) {
+	clock := providertest.NewClock()
+	tinyLFUProvider := &TinyLFUProvider{MaxEntries: maxEntries, Clock: clock.Now}
+	tinyLFUProvider.Init()
+	return tinyLFUProvider, clock
+}
+
+func TestTinyLFUSetAndGet(t *testing.T) {
+	tinyLFUProvider := NewTinyLFUProvider(0)
+
+	_, err := tinyLFUProvider.Get(cacheKey)
+	assert.Equal(t, ErrNotFound, err)
+
+	assert.Nil(t, tinyLFUProvider.Set(cacheKey, []byte(cacheValue)))
+	value, err := tinyLFUProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(value))
+
+	assert.Nil(t, tinyLFUProvider.Set(cacheKey, []byte("updated")))
+	value, err = tinyLFUProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", string(value))
+	assert.Equal(t, 1, tinyLFUProvider.Len())
+}
+
+func TestTinyLFUStats(t *testing.T) {
+	tinyLFUProvider, _ := NewCacheableStorage(100)
+	assert.Equal(t, float64(0), tinyLFUProvider.Stats().HitRatio())
+
+	assert.Nil(t, tinyLFUProvider.Set(cacheKey, []byte(cacheValue)))
+	for i := 0; i < 3; i++ {
+		_, err := tinyLFUProvider.Get(cacheKey)
+		assert.Nil(t, err)
+	}
+	_, err := tinyLFUProvider.Get("missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	stats := tinyLFUProvider.Stats()
+	assert.Equal(t, uint64(3), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 0.75, stats.HitRatio())
+
+	assert.Nil(t, tinyLFUProvider.Reset())
+	assert.Equal(t, Stats{}, tinyLFUProvider.Stats())
+}
+
+func TestTinyLFUBoundedByMaxEntries(t *testing.T) {
+	tinyLFUProvider, _ := NewCacheableStorage(100)
+
+	for i := 0; i < 1000; i++ {
+		assert.Nil(t, tinyLFUProvider.Set(fmt.Sprintf("key%d", i), []byte(cacheValue)))
+		assert.True(t, tinyLFUProvider.Len() <= 100)
+	}
+	stats := tinyLFUProvider.Stats()
+	assert.Equal(t, uint64(900), stats.Evictions+stats.Rejections)
+}
+
+func TestTinyLFUKeepsFrequentKeysDuringScan(t *testing.T) {
+	tinyLFUProvider, _ := NewCacheableStorage(100)
+
+	for i := 0; i < 50; i++ {
+		assert.Nil(t, tinyLFUProvider.Set(fmt.Sprintf("hot%d", i), []byte(cacheValue)))
+	}
+	for j := 0; j < 5; j++ {
+		for i := 0; i < 50; i++ {
+			tinyLFUProvider.Get(fmt.Sprintf("hot%d", i))
+		}
+	}
+
+	// A scan of keys read only once must not flush the frequently used ones,
+	// which keep being read meanwhile
+	for i := 0; i < 1000; i++ {
+		assert.Nil(t, tinyLFUProvider.Set(fmt.Sprintf("scan%d", i), []byte(cacheValue)))
+		tinyLFUProvider.Get(fmt.Sprintf("hot%d", i%50))
+	}
+
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, true, tinyLFUProvider.HasKey(fmt.Sprintf("hot%d", i)))
+	}
+	assert.True(t, tinyLFUProvider.Stats().Rejections > 0)
+}
+
+func TestTinyLFUHitRatioAgainstLRU(t *testing.T) {
+	tinyLFUProvider, _ := NewCacheableStorage(100)
+	lruProvider := lru.NewLRUProvider(100, 0)
+
+	// Skewed workload over many more keys than the caches can hold, where a
+	// miss is followed by caching the key as a loader would
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, 10000)
+	lruHits := 0
+	for i := 0; i < 100000; i++ {
+		key := fmt.Sprintf("key%d", zipf.Uint64())
+		if _, err := tinyLFUProvider.Get(key); err != nil {
+			tinyLFUProvider.Set(key, []byte(cacheValue))
+		}
+		if _, err := lruProvider.Get(key); err != nil {
+			lruProvider.Set(key, []byte(cacheValue))
+		} else {
+			lruHits++
+		}
+	}
+
+	assert.True(t, tinyLFUProvider.Stats().HitRatio() > float64(lruHits)/100000)
+}
+
+func TestTinyLFUTimeToLive(t *testing.T) {
+	tinyLFUProvider, clock := NewCacheableStorage(0)
+
+	assert.Nil(t, tinyLFUProvider.SetWithTTL(cacheKey, []byte(cacheValue), time.Minute))
+	assert.Nil(t, tinyLFUProvider.Set("no_ttl", []byte(cacheValue)))
+
+	clock.Advance(59 * time.Second)
+	assert.Equal(t, true, tinyLFUProvider.HasKey(cacheKey))
+
+	clock.Advance(time.Second)
+	_, err := tinyLFUProvider.Get(cacheKey)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, 1, tinyLFUProvider.Len())
+
+	clock.Advance(24 * time.Hour)
+	assert.Equal(t, true, tinyLFUProvider.HasKey("no_ttl"))
+}
+
+func TestTinyLFUDeleteAndReset(t *testing.T) {
+	tinyLFUProvider, _ := NewCacheableStorage(0)
+
+	assert.Nil(t, tinyLFUProvider.Delete(cacheKey))
+	assert.Nil(t, tinyLFUProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, tinyLFUProvider.Delete(cacheKey))
+	assert.Equal(t, false, tinyLFUProvider.HasKey(cacheKey))
+
+	assert.Nil(t, tinyLFUProvider.Set(cacheKey, []byte(cacheValue)))
+	assert.Nil(t, tinyLFUProvider.Reset())
+	assert.Equal(t, 0, tinyLFUProvider.Len())
+}
+
+func TestTinyLFUReturnsCopies(t *testing.T) {
+	tinyLFUProvider, _ := NewCacheableStorage(0)
+
+	value := []byte(cacheValue)
+	assert.Nil(t, tinyLFUProvider.Set(cacheKey, value))
+	value[0] = 'V'
+
+	cached, err := tinyLFUProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(cached))
+	cached[0] = 'V'
+
+	cached, err = tinyLFUProvider.Get(cacheKey)
+	assert.Nil(t, err)
+	assert.Equal(t, cacheValue, string(cached))
+}
+
+func TestTinyLFUContextCanceled(t *testing.T) {
+	tinyLFUProvider, _ := NewCacheableStorage(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, tinyLFUProvider.InitContext(ctx))
+	assert.Equal(t, context.Canceled, tinyLFUProvider.SetContext(ctx, cacheKey, []byte(cacheValue)))
+	_, err := tinyLFUProvider.GetContext(ctx, cacheKey)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, tinyLFUProvider.DeleteContext(ctx, cacheKey))
+	assert.Equal(t, context.Canceled, tinyLFUProvider.ResetContext(ctx))
+	assert.Equal(t, false, tinyLFUProvider.HasKeyContext(ctx, cacheKey))
+}
+
+func TestCountMinSketch(t *testing.T) {
+	sketch := newCountMinSketch(100)
+
+	for i := 0; i < 20; i++ {
+		sketch.increment(cacheKey)
+	}
+	assert.Equal(t, uint8(sketchMaxCounter), sketch.estimate(cacheKey))
+
+	sketch.reset()
+	assert.Equal(t, uint8(sketchMaxCounter/2), sketch.estimate(cacheKey))
+}
+
+func TestTinyLFUConformance(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) providertest.Fixture {
+		tinyLFUProvider, clock := NewCacheableStorage(0)
+		return providertest.Fixture{Provider: tinyLFUProvider, Advance: clock.Advance}
+	})
+}
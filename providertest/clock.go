@@ -0,0 +1,32 @@
+package providertest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a manually advanced clock, to be given to providers as the current
+// time used for expiration. Its Advance method can be set as Fixture.Advance.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a clock set at a fixed time
+func NewClock() *Clock {
+	return &Clock{now: time.Unix(1557833581, 0)}
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,271 @@
+// Package providertest checks that a storage provider behaves as gocacheable
+// expects from a CacheProviderInterface implementation. Provider authors can
+// run it from their own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		providertest.Run(t, func(t *testing.T) providertest.Fixture {
+//			provider := &MyProvider{}
+//			provider.Init()
+//			return providertest.Fixture{Provider: provider}
+//		})
+//	}
+//
+// Run the tests with -race to also detect data races.
+package providertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gcInterfaces "github.com/josemiguelmelo/gocacheable/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+// defaultTTLResolution is the expiration resolution assumed when the fixture
+// does not set one
+const defaultTTLResolution = time.Millisecond
+
+// Fixture is a provider under test
+type Fixture struct {
+	// Provider is initialized and empty, and not shared with other fixtures
+	Provider gcInterfaces.CacheProviderInterface
+	// Advance moves the clock used by the provider to expire entries forward.
+	// When nil, the time to live tests sleep instead.
+	Advance func(d time.Duration)
+	// TTLResolution is the precision of the storage expiration, such as a
+	// second for memcached. A millisecond when zero.
+	TTLResolution time.Duration
+}
+
+func (f Fixture) advance(d time.Duration) {
+	if f.Advance != nil {
+		f.Advance(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+func (f Fixture) ttl() time.Duration {
+	if f.TTLResolution > 0 {
+		return 10 * f.TTLResolution
+	}
+	return 10 * defaultTTLResolution
+}
+
+// Factory returns a new fixture for every test. Cleanups can be registered
+// on t.
+type Factory func(t *testing.T) Fixture
+
+// Run runs the conformance tests against the providers returned by factory
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, f Fixture)
+	}{
+		{"MissingKey", testMissingKey},
+		{"SetAndGet", testSetAndGet},
+		{"Overwrite", testOverwrite},
+		{"ValueIsolation", testValueIsolation},
+		{"Delete", testDelete},
+		{"Reset", testReset},
+		{"HasKey", testHasKey},
+		{"TimeToLive", testTimeToLive},
+		{"NoTimeToLive", testNoTimeToLive},
+		{"OverwriteRemovesTimeToLive", testOverwriteRemovesTimeToLive},
+		{"ContextCanceled", testContextCanceled},
+		{"Concurrency", testConcurrency},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, factory(t))
+		})
+	}
+}
+
+func testMissingKey(t *testing.T, f Fixture) {
+	value, err := f.Provider.Get("missing")
+	assert.True(t, errors.Is(err, gcInterfaces.ErrNotFound), "Get of a missing key must fail with ErrNotFound")
+	assert.Empty(t, value)
+	assert.Equal(t, false, f.Provider.HasKey("missing"))
+}
+
+func testSetAndGet(t *testing.T, f Fixture) {
+	values := map[string][]byte{
+		"text":   []byte("value"),
+		"binary": {0x00, 0xff, '\r', '\n', 0x7f, ' '},
+		"large":  bytes.Repeat([]byte("0123456789"), 10*1024),
+	}
+
+	for key, expected := range values {
+		assert.Nil(t, f.Provider.Set(key, expected))
+	}
+	for key, expected := range values {
+		value, err := f.Provider.Get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, value, "value of %s", key)
+	}
+}
+
+func testOverwrite(t *testing.T, f Fixture) {
+	assert.Nil(t, f.Provider.Set("key", []byte("first")))
+	assert.Nil(t, f.Provider.Set("key", []byte("second")))
+
+	value, err := f.Provider.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "second", string(value))
+}
+
+func testValueIsolation(t *testing.T, f Fixture) {
+	value := []byte("value")
+	assert.Nil(t, f.Provider.Set("key", value))
+	value[0] = 'V'
+
+	cached, err := f.Provider.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", string(cached), "changing a value after Set must not change the cache")
+	if len(cached) > 0 {
+		cached[0] = 'V'
+	}
+
+	cached, err = f.Provider.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", string(cached), "changing a value returned by Get must not change the cache")
+}
+
+func testDelete(t *testing.T, f Fixture) {
+	assert.Nil(t, f.Provider.Set("key", []byte("value")))
+	assert.Nil(t, f.Provider.Set("other", []byte("value")))
+
+	assert.Nil(t, f.Provider.Delete("key"))
+	_, err := f.Provider.Get("key")
+	assert.NotNil(t, err)
+	assert.Equal(t, true, f.Provider.HasKey("other"))
+
+	assert.Nil(t, f.Provider.Delete("key"), "deleting a missing key must not fail")
+	assert.Nil(t, f.Provider.Delete("never_set"), "deleting a missing key must not fail")
+}
+
+func testReset(t *testing.T, f Fixture) {
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, f.Provider.Set(fmt.Sprintf("key%d", i), []byte("value")))
+	}
+
+	assert.Nil(t, f.Provider.Reset())
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, false, f.Provider.HasKey(fmt.Sprintf("key%d", i)))
+	}
+
+	// The provider is still usable after a reset
+	assert.Nil(t, f.Provider.Set("key", []byte("value")))
+	value, err := f.Provider.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", string(value))
+}
+
+func testHasKey(t *testing.T, f Fixture) {
+	assert.Equal(t, false, f.Provider.HasKey("key"))
+	assert.Nil(t, f.Provider.Set("key", []byte("value")))
+	assert.Equal(t, true, f.Provider.HasKey("key"))
+
+	// HasKey does not consume the value
+	value, err := f.Provider.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", string(value))
+}
+
+func testTimeToLive(t *testing.T, f Fixture) {
+	ttl := f.ttl()
+	assert.Nil(t, f.Provider.SetWithTTL("key", []byte("value"), ttl))
+	assert.Nil(t, f.Provider.SetWithTTLContext(context.Background(), "context", []byte("value"), ttl))
+
+	value, err := f.Provider.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", string(value))
+
+	f.advance(2 * ttl)
+	_, err = f.Provider.Get("key")
+	assert.NotNil(t, err, "entries must expire after their time to live")
+	assert.Equal(t, false, f.Provider.HasKey("key"))
+	assert.Equal(t, false, f.Provider.HasKey("context"))
+}
+
+func testNoTimeToLive(t *testing.T, f Fixture) {
+	assert.Nil(t, f.Provider.Set("set", []byte("value")))
+	assert.Nil(t, f.Provider.SetWithTTL("zero", []byte("value"), 0))
+	assert.Nil(t, f.Provider.SetWithTTL("negative", []byte("value"), -time.Second))
+
+	f.advance(2 * f.ttl())
+	for _, key := range []string{"set", "zero", "negative"} {
+		assert.Equal(t, true, f.Provider.HasKey(key), "%s must not expire", key)
+	}
+}
+
+func testOverwriteRemovesTimeToLive(t *testing.T, f Fixture) {
+	assert.Nil(t, f.Provider.SetWithTTL("key", []byte("first"), f.ttl()))
+	assert.Nil(t, f.Provider.Set("key", []byte("second")))
+
+	f.advance(2 * f.ttl())
+	value, err := f.Provider.Get("key")
+	assert.Nil(t, err, "Set must replace the time to live of an entry")
+	assert.Equal(t, "second", string(value))
+}
+
+func testContextCanceled(t *testing.T, f Fixture) {
+	assert.Nil(t, f.Provider.Set("key", []byte("value")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, f.Provider.SetContext(ctx, "key", []byte("updated")))
+	assert.Equal(t, context.Canceled, f.Provider.SetWithTTLContext(ctx, "key", []byte("updated"), time.Minute))
+	_, err := f.Provider.GetContext(ctx, "key")
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, context.Canceled, f.Provider.DeleteContext(ctx, "key"))
+	assert.Equal(t, context.Canceled, f.Provider.ResetContext(ctx))
+	assert.Equal(t, false, f.Provider.HasKeyContext(ctx, "key"))
+
+	// Nothing was changed
+	value, err := f.Provider.Get("key")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", string(value))
+}
+
+func testConcurrency(t *testing.T, f Fixture) {
+	const (
+		goroutines = 8
+		operations = 200
+		keys       = 16
+	)
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < operations; i++ {
+				key := fmt.Sprintf("key%d", (g+i)%keys)
+				switch i % 4 {
+				case 0:
+					assert.Nil(t, f.Provider.Set(key, []byte(fmt.Sprintf("value-%d-%d", g, i))))
+				case 1:
+					if value, err := f.Provider.Get(key); err == nil {
+						assert.True(t, strings.HasPrefix(string(value), "value-"), "unexpected value %q", value)
+					}
+				case 2:
+					f.Provider.HasKey(key)
+				case 3:
+					assert.Nil(t, f.Provider.Delete(key))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,163 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram buckets
+var latencyBuckets = [...]time.Duration{
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram counts latencies in fixed buckets. Observing a latency only takes
+// atomic operations, so it is safe for concurrent use.
+type Histogram struct {
+	// counts has a last bucket for the latencies above every bound
+	counts [len(latencyBuckets) + 1]atomic.Uint64
+	sum    atomic.Int64
+}
+
+// Observe records a latency
+func (h *Histogram) Observe(latency time.Duration) {
+	i := sort.Search(len(latencyBuckets), func(i int) bool {
+		return latency <= latencyBuckets[i]
+	})
+	h.counts[i].Add(1)
+	h.sum.Add(int64(latency))
+}
+
+// Snapshot returns the latencies observed so far
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snapshot := HistogramSnapshot{Buckets: make([]Bucket, len(latencyBuckets))}
+	for i := range h.counts {
+		snapshot.Count += h.counts[i].Load()
+		if i < len(latencyBuckets) {
+			snapshot.Buckets[i] = Bucket{UpperBound: latencyBuckets[i], Count: snapshot.Count}
+		}
+	}
+	snapshot.Sum = time.Duration(h.sum.Load())
+	return snapshot
+}
+
+// Bucket is a bucket of a histogram
+type Bucket struct {
+	UpperBound time.Duration
+	// Count is the number of latencies lower or equal to UpperBound
+	Count uint64
+}
+
+// HistogramSnapshot holds the latencies observed by a histogram. As counters
+// are read one at a time while latencies are being observed, they may be off
+// by the observations in progress.
+type HistogramSnapshot struct {
+	// Count is the number of latencies observed
+	Count uint64
+	// Sum is the total of the latencies observed
+	Sum time.Duration
+	// Buckets are cumulative, latencies above the last bound are only
+	// included in Count
+	Buckets []Bucket
+}
+
+// Mean returns the mean latency, or zero if none was observed
+func (s HistogramSnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile returns the upper bound of the bucket holding the q quantile, with
+// q between 0 and 1. Latencies above every bound are reported as the last
+// bound. It returns zero if no latency was observed.
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 || len(s.Buckets) == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(s.Count)))
+	for _, b := range s.Buckets {
+		if b.Count >= rank && b.Count > 0 {
+			return b.UpperBound
+		}
+	}
+	return s.Buckets[len(s.Buckets)-1].UpperBound
+}
+
+// sizeBuckets are the upper bounds, in bytes, of the size histogram buckets
+var sizeBuckets = [...]int{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+
+// SizeHistogram counts sizes in bytes in fixed buckets. Observing a size only
+// takes atomic operations, so it is safe for concurrent use.
+type SizeHistogram struct {
+	// counts has a last bucket for the sizes above every bound
+	counts [len(sizeBuckets) + 1]atomic.Uint64
+	sum    atomic.Int64
+}
+
+// Observe records a size in bytes
+func (h *SizeHistogram) Observe(size int) {
+	i := sort.Search(len(sizeBuckets), func(i int) bool {
+		return size <= sizeBuckets[i]
+	})
+	h.counts[i].Add(1)
+	h.sum.Add(int64(size))
+}
+
+// Snapshot returns the sizes observed so far
+func (h *SizeHistogram) Snapshot() SizeHistogramSnapshot {
+	snapshot := SizeHistogramSnapshot{Buckets: make([]SizeBucket, len(sizeBuckets))}
+	for i := range h.counts {
+		snapshot.Count += h.counts[i].Load()
+		if i < len(sizeBuckets) {
+			snapshot.Buckets[i] = SizeBucket{UpperBound: sizeBuckets[i], Count: snapshot.Count}
+		}
+	}
+	snapshot.Sum = h.sum.Load()
+	return snapshot
+}
+
+// SizeBucket is a bucket of a size histogram
+type SizeBucket struct {
+	// UpperBound is a size in bytes
+	UpperBound int
+	// Count is the number of sizes lower or equal to UpperBound
+	Count uint64
+}
+
+// SizeHistogramSnapshot holds the sizes observed by a size histogram
+type SizeHistogramSnapshot struct {
+	// Count is the number of sizes observed
+	Count uint64
+	// Sum is the total of the sizes observed, in bytes
+	Sum int64
+	// Buckets are cumulative, sizes above the last bound are only included
+	// in Count
+	Buckets []SizeBucket
+}
+
+// Mean returns the mean size in bytes, or zero if none was observed
+func (s SizeHistogramSnapshot) Mean() int64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / int64(s.Count)
+}
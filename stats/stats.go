@@ -0,0 +1,202 @@
+package stats
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Operation is an operation of a cache provider
+type Operation int
+
+const (
+	// Get reads a key
+	Get Operation = iota
+	// Set writes a key
+	Set
+	// Delete removes a key
+	Delete
+	// Reset empties the cache storage
+	Reset
+	// HasKey checks if a key exists
+	HasKey
+	// GetMany reads several keys in a single call
+	GetMany
+	// SetMany writes several keys in a single call
+	SetMany
+	// DeleteMany removes several keys in a single call
+	DeleteMany
+	// AddTags adds a key to the index of its tags
+	AddTags
+	// PopTag reads and removes the index of a tag
+	PopTag
+
+	operationCount
+)
+
+func (op Operation) String() string {
+	switch op {
+	case Get:
+		return "get"
+	case Set:
+		return "set"
+	case Delete:
+		return "delete"
+	case Reset:
+		return "reset"
+	case HasKey:
+		return "has_key"
+	case GetMany:
+		return "get_many"
+	case SetMany:
+		return "set_many"
+	case DeleteMany:
+		return "delete_many"
+	case AddTags:
+		return "add_tags"
+	case PopTag:
+		return "pop_tag"
+	default:
+		return "unknown"
+	}
+}
+
+// Recorder records the statistics of a cache module. Recording only takes
+// atomic operations, so it is safe for concurrent use and cheap enough to be
+// always enabled.
+type Recorder struct {
+	hits           atomic.Uint64
+	misses         atomic.Uint64
+	loaderCalls    atomic.Uint64
+	loaderErrors   atomic.Uint64
+	sets           atomic.Uint64
+	deletes        atomic.Uint64
+	providerErrors atomic.Uint64
+	loading        atomic.Int64
+
+	provider   [operationCount]Histogram
+	loader     Histogram
+	valueSizes SizeHistogram
+}
+
+// NewRecorder returns a recorder with every counter at zero
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// RecordHit records a read finding a usable entry
+func (r *Recorder) RecordHit() {
+	r.hits.Add(1)
+}
+
+// RecordMiss records a read finding no usable entry
+func (r *Recorder) RecordMiss() {
+	r.misses.Add(1)
+}
+
+// RecordSets records n entries written
+func (r *Recorder) RecordSets(n int) {
+	r.sets.Add(uint64(n))
+}
+
+// RecordValueSize records the size in bytes of a value written to the provider
+func (r *Recorder) RecordValueSize(size int) {
+	r.valueSizes.Observe(size)
+}
+
+// RecordDeletes records n keys removed
+func (r *Recorder) RecordDeletes(n int) {
+	r.deletes.Add(uint64(n))
+}
+
+// ObserveProvider records a provider operation which took latency, and
+// returned err
+func (r *Recorder) ObserveProvider(op Operation, latency time.Duration, err error) {
+	if op >= 0 && op < operationCount {
+		r.provider[op].Observe(latency)
+	}
+	if err != nil {
+		r.providerErrors.Add(1)
+	}
+}
+
+// StartLoader records a loader call in progress. The function returned
+// records its end, with the error the loader returned.
+func (r *Recorder) StartLoader() func(err error) {
+	r.loading.Add(1)
+	start := time.Now()
+	return func(err error) {
+		r.loading.Add(-1)
+		r.ObserveLoader(time.Since(start), err)
+	}
+}
+
+// ObserveLoader records a loader call which took latency, and returned err
+func (r *Recorder) ObserveLoader(latency time.Duration, err error) {
+	r.loaderCalls.Add(1)
+	r.loader.Observe(latency)
+	if err != nil {
+		r.loaderErrors.Add(1)
+	}
+}
+
+// Snapshot returns the statistics recorded so far
+func (r *Recorder) Snapshot() Stats {
+	s := Stats{
+		Hits:            r.hits.Load(),
+		Misses:          r.misses.Load(),
+		LoaderCalls:     r.loaderCalls.Load(),
+		LoaderErrors:    r.loaderErrors.Load(),
+		Sets:            r.sets.Load(),
+		Deletes:         r.deletes.Load(),
+		ProviderErrors:  r.providerErrors.Load(),
+		LoadersInFlight: r.loading.Load(),
+		ProviderLatency: map[Operation]HistogramSnapshot{},
+		LoaderLatency:   r.loader.Snapshot(),
+		ValueSizes:      r.valueSizes.Snapshot(),
+	}
+	for op := range r.provider {
+		if latency := r.provider[op].Snapshot(); latency.Count > 0 {
+			s.ProviderLatency[Operation(op)] = latency
+		}
+	}
+	return s
+}
+
+// Stats are the statistics of a cache module
+type Stats struct {
+	// Hits counts the reads finding a usable entry, stale or not
+	Hits uint64
+	// Misses counts the reads finding no usable entry
+	Misses uint64
+	// LoaderCalls counts the loader calls, concurrent loads sharing a call
+	// counting once
+	LoaderCalls uint64
+	// LoaderErrors counts the loader calls returning an error
+	LoaderErrors uint64
+	// Sets counts the entries written, including cached loader errors
+	Sets uint64
+	// Deletes counts the keys removed
+	Deletes uint64
+	// ProviderErrors counts the provider operations returning an error.
+	// Reading a missing key is a miss, not an error.
+	ProviderErrors uint64
+	// LoadersInFlight is the number of loader calls in progress
+	LoadersInFlight int64
+	// ProviderLatency holds the latency of the provider operations performed
+	ProviderLatency map[Operation]HistogramSnapshot
+	// LoaderLatency holds the latency of the loader calls
+	LoaderLatency HistogramSnapshot
+	// ValueSizes holds the sizes of the values written to the provider, once
+	// encoded, compressed and encrypted
+	ValueSizes SizeHistogramSnapshot
+}
+
+// HitRatio returns the share of reads finding a usable entry, or zero if
+// nothing was read
+func (s Stats) HitRatio() float64 {
+	reads := s.Hits + s.Misses
+	if reads == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(reads)
+}
@@ -0,0 +1,114 @@
+package stats
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	assert.Equal(t, time.Duration(0), h.Snapshot().Mean())
+	assert.Equal(t, time.Duration(0), h.Snapshot().Quantile(0.5))
+
+	for _, latency := range []time.Duration{10 * time.Microsecond, time.Millisecond, 3 * time.Millisecond, time.Minute} {
+		h.Observe(latency)
+	}
+
+	snapshot := h.Snapshot()
+	assert.Equal(t, uint64(4), snapshot.Count)
+	assert.Equal(t, time.Minute+4010*time.Microsecond, snapshot.Sum)
+	assert.Equal(t, snapshot.Sum/4, snapshot.Mean())
+	assert.Equal(t, len(latencyBuckets), len(snapshot.Buckets))
+
+	// Buckets are cumulative, the latency of a minute is above every bound
+	assert.Equal(t, Bucket{UpperBound: 50 * time.Microsecond, Count: 1}, snapshot.Buckets[0])
+	assert.Equal(t, Bucket{UpperBound: time.Millisecond, Count: 2}, snapshot.Buckets[4])
+	assert.Equal(t, Bucket{UpperBound: 5 * time.Millisecond, Count: 3}, snapshot.Buckets[6])
+	assert.Equal(t, uint64(3), snapshot.Buckets[len(snapshot.Buckets)-1].Count)
+
+	assert.Equal(t, 50*time.Microsecond, snapshot.Quantile(0))
+	assert.Equal(t, time.Millisecond, snapshot.Quantile(0.5))
+	assert.Equal(t, 5*time.Millisecond, snapshot.Quantile(0.75))
+	assert.Equal(t, 10*time.Second, snapshot.Quantile(1))
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	assert.Equal(t, float64(0), r.Snapshot().HitRatio())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.RecordHit()
+			r.RecordHit()
+			r.RecordMiss()
+			r.RecordSets(2)
+			r.RecordDeletes(1)
+			r.ObserveProvider(Get, time.Millisecond, nil)
+			r.ObserveProvider(Set, time.Millisecond, errors.New("Provider failure"))
+			r.ObserveLoader(time.Millisecond, nil)
+		}()
+	}
+	wg.Wait()
+	r.ObserveLoader(time.Second, errors.New("Loader failure"))
+
+	s := r.Snapshot()
+	assert.Equal(t, uint64(20), s.Hits)
+	assert.Equal(t, uint64(10), s.Misses)
+	assert.InDelta(t, 2.0/3.0, s.HitRatio(), 0.001)
+	assert.Equal(t, uint64(20), s.Sets)
+	assert.Equal(t, uint64(10), s.Deletes)
+	assert.Equal(t, uint64(10), s.ProviderErrors)
+	assert.Equal(t, uint64(11), s.LoaderCalls)
+	assert.Equal(t, uint64(1), s.LoaderErrors)
+	assert.Equal(t, uint64(11), s.LoaderLatency.Count)
+
+	assert.Equal(t, 2, len(s.ProviderLatency))
+	assert.Equal(t, uint64(10), s.ProviderLatency[Get].Count)
+	assert.Equal(t, 10*time.Millisecond, s.ProviderLatency[Set].Sum)
+}
+
+func TestOperationString(t *testing.T) {
+	assert.Equal(t, "get", Get.String())
+	assert.Equal(t, "delete_many", DeleteMany.String())
+	assert.Equal(t, "pop_tag", PopTag.String())
+	assert.Equal(t, "unknown", operationCount.String())
+}
+
+func TestSizeHistogram(t *testing.T) {
+	var h SizeHistogram
+	assert.Equal(t, int64(0), h.Snapshot().Mean())
+
+	for _, size := range []int{10, 64, 1000, 100 << 20} {
+		h.Observe(size)
+	}
+
+	snapshot := h.Snapshot()
+	assert.Equal(t, uint64(4), snapshot.Count)
+	assert.Equal(t, int64(1074+100<<20), snapshot.Sum)
+	assert.Equal(t, snapshot.Sum/4, snapshot.Mean())
+	assert.Equal(t, SizeBucket{UpperBound: 64, Count: 2}, snapshot.Buckets[0])
+	assert.Equal(t, SizeBucket{UpperBound: 1 << 10, Count: 3}, snapshot.Buckets[2])
+	assert.Equal(t, uint64(3), snapshot.Buckets[len(snapshot.Buckets)-1].Count)
+}
+
+func TestStartLoader(t *testing.T) {
+	r := NewRecorder()
+	finishFirst := r.StartLoader()
+	finishSecond := r.StartLoader()
+	assert.Equal(t, int64(2), r.Snapshot().LoadersInFlight)
+
+	finishFirst(nil)
+	finishSecond(errors.New("Loader failure"))
+	s := r.Snapshot()
+	assert.Equal(t, int64(0), s.LoadersInFlight)
+	assert.Equal(t, uint64(2), s.LoaderCalls)
+	assert.Equal(t, uint64(1), s.LoaderErrors)
+	assert.Equal(t, uint64(2), s.LoaderLatency.Count)
+}